@@ -121,6 +121,7 @@ function read_log_variables() {
     while IFS="=" read -r key value; do
         case "$key" in
             "replica_log_debug_overrides") replica_log_debug_overrides="${value}" ;;
+            "replica_log_level") replica_log_level="${value}" ;;
         esac
     done <"$1"
 }
@@ -206,6 +207,8 @@ BACKUP_RETENTION_TIME_SECS="${backup_retention_time_secs:-86400}"
 BACKUP_PURGING_INTERVAL_SECS="${backup_purging_interval_secs:-3600}"
 # Default is an empty list
 REPLICA_LOG_DEBUG_OVERRIDES="${replica_log_debug_overrides:-[]}"
+# Default is info
+REPLICA_LOG_LEVEL="${replica_log_level:-info}"
 # Default is null (None)
 MALICIOUS_BEHAVIOR="${malicious_behavior:-null}"
 
@@ -236,6 +239,7 @@ sed -e "s@{{ ipv6_address }}@${IPV6_ADDRESS}@" \
     -e "s@{{ backup_retention_time_secs }}@${BACKUP_RETENTION_TIME_SECS}@" \
     -e "s@{{ backup_purging_interval_secs }}@${BACKUP_PURGING_INTERVAL_SECS}@" \
     -e "s@{{ replica_log_debug_overrides }}@${REPLICA_LOG_DEBUG_OVERRIDES}@" \
+    -e "s@{{ replica_log_level }}@${REPLICA_LOG_LEVEL}@" \
     -e "s@{{ malicious_behavior }}@${MALICIOUS_BEHAVIOR}@" \
     "${IN_FILE}" >"${OUT_FILE}"
 
@@ -92,6 +92,10 @@ options may be specified:
 
     Be sure to properly quote the string.
 
+  --replica_log_level level
+    The log level of the node software, one of critical, error, warning,
+    info, debug or trace. Defaults to info. Primarily intended for testing.
+
   --malicious_behavior malicious_behavior
     A JSON-object that describes the malicious behavior activated on
     the node. This is only used for testing.
@@ -127,7 +131,7 @@ function build_ic_bootstrap_tar() {
     local BACKUP_RETENTION_TIME_SECS BACKUP_PURGING_INTERVAL_SECS
     local JOURNALBEAT_HOSTS JOURNALBEAT_TAGS
     local ACCOUNTS_SSH_AUTHORIZED_KEYS
-    local REPLICA_LOG_DEBUG_OVERRIDES
+    local REPLICA_LOG_DEBUG_OVERRIDES REPLICA_LOG_LEVEL
     local MALICIOUS_BEHAVIOR
     local BITCOIND_ADDR
     local ONCHAIN_OBSERVABILITY_OVERRIDES
@@ -182,6 +186,9 @@ function build_ic_bootstrap_tar() {
             --replica_log_debug_overrides)
                 REPLICA_LOG_DEBUG_OVERRIDES="$2"
                 ;;
+            --replica_log_level)
+                REPLICA_LOG_LEVEL="$2"
+                ;;
             --malicious_behavior)
                 MALICIOUS_BEHAVIOR="$2"
                 ;;
@@ -233,8 +240,9 @@ EOF
         echo "backup_retention_time_secs=${BACKUP_RETENTION_TIME_SECS}" >"${BOOTSTRAP_TMPDIR}/backup.conf"
         echo "backup_puging_interval_secs=${BACKUP_PURGING_INTERVAL_SECS}" >>"${BOOTSTRAP_TMPDIR}/backup.conf"
     fi
-    if [ "${REPLICA_LOG_DEBUG_OVERRIDES}" != "" ]; then
+    if [ "${REPLICA_LOG_DEBUG_OVERRIDES}" != "" ] || [ "${REPLICA_LOG_LEVEL}" != "" ]; then
         echo "replica_log_debug_overrides=${REPLICA_LOG_DEBUG_OVERRIDES}" >"${BOOTSTRAP_TMPDIR}/log.conf"
+        echo "replica_log_level=${REPLICA_LOG_LEVEL}" >>"${BOOTSTRAP_TMPDIR}/log.conf"
     fi
     if [ "${MALICIOUS_BEHAVIOR}" != "" ]; then
         echo "malicious_behavior=${MALICIOUS_BEHAVIOR}" >"${BOOTSTRAP_TMPDIR}/malicious_behavior.conf"
@@ -4,16 +4,17 @@ For example, you might want to build some targets with optimizations enabled
 no matter what the current Bazel flags are.
 """
 
-def _release_nostrip_transition(_settings, _attr):
+def _release_nostrip_transition(settings, _attr):
+    debug_assertions = "on" if settings["//bazel:debug_replica"] else "off"
     return {
         "//command_line_option:compilation_mode": "opt",
         "//command_line_option:strip": "never",
-        "@rules_rust//:extra_rustc_flags": ["-Cdebug-assertions=off"],
+        "@rules_rust//:extra_rustc_flags": ["-Cdebug-assertions=" + debug_assertions],
     }
 
 release_nostrip_transition = transition(
     implementation = _release_nostrip_transition,
-    inputs = [],
+    inputs = ["//bazel:debug_replica"],
     outputs = [
         "//command_line_option:compilation_mode",
         "//command_line_option:strip",
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var DEFAULT_AFFECTED_BASE_REF = "origin/master"
+
+func run_git(args ...string) (string, error) {
+	command := append([]string{"git"}, args...)
+	gitCmd := exec.Command(command[0], command[1:]...)
+	outputBuffer := &bytes.Buffer{}
+	stdErrBuffer := &bytes.Buffer{}
+	gitCmd.Stdout = outputBuffer
+	gitCmd.Stderr = stdErrBuffer
+	traced := trace_command(command)
+	err := gitCmd.Run()
+	traced(err)
+	if err != nil {
+		return "", fmt.Errorf("Git command: [%s] failed: %s", strings.Join(command, " "), stdErrBuffer.String())
+	}
+	return strings.TrimSpace(outputBuffer.String()), nil
+}
+
+// Returns files (relative to the workspace root) changed since the merge base with base_ref, including uncommitted and untracked ones.
+func get_changed_files(base_ref string) ([]string, error) {
+	merge_base, err := run_git("merge-base", base_ref, "HEAD")
+	if err != nil {
+		return []string{}, err
+	}
+	output, err := run_git("diff", "--name-only", merge_base)
+	if err != nil {
+		return []string{}, err
+	}
+	untracked, err := run_git("ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return []string{}, err
+	}
+	output += "\n" + untracked
+	workspace := get_workspace_root()
+	// Deleted files can't be resolved by Bazel.
+	return filter(strings.Split(output, "\n"), func(s string) bool {
+		if len(s) == 0 {
+			return false
+		}
+		_, err := os.Stat(filepath.Join(workspace, s))
+		return err == nil
+	}), nil
+}
+
+// Returns system tests, which transitively depend on any of the given labels or files.
+func get_dependent_tests(cfg *QueryConfig, labels []string) ([]TestTarget, error) {
+	universe := fmt.Sprintf("tests(%s)", strings.Join(cfg.get_universe(), " + "))
+	query := fmt.Sprintf("rdeps(%s, set(%s))", universe, strings.Join(labels, " "))
+	// Labels, which don't belong to any Bazel package (e.g. changed docs), are skipped due to --keep_going.
+	affected, err := run_bazel_xml_query(query, "--keep_going")
+	if err != nil {
+		return []TestTarget{}, err
+	}
+	return filter_targets(affected, func(t *TestTarget) bool {
+		return !is_excluded(t, cfg.excludes)
+	}), nil
+}
+
+func run_affected_tests(cmd *cobra.Command, cfg *Config, bazel_args []string) error {
+	changed_files, err := get_changed_files(cfg.baseRef)
+	if err != nil {
+		return err
+	}
+	if len(changed_files) == 0 {
+		cmd.Printf("%sNo local changes against `%s` were found.%s\n", CYAN, cfg.baseRef, NC)
+		return nil
+	}
+	affected, err := get_dependent_tests(&cfg.queryCfg, changed_files)
+	if err != nil {
+		return err
+	}
+	targets := target_names(affected)
+	if len(targets) == 0 {
+		cmd.Printf("%sNone of the system tests is affected by %d changed files.%s\n", CYAN, len(changed_files), NC)
+		return nil
+	}
+	cmd.Printf("%sThe following %d system tests are affected by %d changed files:\n%s%s\n", CYAN, len(targets), len(changed_files), strings.Join(targets, "\n"), NC)
+	if !cfg.isDryRun && !cfg.assumeYes && !ask_confirmation(cmd, "Run all of them?") {
+		return nil
+	}
+	return run_system_tests(cmd, cfg, targets, bazel_args)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var DEFAULT_CRASH_LOG_MINUTES = 30
+
+// Directory in the undeclared test outputs, into which the test driver collects artifacts of the nodes of failed tests.
+var CRASH_ARTIFACTS_DIR = "crash_artifacts"
+
+// Saved artifacts of a run are described by this file, next to the copied logs and outputs.
+var ARTIFACTS_RESULT_FILE = "result.json"
+var ARTIFACTS_RUN_TIME_FORMAT = "20060102-150405"
+
+// Bazel keeps the undeclared outputs of the last run of the target unzipped, if --nozip_undeclared_test_outputs is set.
+func get_crash_artifacts_dir(target string) string {
+	return filepath.Join(get_test_logs_dir(target), "test.outputs", CRASH_ARTIFACTS_DIR)
+}
+
+func print_crash_artifacts(cmd *cobra.Command, targets []string, since time.Time) {
+	for _, target := range targets {
+		dir := get_crash_artifacts_dir(target)
+		if info, err := os.Stat(dir); err == nil && !info.ModTime().Before(since.Add(-time.Second)) {
+			cmd.PrintErrf("%sCore dumps, panics and journald logs of the nodes of failed target `%s` were collected in %s%s\n", RED, target, dir, NC)
+		}
+	}
+}
+
+// Directory name derived from the label, e.g. rs_tests_nns_nns_upgrade_test for //rs/tests/nns:nns_upgrade_test.
+func get_target_dir_name(target string) string {
+	return strings.NewReplacer("//", "", "/", "_", ":", "_").Replace(target)
+}
+
+func get_artifacts_base_dir() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "artifacts"), nil
+}
+
+// By default, artifacts are kept in ~/.ict/artifacts/<target>/<run start time>.
+// With a custom directory, artifacts of multiple targets are kept in subdirectories named after the targets.
+func get_artifacts_dir(custom_dir string, target string, targets_count int, started time.Time) (string, error) {
+	if len(custom_dir) > 0 {
+		if targets_count > 1 {
+			return filepath.Join(custom_dir, get_target_dir_name(target)), nil
+		}
+		return custom_dir, nil
+	}
+	base_dir, err := get_artifacts_base_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base_dir, get_target_dir_name(target), started.Format(ARTIFACTS_RUN_TIME_FORMAT)), nil
+}
+
+// Copies logs, test.xml and (undeclared) outputs of the targets out of bazel-testlogs, which are overwritten by the next Bazel invocation.
+// Targets, which haven't run since the given time, e.g. because their build failed, are skipped.
+func save_artifacts(cmd *cobra.Command, targets []string, custom_dir string, started time.Time) {
+	for _, target := range targets {
+		result, err := get_test_result(target, started)
+		if err != nil || result.Status == "NO_RESULT" {
+			continue
+		}
+		dir, err := get_artifacts_dir(custom_dir, target, len(targets), started)
+		if err == nil {
+			err = save_target_artifacts(target, result, dir)
+		}
+		if err != nil {
+			cmd.PrintErrf("%sFailed to save artifacts of target `%s`: %s%s\n", RED, target, err, NC)
+			continue
+		}
+		cmd.Printf("%sArtifacts of target `%s` were saved to %s%s\n", CYAN, target, dir, NC)
+	}
+}
+
+func save_target_artifacts(target string, result testResult, dir string) error {
+	logs_dir := get_test_logs_dir(target)
+	if err := copy_dir(logs_dir, dir); err != nil {
+		return err
+	}
+	// Paths of the result refer to the copies rather than to bazel-testlogs.
+	for _, path := range []*string{&result.TestLog, &result.TestXml, &result.OutputsDir} {
+		if rel, err := filepath.Rel(logs_dir, *path); err == nil {
+			*path = filepath.Join(dir, rel)
+		}
+	}
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ARTIFACTS_RESULT_FILE), content, 0644)
+}
+
+type savedArtifacts struct {
+	Dir       string     `json:"dir"`
+	StartedAt time.Time  `json:"started_at"`
+	Result    testResult `json:"result"`
+}
+
+// Returns the artifacts saved in ~/.ict/artifacts, the most recent runs first.
+func load_saved_artifacts() ([]savedArtifacts, error) {
+	base_dir, err := get_artifacts_base_dir()
+	if err != nil {
+		return []savedArtifacts{}, err
+	}
+	result_files, err := filepath.Glob(filepath.Join(base_dir, "*", "*", ARTIFACTS_RESULT_FILE))
+	if err != nil {
+		return []savedArtifacts{}, err
+	}
+	saved := []savedArtifacts{}
+	for _, result_file := range result_files {
+		dir := filepath.Dir(result_file)
+		started, err := time.ParseInLocation(ARTIFACTS_RUN_TIME_FORMAT, filepath.Base(dir), time.Local)
+		if err != nil {
+			continue
+		}
+		artifacts := savedArtifacts{Dir: dir, StartedAt: started}
+		if content, err := os.ReadFile(result_file); err != nil || json.Unmarshal(content, &artifacts.Result) != nil {
+			continue
+		}
+		saved = append(saved, artifacts)
+	}
+	sort.SliceStable(saved, func(i, j int) bool {
+		return saved[i].StartedAt.After(saved[j].StartedAt)
+	})
+	return saved, nil
+}
+
+// Returns the artifacts of the most recent run of a target, whose label contains the given substring.
+func find_saved_artifacts(target string) (*savedArtifacts, error) {
+	saved, err := load_saved_artifacts()
+	if err != nil {
+		return nil, err
+	}
+	for i := range saved {
+		if strings.Contains(saved[i].Result.Target, target) {
+			return &saved[i], nil
+		}
+	}
+	return nil, fmt.Errorf("\nNo saved artifacts of a target matching `%s` were found, see `ict artifacts list`.", target)
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type ArtifactsListConfig struct {
+	isJson bool
+}
+
+func ArtifactsListCommand(cfg *ArtifactsListConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		saved, err := load_saved_artifacts()
+		if err != nil {
+			return err
+		}
+		if len(args) > 0 {
+			saved = filter_saved_artifacts(saved, args[0])
+		}
+		if cfg.isJson {
+			return print_json(cmd, saved)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "STARTED\tTARGET\tRESULT\tDURATION\tDIRECTORY")
+		for _, s := range saved {
+			duration := (time.Duration(s.Result.DurationSecs) * time.Second).Round(time.Second)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.StartedAt.Format("2006-01-02 15:04:05"), s.Result.Target, s.Result.Status, duration, s.Dir)
+		}
+		return w.Flush()
+	}
+}
+
+func filter_saved_artifacts(saved []savedArtifacts, target string) []savedArtifacts {
+	filtered := []savedArtifacts{}
+	for _, s := range saved {
+		if strings.Contains(s.Result.Target, target) {
+			filtered = append(filtered, s)
+		}
+	}
+	return filtered
+}
+
+func ArtifactsOpenCommand(cmd *cobra.Command, args []string) error {
+	artifacts, err := find_saved_artifacts(args[0])
+	if err != nil {
+		return err
+	}
+	cmd.Printf("%sArtifacts of the run of `%s` started at %s are in:\n%s%s\n", CYAN, artifacts.Result.Target, artifacts.StartedAt.Format(time.RFC1123), artifacts.Dir, NC)
+	return open_in_default_app(artifacts.Dir)
+}
+
+// Opens a directory or URL with the default application of the desktop.
+// Without a desktop, e.g. on a devenv via SSH, printing the path beforehand is all we can do.
+func open_in_default_app(path string) error {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	if _, err := exec.LookPath(opener); err != nil {
+		return nil
+	}
+	return exec.Command(opener, path).Run()
+}
+
+func NewArtifactsCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "artifacts",
+		Short: "Browse logs and outputs saved from previous test runs",
+	}
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
+
+func NewArtifactsListCmd() *cobra.Command {
+	var cfg = ArtifactsListConfig{}
+	var cmd = &cobra.Command{
+		Use:     "list [<target_substring>]",
+		Short:   "List saved artifacts of previous test runs, the most recent first",
+		Example: "  ict artifacts list\n  ict artifacts list nns_upgrade_test --json",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    ArtifactsListCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print saved artifacts with results of their runs as JSON.")
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
+
+func NewArtifactsOpenCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "open <target_substring>",
+		Short:   "Open the artifacts of the most recent run of a target",
+		Example: "  ict artifacts open basic_health_test",
+		Args:    cobra.ExactArgs(1),
+		RunE:    ArtifactsOpenCommand,
+	}
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
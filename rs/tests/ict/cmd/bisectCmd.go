@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var BISECT_GOOD = "good"
+var BISECT_BAD = "bad"
+var BISECT_SKIP = "skip"
+
+type BisectConfig struct {
+	testCfg Config
+	good    string
+	bad     string
+}
+
+func ValidateBisectCommand(cfg *BisectConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(cfg.good) == 0 {
+			return fmt.Errorf("option --good is required, it should be a commit at which the test passes.")
+		}
+		return positional_args(cobra.ExactArgs(1))(cmd, args)
+	}
+}
+
+func BisectCommandWithConfig(cfg *BisectConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, bazel_args := split_bazel_args(cmd, args)
+		all_targets, err := get_all_system_test_targets(&cfg.testCfg.queryCfg)
+		if err != nil {
+			return err
+		}
+		target, err := resolve_target(cmd, all_targets, args[0], &cfg.testCfg.matchCfg)
+		if err != nil {
+			return err
+		}
+		// Checking out the commits under test would otherwise fail or carry the local changes along.
+		if changes, err := run_git("status", "--porcelain", "--untracked-files=no"); err != nil {
+			return err
+		} else if len(changes) > 0 {
+			return fmt.Errorf("\nThe working tree has uncommitted changes, commit or stash them before bisecting:\n%s", changes)
+		}
+		command := get_bazel_test_command(&cfg.testCfg, []string{target}, bazel_args)
+		print_bazel_command(cmd, command)
+		return run_bisect(cmd, cfg, target, command)
+	}
+}
+
+// Drives git bisect between the good and the bad commit, the test is run at each of the commits git checks out.
+func run_bisect(cmd *cobra.Command, cfg *BisectConfig, target string, command []string) error {
+	output, err := run_git("bisect", "start", cfg.bad, cfg.good)
+	if err != nil {
+		return err
+	}
+	// Restores the commit, which was checked out before bisecting.
+	defer run_git("bisect", "reset")
+	for step := 1; ; step++ {
+		if result, done := get_bisect_result(output); done {
+			cmd.Printf("%s%s%s\n", GREEN, result, NC)
+			return nil
+		}
+		commit, err := run_git("log", "-1", "--format=%h %s")
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%sStep %d: %s\nTesting commit %s ...%s\n", CYAN, step, get_bisect_progress(output), commit, NC)
+		verdict, reason, err := get_bisect_verdict(command, target)
+		if err != nil {
+			return cleanup_interrupted_run(cmd, &cfg.testCfg, err)
+		}
+		if len(reason) > 0 {
+			cmd.Printf("%sCommit %s is skipped, because %s%s\n", CYAN, commit, reason, NC)
+		} else {
+			cmd.Printf("%sCommit %s is %s%s\n", CYAN, commit, verdict, NC)
+		}
+		if output, err = run_git("bisect", verdict); err != nil {
+			return err
+		}
+	}
+}
+
+// Only failures of the test itself mark a commit as bad, commits which can't be built or hit infra failures are skipped.
+// An interrupted run stops the bisection.
+func get_bisect_verdict(command []string, target string) (string, string, error) {
+	passed, err := run_bazel_test_iteration(command, []string{target})
+	if _, ok := err.(*bazelInterruptedError); ok {
+		return "", "", err
+	}
+	if err != nil {
+		return BISECT_SKIP, fmt.Sprintf("the build failed: %s", err), nil
+	}
+	if passed[target] {
+		return BISECT_GOOD, "", nil
+	}
+	if signature, ok := get_infra_failure_signature(target); ok {
+		return BISECT_SKIP, fmt.Sprintf("of an infra failure: %s", signature), nil
+	}
+	return BISECT_BAD, "", nil
+}
+
+// The line of git bisect, e.g. "Bisecting: 12 revisions left to test after this (roughly 4 steps)".
+func get_bisect_progress(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Bisecting:") {
+			return line
+		}
+	}
+	return "Bisecting ..."
+}
+
+// Bisecting is done, once git either found the first bad commit or only skipped commits are left to test.
+func get_bisect_result(output string) (string, bool) {
+	if strings.Contains(output, "is the first bad commit") || strings.Contains(output, "only 'skip'ped commits left") {
+		return output, true
+	}
+	return "", false
+}
+
+func NewBisectCmd() *cobra.Command {
+	var cfg = BisectConfig{}
+	var bisectCmd = &cobra.Command{
+		Use:     "bisect <system_test_target> --good <commit> [--bad <commit>] [flags] [-- <bazel_args>]",
+		Short:   "Find the first commit, at which a system_test target started to fail, via git bisect",
+		Example: "  ict bisect basic_health_test --good 2a3b4c5 --bad HEAD\n  ict bisect nns_upgrade_test --good origin/master~50 --include-tests=upgrade",
+		Args:    ValidateBisectCommand(&cfg),
+		RunE:    BisectCommandWithConfig(&cfg),
+	}
+	add_match_flags(bisectCmd, &cfg.testCfg.matchCfg)
+	bisectCmd.Flags().StringVarP(&cfg.good, "good", "", "", "Commit, at which the test passes.")
+	bisectCmd.Flags().StringVarP(&cfg.bad, "bad", "", "HEAD", "Commit, at which the test fails.")
+	bisectCmd.Flags().Uint64VarP(&cfg.testCfg.seed, "seed", "", DEFAULT_RNG_SEED, "Seed of the random number generator of the test driver, which is passed to the tests as RNG_SEED.")
+	bisectCmd.Flags().StringArrayVarP(&cfg.testCfg.testEnv, "env", "e", []string{}, "Set an environment variable of the tests, e.g. --env FEATURE_FLAG=1 (can be repeated).")
+	bisectCmd.Flags().StringVarP(&cfg.testCfg.filterTests, "include-tests", "i", "", "Execute only those test functions which contain a substring.")
+	bisectCmd.Flags().StringVarP(&cfg.testCfg.farmBaseUrl, "farm-url", "", "", "Use a custom url for the Farm webservice.")
+	add_query_flags(bisectCmd, &cfg.testCfg.queryCfg)
+	bisectCmd.SetOut(os.Stdout)
+	return bisectCmd
+}
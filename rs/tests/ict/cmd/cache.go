@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/schollz/closestmatch"
+	"github.com/spf13/cobra"
+)
+
+// Cached bazel query results older than this are considered stale.
+var QUERY_CACHE_TTL = 24 * time.Hour
+
+type QueryConfig struct {
+	noCache bool
+	offline bool
+	// Custom query expression, which replaces the default universe of system tests.
+	expression string
+	// Bazel packages, in which system tests are looked up.
+	universe []string
+	// Targets matching any of these substrings or tags are skipped.
+	excludes []string
+}
+
+func add_query_flags(cmd *cobra.Command, cfg *QueryConfig) {
+	cmd.Flags().BoolVarP(&cfg.noCache, "no-cache", "", false, "Ignore cached Bazel query results and query targets anew.")
+	cmd.Flags().BoolVarP(&cfg.offline, "offline", "", false, "Use only the last cached Bazel query results, without invoking Bazel.")
+	cmd.MarkFlagsMutuallyExclusive("no-cache", "offline")
+	add_universe_flags(cmd, cfg)
+}
+
+func add_universe_flags(cmd *cobra.Command, cfg *QueryConfig) {
+	cmd.Flags().StringSliceVarP(&cfg.universe, "universe", "", DEFAULT_QUERY_UNIVERSE, "Bazel packages (comma-separated or repeated), in which system tests are looked up.")
+	cmd.Flags().StringArrayVarP(&cfg.excludes, "exclude", "", []string{}, "Skip targets containing this substring or having this tag (repeatable).")
+}
+
+type queryCacheEntry struct {
+	Workspace   string       `json:"workspace"`
+	Query       string       `json:"query"`
+	Fingerprint string       `json:"fingerprint"`
+	CreatedAt   time.Time    `json:"created_at"`
+	Targets     []TestTarget `json:"targets"`
+}
+
+func get_query_cache_dir() (string, error) {
+	cache_dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cache_dir, "ict"), nil
+}
+
+// Files marking the root of a bazel workspace, with or without bzlmod.
+var WORKSPACE_FILES = []string{"WORKSPACE.bazel", "WORKSPACE", "MODULE.bazel"}
+
+// Name of the workspace in WORKSPACE.bazel of the IC repo.
+var IC_WORKSPACE_NAME = "ic"
+var WORKSPACE_NAME_REGEX = regexp.MustCompile(`(?:workspace|module)\(\s*name\s*=\s*"([^"]+)"`)
+
+// Walks up from the current directory until a bazel WORKSPACE file is found.
+func find_workspace_root() (string, string, bool) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", false
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		for _, name := range WORKSPACE_FILES {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir, name, true
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			return cwd, "", false
+		}
+	}
+}
+
+// Falls back to the current directory, if no WORKSPACE file exists.
+func get_workspace_root() string {
+	root, _, _ := find_workspace_root()
+	return root
+}
+
+// Bazel fails with cryptic errors outside of the IC repo, so commands running bazel check for it upfront.
+func get_ic_workspace_root() (string, error) {
+	root, file, ok := find_workspace_root()
+	if !ok {
+		return "", fmt.Errorf("\nict has to be run within a checkout of the IC repo (github.com/dfinity/ic), but no %s was found in %s or its parents.", strings.Join(WORKSPACE_FILES, ", "), root)
+	}
+	content, err := os.ReadFile(filepath.Join(root, file))
+	if err != nil {
+		return root, nil
+	}
+	if match := WORKSPACE_NAME_REGEX.FindSubmatch(content); match != nil && string(match[1]) != IC_WORKSPACE_NAME {
+		return "", fmt.Errorf("\nict has to be run within a checkout of the IC repo (github.com/dfinity/ic), but %s is the bazel workspace `%s`.", root, match[1])
+	}
+	return root, nil
+}
+
+// Converts bazel package patterns, e.g. //rs/tests/..., into directories relative to the workspace root.
+func get_watched_dirs(universe []string) []string {
+	dirs := make([]string, 0, len(universe))
+	for _, pkg := range universe {
+		dir := strings.TrimPrefix(pkg, "//")
+		dir = strings.TrimSuffix(strings.SplitN(dir, ":", 2)[0], "...")
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs
+}
+
+// Computes a hash over paths and modification times of all BUILD/.bzl files in the watched dirs.
+func get_build_files_fingerprint(workspace string, watched_dirs []string) string {
+	hash := sha256.New()
+	for _, watched_dir := range watched_dirs {
+		filepath.WalkDir(filepath.Join(workspace, watched_dir), func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			name := d.Name()
+			if name == "BUILD.bazel" || name == "BUILD" || strings.HasSuffix(name, ".bzl") {
+				if info, err := d.Info(); err == nil {
+					fmt.Fprintf(hash, "%s:%d\n", path, info.ModTime().UnixNano())
+				}
+			}
+			return nil
+		})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func get_query_cache_file(workspace string, query string) (string, error) {
+	cache_dir, err := get_query_cache_dir()
+	if err != nil {
+		return "", err
+	}
+	key := sha256.Sum256([]byte(workspace + "\n" + query))
+	return filepath.Join(cache_dir, "targets_"+hex.EncodeToString(key[:8])+".json"), nil
+}
+
+func load_query_cache(workspace string, query string) (*queryCacheEntry, error) {
+	cache_file, err := get_query_cache_file(workspace, query)
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(cache_file)
+	if err != nil {
+		return nil, err
+	}
+	var entry queryCacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func read_query_cache(workspace string, query string, fingerprint string) ([]TestTarget, bool) {
+	entry, err := load_query_cache(workspace, query)
+	if err != nil {
+		return nil, false
+	}
+	if entry.Fingerprint != fingerprint || time.Since(entry.CreatedAt) > QUERY_CACHE_TTL {
+		return nil, false
+	}
+	return entry.Targets, true
+}
+
+// Serves the last cached query result regardless of its freshness.
+func read_offline_query_cache(workspace string, query string, watched_dirs []string) ([]TestTarget, error) {
+	entry, err := load_query_cache(workspace, query)
+	if err != nil {
+		return []TestTarget{}, fmt.Errorf("No cached results for Bazel query [%s] are available in offline mode.\nRun the same command without --offline first.", query)
+	}
+	age := time.Since(entry.CreatedAt).Round(time.Minute)
+	fmt.Fprintf(os.Stderr, "%sOffline mode: using Bazel query results cached %s ago, they might be stale.%s\n", RED, age, NC)
+	if entry.Fingerprint != get_build_files_fingerprint(workspace, watched_dirs) {
+		fmt.Fprintf(os.Stderr, "%sBUILD files have changed since the cache was written.%s\n", RED, NC)
+	}
+	return entry.Targets, nil
+}
+
+func write_query_cache(workspace string, query string, fingerprint string, targets []TestTarget) error {
+	cache_file, err := get_query_cache_file(workspace, query)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(cache_file), 0755); err != nil {
+		return err
+	}
+	entry := queryCacheEntry{
+		Workspace:   workspace,
+		Query:       query,
+		Fingerprint: fingerprint,
+		CreatedAt:   time.Now(),
+		Targets:     targets,
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(cache_file, content, 0644)
+}
+
+// Runs bazel query, unless a fresh result for the same query is found in the cache.
+func run_cached_bazel_query(query string, cfg *QueryConfig) ([]TestTarget, error) {
+	workspace := get_workspace_root()
+	watched_dirs := get_watched_dirs(cfg.get_universe())
+	if cfg.offline {
+		return read_offline_query_cache(workspace, query, watched_dirs)
+	}
+	fingerprint := get_build_files_fingerprint(workspace, watched_dirs)
+	if !cfg.noCache {
+		if targets, ok := read_query_cache(workspace, query, fingerprint); ok {
+			log_verbose("Using the %d targets of `%s` cached for the current BUILD files.", len(targets), query)
+			return targets, nil
+		}
+	}
+	log_verbose("Querying bazel for `%s`, as its cached results are missing or stale.", query)
+	targets, err := run_bazel_xml_query(query)
+	if err != nil {
+		return []TestTarget{}, err
+	}
+	// Failure to write the cache is not critical, the next invocation just queries bazel again.
+	write_query_cache(workspace, query, fingerprint, targets)
+	return targets, nil
+}
+
+// Index files of the fuzzy matcher are keyed by the set of targets and the bag sizes.
+func get_closestmatch_index_file(targets []string, bag_sizes []int) (string, error) {
+	cache_dir, err := get_query_cache_dir()
+	if err != nil {
+		return "", err
+	}
+	sorted := append([]string{}, targets...)
+	sort.Strings(sorted)
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%v\n%s", bag_sizes, strings.Join(sorted, "\n"))
+	return filepath.Join(cache_dir, "closestmatch_"+hex.EncodeToString(hash.Sum(nil)[:8])+".gz"), nil
+}
+
+// Loads a previously built fuzzy matcher index for the same targets, or builds and stores a new one.
+func get_closestmatch_index(targets []string, bag_sizes []int) *closestmatch.ClosestMatch {
+	index_file, err := get_closestmatch_index_file(targets, bag_sizes)
+	if err != nil {
+		return closestmatch.New(targets, bag_sizes)
+	}
+	if index, err := closestmatch.Load(index_file); err == nil {
+		// Mark the index as recently used, so that it is not pruned.
+		os.Chtimes(index_file, time.Now(), time.Now())
+		return index
+	}
+	index := closestmatch.New(targets, bag_sizes)
+	if err := os.MkdirAll(filepath.Dir(index_file), 0755); err == nil {
+		prune_closestmatch_indices(filepath.Dir(index_file))
+		index.Save(index_file)
+	}
+	return index
+}
+
+// Removes index files, which were not used for longer than the query cache TTL.
+func prune_closestmatch_indices(cache_dir string) {
+	files, _ := filepath.Glob(filepath.Join(cache_dir, "closestmatch_*.gz"))
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil && time.Since(info.ModTime()) > QUERY_CACHE_TTL {
+			os.Remove(file)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var CONNECTIVITY_DIAL_TIMEOUT = 5 * time.Second
+
+// Interfaces created by the usual VPN clients, e.g. OpenVPN, WireGuard and GlobalProtect.
+var VPN_INTERFACE_PREFIXES = []string{"tun", "utun", "wg", "ppp", "gpd", "ipsec"}
+
+type CheckConnectivityConfig struct {
+	farmCfg FarmConfig
+	node    string
+	isJson  bool
+}
+
+func has_routable_address(iface net.Interface) bool {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.IsGlobalUnicast() {
+			return true
+		}
+	}
+	return false
+}
+
+// macOS always has a few utun interfaces, only those with a routable address count.
+func check_vpn() checkResult {
+	result := checkResult{Name: "VPN", Hint: "Connect to the VPN, Farm and the testnets are only reachable from the internal network."}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		result.Status, result.Message = CHECK_WARN, fmt.Sprintf("failed to list the network interfaces: %s", err)
+		return result
+	}
+	vpns := []string{}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || !has_routable_address(iface) {
+			continue
+		}
+		for _, prefix := range VPN_INTERFACE_PREFIXES {
+			if strings.HasPrefix(iface.Name, prefix) {
+				vpns = append(vpns, iface.Name)
+				break
+			}
+		}
+	}
+	if len(vpns) == 0 {
+		result.Status, result.Message = CHECK_WARN, "no VPN interface is up, which is fine within the office network"
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("interface %s is up", strings.Join(vpns, ", "))
+	return result
+}
+
+func check_ipv6_address() checkResult {
+	result := checkResult{Name: "IPv6 address", Hint: "The VMs of the testnets are IPv6-only, use a machine with IPv6, e.g. a devenv, or see:\n$ ict testnet tunnel --help"}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("failed to list the network interfaces: %s", err)
+		return result
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil || iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		for _, addr := range addrs {
+			// Unique local addresses, i.e. fc00::/7, aren't routed to Farm.
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() == nil && ipnet.IP.IsGlobalUnicast() && !ipnet.IP.IsPrivate() {
+				result.Status, result.Message = CHECK_PASS, fmt.Sprintf("%s on %s", ipnet.IP, iface.Name)
+				return result
+			}
+		}
+	}
+	result.Status, result.Message = CHECK_FAIL, "no interface has a global IPv6 address"
+	return result
+}
+
+func dial_ipv6(host string, port string) error {
+	conn, err := net.DialTimeout("tcp6", net.JoinHostPort(host, port), CONNECTIVITY_DIAL_TIMEOUT)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func check_farm(farm_base_url string) (checkResult, []farmGroup) {
+	result := checkResult{Name: "Farm", Hint: fmt.Sprintf("Connect to the VPN and make sure %s is reachable.", farm_base_url)}
+	groups, err := list_farm_groups(farm_base_url)
+	if err != nil {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("listing the groups failed: %s", err)
+		return result, groups
+	}
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("%d groups are running on %s", len(groups), farm_base_url)
+	return result, groups
+}
+
+func check_farm_ipv6(farm_base_url string) checkResult {
+	result := checkResult{Name: "Farm over IPv6", Hint: "The test driver talks to Farm and the VMs over IPv6, check the IPv6 route via the VPN."}
+	u, err := url.Parse(farm_base_url)
+	if err != nil || len(u.Hostname()) == 0 {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("`%s` isn't a valid URL", farm_base_url)
+		return result
+	}
+	port := u.Port()
+	if len(port) == 0 {
+		port = map[string]string{"http": "80", "https": "443"}[u.Scheme]
+	}
+	if err := dial_ipv6(u.Hostname(), port); err != nil {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("connecting to %s failed: %s", u.Host, err)
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("%s is reachable", u.Host)
+	return result
+}
+
+// Without --node, the first VM of a running group is probed, the ones of the user first.
+func get_sample_node(groups []farmGroup, user string) (string, string) {
+	for _, owned := range []bool{true, false} {
+		for _, group := range groups {
+			if (group.Spec.Metadata.User == user) == owned && len(group.Vms) > 0 {
+				return group.Vms[0].Ipv6, fmt.Sprintf("%s of %s", group.Vms[0].Name, group.Name)
+			}
+		}
+	}
+	return "", ""
+}
+
+// All VMs of the test driver run an SSH server.
+func check_node(ipv6 string, name string) checkResult {
+	result := checkResult{Name: "Node address", Hint: "The VMs are reachable via IPv6 from the internal network only, check the VPN and the IPv6 route."}
+	if len(ipv6) == 0 {
+		result.Status, result.Message = CHECK_WARN, "no VM is running on Farm to probe, pass the IPv6 of a node via --node"
+		return result
+	}
+	if err := dial_ipv6(ipv6, "22"); err != nil {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("connecting to SSH of %s failed: %s", name, err)
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("SSH of %s is reachable", name)
+	return result
+}
+
+// Testnets kept alive by ict on this machine use the key of the test driver, others fall back to the keys of the agent.
+func check_ssh_agent() checkResult {
+	result := checkResult{Name: "SSH agent", Hint: "Start an SSH agent and add your key via:\n$ eval $(ssh-agent) && ssh-add"}
+	if len(os.Getenv("SSH_AUTH_SOCK")) == 0 {
+		result.Status, result.Message = CHECK_WARN, "SSH_AUTH_SOCK isn't set"
+		return result
+	}
+	output, err := exec.Command("ssh-add", "-l").Output()
+	var exit_err *exec.ExitError
+	if errors.As(err, &exit_err) && exit_err.ExitCode() == 1 {
+		result.Status, result.Message = CHECK_WARN, "the agent holds no keys"
+		return result
+	} else if err != nil {
+		result.Status, result.Message = CHECK_WARN, fmt.Sprintf("the agent isn't reachable: %s", err)
+		return result
+	}
+	keys := strings.Count(strings.TrimSpace(string(output)), "\n") + 1
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("the agent holds %d keys", keys)
+	return result
+}
+
+func get_connectivity_checks(cfg *CheckConnectivityConfig) []checkResult {
+	farm, groups := check_farm(cfg.farmCfg.get_base_url())
+	results := []checkResult{check_vpn(), check_ipv6_address(), farm, check_farm_ipv6(cfg.farmCfg.get_base_url())}
+	ipv6, name := cfg.node, cfg.node
+	if len(ipv6) == 0 {
+		ipv6, name = get_sample_node(groups, cfg.farmCfg.user)
+	}
+	return append(results, check_node(ipv6, name), check_ssh_agent())
+}
+
+func CheckConnectivityCommand(cfg *CheckConnectivityConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		results := get_connectivity_checks(cfg)
+		if cfg.isJson {
+			if err := print_json(cmd, results); err != nil {
+				return err
+			}
+		} else {
+			print_check_results(cmd, results)
+		}
+		return get_checks_error(results)
+	}
+}
+
+func NewCheckConnectivityCmd() *cobra.Command {
+	var cfg = CheckConnectivityConfig{}
+	var cmd = &cobra.Command{
+		Use:     "check-connectivity",
+		Short:   "Check the VPN, IPv6 reachability of Farm and of a node, and the SSH agent, before deploying a testnet",
+		Example: "ict check-connectivity\nict check-connectivity --node 2a00:fb01:400:42:5000:aaff:fe3c:1",
+		Args:    cobra.NoArgs,
+		RunE:    CheckConnectivityCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.node, "node", "", "", "IPv6 of a node to probe, instead of the first VM running on Farm.")
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the results of the checks as JSON.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
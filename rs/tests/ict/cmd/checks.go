@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type checkStatus string
+
+var CHECK_PASS checkStatus = "pass"
+var CHECK_WARN checkStatus = "warn"
+var CHECK_FAIL checkStatus = "fail"
+
+// Outcome of a check of the environment, failing checks come with a hint on how to fix them.
+type checkResult struct {
+	Name    string      `json:"name"`
+	Status  checkStatus `json:"status"`
+	Message string      `json:"message"`
+	Hint    string      `json:"hint,omitempty"`
+}
+
+func print_check_results(cmd *cobra.Command, results []checkResult) {
+	colors := map[checkStatus]string{CHECK_PASS: GREEN, CHECK_WARN: YELLOW, CHECK_FAIL: RED}
+	for _, r := range results {
+		cmd.Printf("%s[%s]%s %s: %s\n", colors[r.Status], r.Status, NC, r.Name, r.Message)
+		if len(r.Hint) > 0 && r.Status != CHECK_PASS {
+			cmd.Printf("       %s\n", strings.ReplaceAll(r.Hint, "\n", "\n       "))
+		}
+	}
+}
+
+// Warnings don't fail the command, as the environment may still work.
+func get_checks_error(results []checkResult) error {
+	failed := 0
+	for _, r := range results {
+		if r.Status == CHECK_FAIL {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("\n%d of %d checks failed.", failed, len(results))
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package cmd_test
 
 import (
 	"bytes"
+	"path/filepath"
 	"testing"
 
 	"github.com/dfinity/ic/rs/tests/ict/cmd"
@@ -10,6 +11,7 @@ import (
 
 func Test_RootCmdWitNoArgs(t *testing.T) {
 	expected := "A simple CLI for running system_tests in Bazel"
+	isolateConfig(t)
 	actual := new(bytes.Buffer)
 	var command = cmd.NewRootCmd()
 	command.SetOut(actual)
@@ -85,3 +87,57 @@ func Test_ListCmdWithOneArg(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.Contains(t, actual.String(), expected)
 }
+
+func Test_QueryCmdWithNoArgs(t *testing.T) {
+	expected := "requires at least 1 arg(s), only received 0"
+	actual := new(bytes.Buffer)
+	var command = cmd.NewQueryCmd()
+	command.SetOut(actual)
+	command.SetErr(actual)
+
+	err := command.Execute()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, actual.String(), expected)
+}
+
+func Test_TestCmdWithBazelFlagBeforeDash(t *testing.T) {
+	expected := "unknown flag: --test_output"
+	actual := new(bytes.Buffer)
+	var command = cmd.NewTestCmd()
+	command.SetOut(actual)
+	command.SetErr(actual)
+	command.SetArgs([]string{"basic_health_test", "--test_output=errors", "--", "--runs_per_test=3"})
+
+	err := command.Execute()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, actual.String(), expected)
+}
+
+func Test_RemoteRequiresExecutor(t *testing.T) {
+	tests := []struct {
+		userBazelrc string
+		bazelArgs   []string
+		isValid     bool
+	}{
+		{"", []string{}, false},
+		{"build:remote --remote_executor=grpcs://buildfarm:443\n", []string{}, true},
+		{"build --remote_executor grpcs://buildfarm:443\n", []string{}, true},
+		{"build:remote --remote_executor=\n", []string{}, false},
+		{"# build:remote --remote_executor=grpcs://buildfarm:443\n", []string{}, false},
+		{"", []string{"--remote_executor=grpcs://buildfarm:443"}, true},
+	}
+	for _, test := range tests {
+		_, repoConfig := isolateConfig(t)
+		writeConfig(t, filepath.Join(filepath.Dir(repoConfig), "user.bazelrc"), test.userBazelrc)
+
+		err := cmd.CheckRemoteExecutor(test.bazelArgs)
+
+		if test.isValid {
+			assert.Nil(t, err, test.userBazelrc)
+		} else {
+			assert.ErrorContains(t, err, "user.bazelrc", test.userBazelrc)
+		}
+	}
+}
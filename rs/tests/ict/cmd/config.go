@@ -0,0 +1,277 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Defaults of the flags are read from the config file of the user and the one of the repo, the latter taking precedence.
+// Flags given on the command line take precedence over both, as well as over ICT_* environment variables.
+var USER_CONFIG_FILE = filepath.Join("ict", "config.toml")
+var REPO_CONFIG_FILE = ".ict.toml"
+
+// Key of the bazel flags appended to each bazel test command, before the bazel args given after --.
+var BAZEL_FLAGS_CONFIG_KEY = "bazel-flags"
+
+// Bazel flags of the config files, the ones of the repo come last, so that they take precedence.
+var CONFIG_BAZEL_FLAGS = []string{}
+
+// Named profiles bundle flags in sections like [profile.debug] and are selected via --profile debug.
+// Their keys take precedence over the top-level keys of both config files.
+var PROFILE_FLAG = "profile"
+var PROFILE_SECTION_PREFIX = "profile."
+
+// Short names of targets in the [alias] section, e.g. `nnsup = "//rs/tests/nns:nns_upgrade_test"`, resolved before matching.
+var ALIAS_SECTION = "alias"
+var TARGET_ALIASES = map[string]string{}
+
+// Each flag can also be set via an environment variable, e.g. ICT_FARM_DC for --farm-dc, taking precedence over the config files.
+// Values of array flags are separated by commas, the ones of ICT_BAZEL_FLAGS by whitespace.
+var ENV_PREFIX = "ICT_"
+
+type configValue struct {
+	values []string
+	// File and section, in which the value is defined.
+	source string
+}
+
+type configProfile struct {
+	values     map[string]configValue
+	bazelFlags []string
+}
+
+// Keys of the config files, the ones of later files replacing the ones of earlier files.
+type configFiles struct {
+	values     map[string]configValue
+	bazelFlags []string
+	profiles   map[string]*configProfile
+	aliases    map[string]string
+}
+
+// Same as $XDG_CONFIG_HOME, i.e. ~/.config by default, also on macOS.
+func get_user_config_file() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); len(dir) > 0 {
+		return filepath.Join(dir, USER_CONFIG_FILE), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", USER_CONFIG_FILE), nil
+}
+
+func get_config_files() []string {
+	files := []string{}
+	if user_file, err := get_user_config_file(); err == nil {
+		files = append(files, user_file)
+	}
+	if root, _, ok := find_workspace_root(); ok {
+		files = append(files, filepath.Join(root, REPO_CONFIG_FILE))
+	}
+	return files
+}
+
+// Missing config files are skipped, keys are flag names, in which `_` may be used instead of `-`.
+func load_config_files(files []string) (*configFiles, error) {
+	config := configFiles{values: map[string]configValue{}, bazelFlags: []string{}, profiles: map[string]*configProfile{}, aliases: map[string]string{}}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		doc, err := parse_toml(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", file, err)
+		}
+		for alias, target := range doc[ALIAS_SECTION] {
+			if len(target) != 1 {
+				return nil, fmt.Errorf("%s [%s]: alias `%s` should stand for a single target", file, ALIAS_SECTION, alias)
+			}
+			config.aliases[alias] = target[0]
+		}
+		for section, keys := range doc {
+			if section == ALIAS_SECTION {
+				continue
+			}
+			values, bazel_flags, source := config.values, &config.bazelFlags, file
+			if len(section) > 0 {
+				name := strings.TrimPrefix(section, PROFILE_SECTION_PREFIX)
+				if name == section || len(name) == 0 {
+					return nil, fmt.Errorf("%s: unknown section [%s], expected [%s] or [%s<name>]", file, section, ALIAS_SECTION, PROFILE_SECTION_PREFIX)
+				}
+				if _, ok := config.profiles[name]; !ok {
+					config.profiles[name] = &configProfile{values: map[string]configValue{}, bazelFlags: []string{}}
+				}
+				values, bazel_flags, source = config.profiles[name].values, &config.profiles[name].bazelFlags, fmt.Sprintf("%s [%s]", file, section)
+			}
+			for key, value := range keys {
+				key = strings.ReplaceAll(key, "_", "-")
+				if key == BAZEL_FLAGS_CONFIG_KEY {
+					*bazel_flags = append(*bazel_flags, value...)
+				} else if key == PROFILE_FLAG && len(section) > 0 {
+					return nil, fmt.Errorf("%s: profiles can't select other profiles", source)
+				} else {
+					values[key] = configValue{values: value, source: source}
+				}
+			}
+		}
+	}
+	return &config, nil
+}
+
+func get_profile_names(config *configFiles) []string {
+	names := []string{}
+	for name := range config.profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environment variables of unknown flags are skipped, as other tools may use the same prefix.
+func get_env_values(flags map[string][]*pflag.Flag) (map[string]configValue, []string, []string) {
+	values := map[string]configValue{}
+	bazel_flags := []string{}
+	skipped := []string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(name, ENV_PREFIX) || len(value) == 0 {
+			continue
+		}
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, ENV_PREFIX), "_", "-"))
+		if key == BAZEL_FLAGS_CONFIG_KEY {
+			bazel_flags = append(bazel_flags, strings.Fields(value)...)
+		} else if f, ok := flags[key]; ok && strings.HasSuffix(f[0].Value.Type(), "Array") {
+			values[key] = configValue{values: strings.Split(value, ","), source: "$" + name}
+		} else if ok {
+			values[key] = configValue{values: []string{value}, source: "$" + name}
+		} else {
+			skipped = append(skipped, name)
+		}
+	}
+	sort.Strings(skipped)
+	return values, bazel_flags, skipped
+}
+
+// The profile given on the command line, otherwise the one of the environment or the config files, if any.
+func select_profile(config *configFiles, env_values map[string]configValue, profile_flag *pflag.Flag) (map[string]configValue, []string, error) {
+	name := profile_flag.Value.String()
+	if value, ok := env_values[PROFILE_FLAG]; ok && !profile_flag.Changed {
+		name = value.values[0]
+	} else if value, ok := config.values[PROFILE_FLAG]; ok && !profile_flag.Changed {
+		name = value.values[0]
+	}
+	if len(name) == 0 {
+		return map[string]configValue{}, []string{}, nil
+	}
+	profile, ok := config.profiles[name]
+	if !ok {
+		names := get_profile_names(config)
+		if len(names) == 0 {
+			return nil, nil, fmt.Errorf("no profile `%s` is defined, profiles are defined in [%s<name>] sections of %s", name, PROFILE_SECTION_PREFIX, strings.Join(get_config_files(), " or "))
+		}
+		return nil, nil, fmt.Errorf("no profile `%s` is defined, the defined profiles are: %s", name, strings.Join(names, ", "))
+	}
+	return profile.values, profile.bazelFlags, nil
+}
+
+func get_all_flags(root *cobra.Command) map[string][]*pflag.Flag {
+	flags := map[string][]*pflag.Flag{}
+	seen := map[*pflag.Flag]bool{}
+	var visit func(cmd *cobra.Command)
+	visit = func(cmd *cobra.Command) {
+		for _, set := range []*pflag.FlagSet{cmd.Flags(), cmd.PersistentFlags()} {
+			set.VisitAll(func(f *pflag.Flag) {
+				if !seen[f] {
+					seen[f] = true
+					flags[f.Name] = append(flags[f.Name], f)
+				}
+			})
+		}
+		for _, sub := range cmd.Commands() {
+			visit(sub)
+		}
+	}
+	visit(root)
+	return flags
+}
+
+// Values are set on the flags directly, rather than via the flag set, so that they don't count as given on the command line,
+// e.g. for flags which are mutually exclusive.
+func set_flag_default(f *pflag.Flag, values []string) error {
+	if strings.HasSuffix(f.Value.Type(), "Array") {
+		for _, value := range values {
+			if err := f.Value.Set(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(values) > 1 && !strings.HasSuffix(f.Value.Type(), "Slice") {
+		return fmt.Errorf("option --%s takes a single value, not an array", f.Name)
+	}
+	return f.Value.Set(strings.Join(values, ","))
+}
+
+// Applied to the flags of all commands, of which the executed one validates its args again afterwards.
+func apply_config_defaults(flags map[string][]*pflag.Flag, values map[string]configValue) ([]string, error) {
+	applied := []string{}
+	keys := []string{}
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := values[key]
+		if _, ok := flags[key]; !ok {
+			return nil, fmt.Errorf("%s: unknown option `%s`, keys are names of options, e.g. farm-dc", value.source, key)
+		}
+		for _, f := range flags[key] {
+			if f.Changed {
+				continue
+			}
+			if err := set_flag_default(f, value.values); err != nil {
+				return nil, fmt.Errorf("%s: invalid value of `%s`: %s", value.source, key, err)
+			}
+		}
+		applied = append(applied, fmt.Sprintf("%s = %s (%s)", key, strings.Join(value.values, ","), value.source))
+	}
+	return applied, nil
+}
+
+func load_config(root *cobra.Command) ([]string, error) {
+	config, err := load_config_files(get_config_files())
+	if err != nil {
+		return nil, err
+	}
+	flags := get_all_flags(root)
+	env_values, env_bazel_flags, skipped := get_env_values(flags)
+	profile_values, profile_bazel_flags, err := select_profile(config, env_values, root.PersistentFlags().Lookup(PROFILE_FLAG))
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]configValue{}
+	for _, layer := range []map[string]configValue{config.values, profile_values, env_values} {
+		for key, value := range layer {
+			values[key] = value
+		}
+	}
+	TARGET_ALIASES = config.aliases
+	CONFIG_BAZEL_FLAGS = append(append(append([]string{}, config.bazelFlags...), profile_bazel_flags...), env_bazel_flags...)
+	applied, err := apply_config_defaults(flags, values)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range skipped {
+		applied = append(applied, fmt.Sprintf("$%s skipped, as there is no option --%s", name, strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, ENV_PREFIX), "_", "-"))))
+	}
+	return applied, nil
+}
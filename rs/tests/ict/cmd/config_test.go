@@ -0,0 +1,258 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+// Runs in a workspace of its own, so that neither the config of the user nor the one of this repo is loaded.
+// Returns the paths of the user's and the repo's config files.
+func isolateConfig(t *testing.T) (string, string) {
+	isolateState(t)
+	configHome := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+	for _, env := range os.Environ() {
+		// Empty environment variables are skipped like unset ones.
+		if name, _, _ := strings.Cut(env, "="); strings.HasPrefix(name, "ICT_") {
+			t.Setenv(name, "")
+		}
+	}
+	workspace := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(workspace, "WORKSPACE.bazel"), []byte{}, 0644))
+	cwd, err := os.Getwd()
+	assert.Nil(t, err)
+	assert.Nil(t, os.Chdir(workspace))
+	t.Cleanup(func() { os.Chdir(cwd) })
+	return filepath.Join(configHome, "ict", "config.toml"), filepath.Join(workspace, ".ict.toml")
+}
+
+func writeConfig(t *testing.T, file string, content string) {
+	if len(content) == 0 {
+		return
+	}
+	assert.Nil(t, os.MkdirAll(filepath.Dir(file), 0755))
+	assert.Nil(t, os.WriteFile(file, []byte(content), 0644))
+}
+
+// The config is applied to the flags of all commands, `ict recent` lists the (empty) history without side effects.
+func newConfigRootCmd(actual *bytes.Buffer) (*cobra.Command, *cobra.Command) {
+	var command = cmd.NewRootCmd()
+	var recent = cmd.NewRecentCmd()
+	command.AddCommand(recent)
+	command.SetOut(actual)
+	command.SetErr(actual)
+	recent.SetOut(actual)
+	return command, recent
+}
+
+func Test_ParseToml(t *testing.T) {
+	content := `# Comments and blank lines are skipped.
+farm-dc = "zh1" # trailing comment
+group = "a # b"
+env = [
+  "A=\t\"quoted\"", # comment in an array
+  'C:\path',
+]
+retries = 3
+timeout_factor = 1.5
+keepalive = true
+
+[alias]
+nnsup = "//rs/tests/nns:nns_upgrade_test"
+
+[profile.debug]
+bazel-flags = ["--config=debug"]
+
+[profile.empty]
+`
+	expected := cmd.TomlDocument{
+		"": {
+			"farm-dc":        {"zh1"},
+			"group":          {"a # b"},
+			"env":            {"A=\t\"quoted\"", `C:\path`},
+			"retries":        {"3"},
+			"timeout_factor": {"1.5"},
+			"keepalive":      {"true"},
+		},
+		"alias":         {"nnsup": {"//rs/tests/nns:nns_upgrade_test"}},
+		"profile.debug": {"bazel-flags": {"--config=debug"}},
+		"profile.empty": {},
+	}
+
+	actual, err := cmd.ParseToml(content)
+
+	assert.Nil(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func Test_ParseTomlErrors(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"farm-dc = \"zh1\"\nfarm-dc = \"sf1\"\n", "key farm-dc is already defined"},
+		{"[profile.a]\nretries = 1\n[profile.a]\n", "table a already exists"},
+		{"env = [[\"A=1\"]]\n", "key `env`: nested arrays aren't supported"},
+		{"env = [{ a = 1 }]\n", "key `env`: inline tables aren't supported in arrays"},
+		{"env = [\"A=1\",\n", "line 2: expected value, not eof"},
+		{"farm-dc = zh1\n", "line 1:"},
+		{"keepalive = true\nfarm-dc = \"zh1\n", "line 2:"},
+		{"started = 2023-01-01\n", "key `started`: unsupported value"},
+	}
+	for _, test := range tests {
+		_, err := cmd.ParseToml(test.content)
+
+		assert.ErrorContains(t, err, test.expected, test.content)
+	}
+}
+
+func Test_SetTomlKey(t *testing.T) {
+	tests := []struct {
+		content  string
+		key      string
+		value    string
+		expected string
+	}{
+		{"", "farm-dc", "zh1", "farm-dc = \"zh1\"\n"},
+		{"# mine\nfarm-dc = \"sf1\" # old\nretries = 2\n", "farm-dc", "zh1", "# mine\nfarm-dc = \"zh1\"\nretries = 2\n"},
+		{"farm_dc = \"sf1\"\n", "farm-dc", "zh1", "farm-dc = \"zh1\"\n"},
+		{"retries = 2\n\n[alias]\nfarm-dc = \"x\"\n", "farm-dc", "zh1", "retries = 2\nfarm-dc = \"zh1\"\n\n[alias]\nfarm-dc = \"x\"\n"},
+		{"[profile.debug]\nretries = 2\n", "farm-dc", "zh1", "farm-dc = \"zh1\"\n\n[profile.debug]\nretries = 2\n"},
+		{"farm-dc = \"sf1\"\nretries = 2\n", "farm-dc", "", "retries = 2\n"},
+	}
+	for _, test := range tests {
+		actual := cmd.SetTomlKey(test.content, test.key, test.value)
+
+		assert.Equal(t, test.expected, actual, test.content)
+	}
+}
+
+func Test_SetTomlKeyRoundTrips(t *testing.T) {
+	content := "# mine\nretries = 2\n\n[alias]\nnnsup = \"//rs/tests/nns:nns_upgrade_test\"\n"
+	for _, value := range []string{"zh1", "/home/a b/artifacts", `C:\dir`, `say "hi" # not a comment`, "tab\there"} {
+		updated := cmd.SetTomlKey(content, "artifacts-dir", value)
+		actual, err := cmd.ParseToml(updated)
+
+		assert.Nil(t, err, updated)
+		assert.Equal(t, []string{value}, actual[""]["artifacts-dir"])
+		assert.Equal(t, []string{"2"}, actual[""]["retries"])
+		assert.Equal(t, []string{"//rs/tests/nns:nns_upgrade_test"}, actual["alias"]["nnsup"])
+		assert.Equal(t, updated, cmd.SetTomlKey(updated, "artifacts-dir", value))
+	}
+}
+
+// Config of the user < config of the repo < profile < ICT_* environment < command line.
+func Test_ConfigLayering(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		repo     string
+		env      map[string]string
+		args     []string
+		expected string
+	}{
+		{"user config", "limit = 1\n", "", nil, nil, "1"},
+		{"repo config", "limit = 1\n", "limit = 2\n", nil, nil, "2"},
+		{"profile flag", "limit = 1\n", "limit = 2\n[profile.p]\nlimit = 3\n", nil, []string{"--profile", "p"}, "3"},
+		{"profile of the config", "limit = 1\nprofile = \"p\"\n", "[profile.p]\nlimit = 3\n", nil, nil, "3"},
+		{"profile of the environment", "limit = 1\n", "[profile.p]\nlimit = 3\n", map[string]string{"ICT_PROFILE": "p"}, nil, "3"},
+		{"environment", "limit = 1\n", "[profile.p]\nlimit = 3\n", map[string]string{"ICT_LIMIT": "4"}, []string{"--profile", "p"}, "4"},
+		{"command line", "limit = 1\n", "[profile.p]\nlimit = 3\n", map[string]string{"ICT_LIMIT": "4"}, []string{"--profile", "p", "--limit", "5"}, "5"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userConfig, repoConfig := isolateConfig(t)
+			writeConfig(t, userConfig, test.user)
+			writeConfig(t, repoConfig, test.repo)
+			for name, value := range test.env {
+				t.Setenv(name, value)
+			}
+			actual := new(bytes.Buffer)
+			command, recent := newConfigRootCmd(actual)
+			command.SetArgs(append([]string{"recent", "--json"}, test.args...))
+
+			err := command.Execute()
+
+			assert.Nil(t, err, actual.String())
+			assert.Equal(t, test.expected, recent.Flags().Lookup("limit").Value.String())
+		})
+	}
+}
+
+func Test_ConfigBazelFlagsAndAliases(t *testing.T) {
+	userConfig, repoConfig := isolateConfig(t)
+	writeConfig(t, userConfig, "bazel-flags = [\"--user\"]\n\n[alias]\nnnsup = \"//rs/tests/nns:nns_upgrade_test\"\n")
+	writeConfig(t, repoConfig, "bazel_flags = [\"--repo\"]\n\n[profile.p]\nbazel-flags = [\"--profile\"]\n")
+	t.Setenv("ICT_BAZEL_FLAGS", "--env1 --env2")
+	actual := new(bytes.Buffer)
+	command, _ := newConfigRootCmd(actual)
+	command.SetArgs([]string{"recent", "--profile", "p"})
+
+	err := command.Execute()
+
+	assert.Nil(t, err, actual.String())
+	assert.Equal(t, []string{"--user", "--repo", "--profile", "--env1", "--env2"}, cmd.GetConfigBazelFlags())
+	alias, ok := cmd.ResolveTargetAlias("nnsup")
+	assert.True(t, ok)
+	assert.Equal(t, "//rs/tests/nns:nns_upgrade_test", alias)
+}
+
+func Test_ConfigValuesAreValidated(t *testing.T) {
+	expected := "option --limit should be >= 1."
+	userConfig, _ := isolateConfig(t)
+	writeConfig(t, userConfig, "limit = 0\n")
+	actual := new(bytes.Buffer)
+	command, _ := newConfigRootCmd(actual)
+	command.SetArgs([]string{"recent"})
+
+	err := command.Execute()
+
+	assert.EqualError(t, err, expected)
+}
+
+func Test_InvalidConfigFailsCommands(t *testing.T) {
+	expected := "unknown option `bogus`"
+	userConfig, _ := isolateConfig(t)
+	writeConfig(t, userConfig, "bogus = 1\n")
+	actual := new(bytes.Buffer)
+	command, _ := newConfigRootCmd(actual)
+	command.AddCommand(&cobra.Command{Use: "noop", RunE: func(cmd *cobra.Command, args []string) error { return nil }})
+	command.SetArgs([]string{"noop"})
+
+	err := command.Execute()
+
+	assert.ErrorContains(t, err, expected)
+}
+
+func Test_InvalidConfigIsIgnoredByRecent(t *testing.T) {
+	expected := "Ignoring the invalid config"
+	userConfig, _ := isolateConfig(t)
+	writeConfig(t, userConfig, "bogus = 1\n")
+	actual := new(bytes.Buffer)
+	command, _ := newConfigRootCmd(actual)
+	command.SetArgs([]string{"recent"})
+
+	err := command.Execute()
+
+	assert.Nil(t, err)
+	assert.Contains(t, actual.String(), expected)
+}
+
+func Test_ProfileFileHintsAtBazelProfile(t *testing.T) {
+	expected := "option --profile selects a profile of the config, Bazel's trace profile `trace.json.gz` is written via --bazel-profile."
+	isolateConfig(t)
+	actual := new(bytes.Buffer)
+	command, _ := newConfigRootCmd(actual)
+	command.SetArgs([]string{"recent", "--profile", "trace.json.gz"})
+
+	err := command.Execute()
+
+	assert.EqualError(t, err, expected)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Bazel merges the LCOV reports of all instrumented targets into this file, if run with --combined_report=lcov.
+var COVERAGE_REPORT_FILE = "bazel-out/_coverage/_coverage_report.dat"
+
+// Only the code of the repo is instrumented, i.e. neither external crates nor the toolchains.
+var COVERAGE_INSTRUMENTATION_FILTER = "^//rs[/:]"
+
+type crateCoverage struct {
+	Crate string
+	Lines int
+	Hit   int
+}
+
+// Coverage flags are only added if not set via the Bazel args already.
+func get_coverage_flags(bazel_args []string) []string {
+	flags := []string{}
+	if !any_contains_substring(bazel_args, "--combined_report") {
+		flags = append(flags, "--combined_report=lcov")
+	}
+	if !any_contains_substring(bazel_args, "--instrumentation_filter") {
+		flags = append(flags, "--instrumentation_filter="+COVERAGE_INSTRUMENTATION_FILTER)
+	}
+	// System tests are test targets themselves, which Bazel doesn't instrument by default.
+	if !any_contains_substring(bazel_args, "instrument_test_targets") {
+		flags = append(flags, "--instrument_test_targets")
+	}
+	return flags
+}
+
+// Source files of a crate are in its src directory, e.g. rs/nns/governance/src/governance.rs belongs to rs/nns/governance.
+func get_crate_of_source_file(path string) string {
+	if idx := strings.Index(path, "/src/"); idx >= 0 {
+		return path[:idx]
+	}
+	return filepath.Dir(path)
+}
+
+// Computes the line coverage of each crate from the SF (source file), DA (line hits) records of the LCOV report.
+func read_crate_coverage(path string) ([]crateCoverage, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	crates := map[string]*crateCoverage{}
+	var current *crateCoverage
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "SF:") {
+			crate := get_crate_of_source_file(strings.TrimPrefix(line, "SF:"))
+			if _, ok := crates[crate]; !ok {
+				crates[crate] = &crateCoverage{Crate: crate}
+			}
+			current = crates[crate]
+		} else if strings.HasPrefix(line, "DA:") && current != nil {
+			fields := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(fields) < 2 {
+				continue
+			}
+			current.Lines++
+			if hits, err := strconv.ParseInt(fields[1], 10, 64); err == nil && hits > 0 {
+				current.Hit++
+			}
+		} else if line == "end_of_record" {
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	coverage := []crateCoverage{}
+	for _, crate := range crates {
+		if crate.Lines > 0 {
+			coverage = append(coverage, *crate)
+		}
+	}
+	sort.Slice(coverage, func(i, j int) bool {
+		return coverage[i].Crate < coverage[j].Crate
+	})
+	return coverage, nil
+}
+
+// Prints the line coverage of the crates exercised by the tests and optionally renders the report as HTML via genhtml.
+// Only code run by the test driver counts, the replicas on the Farm VMs aren't instrumented.
+func print_coverage_summary(cmd *cobra.Command, html_dir string) error {
+	report := filepath.Join(get_workspace_root(), COVERAGE_REPORT_FILE)
+	coverage, err := read_crate_coverage(report)
+	if err != nil {
+		return fmt.Errorf("\nFailed to read the coverage report: %s", err)
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CRATE\tLINES\tCOVERED\tCOVERAGE")
+	lines, hit := 0, 0
+	for _, crate := range coverage {
+		// Crates with no line exercised are noise, they only happen to be linked into the test.
+		if crate.Hit == 0 {
+			continue
+		}
+		lines += crate.Lines
+		hit += crate.Hit
+		fmt.Fprintf(w, "%s\t%d\t%d\t%.1f%%\n", crate.Crate, crate.Lines, crate.Hit, 100*float64(crate.Hit)/float64(crate.Lines))
+	}
+	if lines > 0 {
+		fmt.Fprintf(w, "TOTAL\t%d\t%d\t%.1f%%\n", lines, hit, 100*float64(hit)/float64(lines))
+	}
+	w.Flush()
+	cmd.Printf("%sLCOV report: %s%s\n", CYAN, report, NC)
+	if len(html_dir) == 0 {
+		return nil
+	}
+	if _, err := exec.LookPath("genhtml"); err != nil {
+		return fmt.Errorf("\nRendering the coverage report as HTML requires genhtml (part of lcov) to be installed.")
+	}
+	command := []string{"genhtml", report, "--output-directory", html_dir, "--quiet"}
+	genhtml := exec.Command(command[0], command[1:]...)
+	genhtml.Dir = get_workspace_root()
+	genhtml.Stderr = os.Stderr
+	traced := trace_command(command)
+	err = genhtml.Run()
+	traced(err)
+	if err != nil {
+		return fmt.Errorf("\nFailed to render the coverage report as HTML: %s", err)
+	}
+	cmd.Printf("%sHTML coverage report: %s%s\n", CYAN, filepath.Join(html_dir, "index.html"), NC)
+	return nil
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// IC-OS images and the bazel caches of the system tests take up tens of GiBs.
+var DOCTOR_MIN_FREE_DISK_GIB = uint64(20)
+var DOCTOR_WARN_FREE_DISK_GIB = uint64(50)
+
+// Same as WORKDIR and --hostname of gitlab-ci/container/container-run.sh
+var CONTAINER_WORKDIR = "/ic"
+var CONTAINER_HOSTNAME = "devenv-container"
+
+type DoctorConfig struct {
+	isJson bool
+}
+
+func check_workspace() checkResult {
+	result := checkResult{Name: "Repo location", Hint: "Run ict from within a checkout of the IC repo, i.e. github.com/dfinity/ic."}
+	workspace, err := get_ic_workspace_root()
+	if err != nil {
+		result.Status, result.Message = CHECK_FAIL, strings.TrimSpace(err.Error())
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, workspace
+	return result
+}
+
+// IC-OS images are only built reproducibly within the build container.
+func check_container(workspace string) checkResult {
+	result := checkResult{Name: "Build container", Hint: "Run ict within the build container via:\n$ ./gitlab-ci/container/container-run.sh"}
+	hostname, _ := os.Hostname()
+	if workspace == CONTAINER_WORKDIR || hostname == CONTAINER_HOSTNAME {
+		result.Status, result.Message = CHECK_PASS, "running within the build container"
+		return result
+	}
+	result.Status, result.Message = CHECK_WARN, "not running within the build container"
+	return result
+}
+
+func check_bazel_version(workspace string) checkResult {
+	result := checkResult{Name: "Bazel", Hint: "Install bazelisk, which picks the version of .bazelversion, see https://github.com/bazelbuild/bazelisk"}
+	output, err := exec.Command("bazel", "--version").Output()
+	if err != nil {
+		result.Status, result.Message = CHECK_FAIL, fmt.Sprintf("`bazel --version` failed: %s", err)
+		return result
+	}
+	version := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(output)), "bazel"))
+	content, err := os.ReadFile(filepath.Join(workspace, ".bazelversion"))
+	if err != nil {
+		result.Status, result.Message = CHECK_PASS, fmt.Sprintf("version %s", version)
+		return result
+	}
+	if expected := strings.TrimSpace(string(content)); version != expected {
+		result.Status, result.Message = CHECK_WARN, fmt.Sprintf("version %s differs from %s of .bazelversion", version, expected)
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, fmt.Sprintf("version %s", version)
+	return result
+}
+
+func get_free_disk_gib(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize) / (1 << 30), nil
+}
+
+// Bazel keeps its output base in ~/.cache/bazel, which is usually on another disk than the workspace.
+func check_disk_space(name string, path string) checkResult {
+	result := checkResult{Name: name, Hint: "Free up space, e.g. via:\n$ bazel clean --expunge"}
+	free, err := get_free_disk_gib(path)
+	if err != nil {
+		result.Status, result.Message = CHECK_WARN, fmt.Sprintf("the free space of %s is unknown: %s", path, err)
+		return result
+	}
+	result.Message = fmt.Sprintf("%d GiB free on %s", free, path)
+	if free < DOCTOR_MIN_FREE_DISK_GIB {
+		result.Status = CHECK_FAIL
+	} else if free < DOCTOR_WARN_FREE_DISK_GIB {
+		result.Status = CHECK_WARN
+	} else {
+		result.Status = CHECK_PASS
+	}
+	return result
+}
+
+// Anonymous pulls of the build container from Docker Hub are rate-limited, see gitlab-ci/container/container-run.sh
+func check_docker_credentials() checkResult {
+	result := checkResult{Name: "Docker Hub credentials", Hint: "Log in to Docker Hub via:\n$ docker login"}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		result.Status, result.Message = CHECK_WARN, err.Error()
+		return result
+	}
+	content, err := os.ReadFile(filepath.Join(home, ".docker", "config.json"))
+	if err != nil || !strings.Contains(string(content), "index.docker.io") {
+		result.Status, result.Message = CHECK_WARN, "not logged in, pulls of the build container are rate-limited"
+		return result
+	}
+	result.Status, result.Message = CHECK_PASS, "logged in"
+	return result
+}
+
+func check_executable(name string, status checkStatus, hint string, executables ...string) checkResult {
+	result := checkResult{Name: name, Hint: hint}
+	for _, executable := range executables {
+		if path, err := exec.LookPath(executable); err == nil {
+			result.Status, result.Message = CHECK_PASS, path
+			return result
+		}
+	}
+	result.Status, result.Message = status, fmt.Sprintf("%s wasn't found on PATH", strings.Join(executables, " or "))
+	return result
+}
+
+func get_doctor_checks() []checkResult {
+	workspace := get_workspace_root()
+	container := check_container(workspace)
+	results := []checkResult{check_workspace(), container, check_bazel_version(workspace), check_disk_space("Disk space (workspace)", workspace)}
+	if cache_dir, err := os.UserCacheDir(); err == nil {
+		if _, err := os.Stat(filepath.Join(cache_dir, "bazel")); err == nil {
+			results = append(results, check_disk_space("Disk space (bazel cache)", filepath.Join(cache_dir, "bazel")))
+		}
+	}
+	results = append(results, check_ssh_agent(), check_docker_credentials())
+	// Podman is only needed outside the build container, while nix is only used for the tooling of some teams.
+	container_status := CHECK_WARN
+	if container.Status != CHECK_PASS {
+		container_status = CHECK_FAIL
+	}
+	results = append(results, check_executable("Container runtime", container_status, "Install podman, which gitlab-ci/container/container-run.sh runs the build container with.", "podman", "docker"))
+	return append(results, check_executable("Nix", CHECK_WARN, "Install nix, if you use the nix shells of the repo, see https://nixos.org/download", "nix"))
+}
+
+func DoctorCommand(cfg *DoctorConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		results := get_doctor_checks()
+		if cfg.isJson {
+			if err := print_json(cmd, results); err != nil {
+				return err
+			}
+			return get_checks_error(results)
+		}
+		print_check_results(cmd, results)
+		cmd.Printf("%sCheck the VPN and IPv6 connectivity to Farm via:\n$ ict check-connectivity%s\n", CYAN, NC)
+		return get_checks_error(results)
+	}
+}
+
+func NewDoctorCmd() *cobra.Command {
+	var cfg = DoctorConfig{}
+	var cmd = &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the repo location, bazel version, disk space, credentials and tools needed to run system tests",
+		Example: "ict doctor\nict doctor --json",
+		Args:    cobra.NoArgs,
+		RunE:    DoctorCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the results of the checks as JSON.")
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment kept alive via --keepalive, which can be reused by later runs via --reuse-env.
+type keptAliveEnv struct {
+	Group     string    `json:"group"`
+	Target    string    `json:"target"`
+	SetupDir  string    `json:"setup_dir"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// VMs of the group as logged by the test driver, along with the subnets of the IC nodes.
+	Vms []keptAliveVm `json:"vms,omitempty"`
+	// Only set for groups with a Prometheus VM.
+	PrometheusUrl string `json:"prometheus_url,omitempty"`
+	GrafanaUrl    string `json:"grafana_url,omitempty"`
+	// Imported via `ict testnet import`, its setup directory only holds the SSH key and the boundary nodes of the group.
+	Imported bool `json:"imported,omitempty"`
+}
+
+func get_kept_alive_envs_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "kept_alive_envs.json"), nil
+}
+
+// Kept alive runs get their own test tmpdir, so that setup directories of the test driver outlive the run.
+func get_kept_alive_test_tmpdir() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "envs", strconv.FormatInt(time.Now().UnixMilli(), 10)), nil
+}
+
+// Returns the value of the last occurrence of the flag in the command, as Bazel uses the last one.
+func get_last_flag_value(command []string, flag string) (string, bool) {
+	value, found := "", false
+	for _, arg := range command {
+		if strings.HasPrefix(arg, flag+"=") {
+			value, found = strings.TrimPrefix(arg, flag+"="), true
+		}
+	}
+	return value, found
+}
+
+// Returns kept alive environments, which haven't expired yet.
+func load_kept_alive_envs() []keptAliveEnv {
+	envs := []keptAliveEnv{}
+	envs_file, err := get_kept_alive_envs_file()
+	if err != nil {
+		return envs
+	}
+	if content, err := os.ReadFile(envs_file); err == nil {
+		json.Unmarshal(content, &envs)
+	}
+	return filter_kept_alive_envs(envs, func(env *keptAliveEnv) bool {
+		return time.Now().Before(env.ExpiresAt)
+	})
+}
+
+func filter_kept_alive_envs(envs []keptAliveEnv, f func(*keptAliveEnv) bool) []keptAliveEnv {
+	filtered := make([]keptAliveEnv, 0)
+	for i := range envs {
+		if f(&envs[i]) {
+			filtered = append(filtered, envs[i])
+		}
+	}
+	return filtered
+}
+
+func record_kept_alive_env(env keptAliveEnv) error {
+	envs_file, err := get_kept_alive_envs_file()
+	if err != nil {
+		return err
+	}
+	envs := filter_kept_alive_envs(load_kept_alive_envs(), func(e *keptAliveEnv) bool {
+		return e.Group != env.Group
+	})
+	envs = append(envs, env)
+	if err := os.MkdirAll(filepath.Dir(envs_file), 0755); err != nil {
+		return err
+	}
+	content, err := json.Marshal(envs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(envs_file, content, 0644)
+}
+
+func remove_kept_alive_envs(groups []string) error {
+	envs_file, err := get_kept_alive_envs_file()
+	if err != nil {
+		return err
+	}
+	envs := filter_kept_alive_envs(load_kept_alive_envs(), func(e *keptAliveEnv) bool {
+		return !any_equals(groups, e.Group)
+	})
+	content, err := json.Marshal(envs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(envs_file, content, 0644)
+}
+
+// Applies the change to the recorded environment of the group, e.g. once its topology changed via proposals.
+func update_kept_alive_env(group string, update func(*keptAliveEnv)) error {
+	for _, env := range load_kept_alive_envs() {
+		if env.Group == group {
+			update(&env)
+			return record_kept_alive_env(env)
+		}
+	}
+	return fmt.Errorf("Farm group %s isn't kept alive by ict on this machine", group)
+}
+
+// The test driver stores the setup of a group in <working_dir>/setup/group_setup.json.
+func find_setup_dir(test_tmpdir string, group string) (string, bool) {
+	setup_dir := ""
+	filepath.WalkDir(test_tmpdir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || len(setup_dir) > 0 {
+			return filepath.SkipDir
+		}
+		if d.Name() != "group_setup.json" || filepath.Base(filepath.Dir(path)) != "setup" {
+			return nil
+		}
+		var group_setup struct {
+			FarmGroupName string `json:"farm_group_name"`
+		}
+		if content, err := os.ReadFile(path); err == nil && json.Unmarshal(content, &group_setup) == nil {
+			if len(group) == 0 || group_setup.FarmGroupName == group {
+				setup_dir = filepath.Dir(path)
+			}
+		}
+		return nil
+	})
+	return setup_dir, len(setup_dir) > 0
+}
+
+// Farm group names are derived from the test binary, i.e. <target_name>--<timestamp>.
+func find_target_of_group(targets []string, group string) string {
+	for _, target := range targets {
+		if strings.HasPrefix(group, get_label_name(target)+"--") {
+			return target
+		}
+	}
+	if len(targets) == 1 {
+		return targets[0]
+	}
+	return ""
+}
+
+func find_kept_alive_env(group string) (*keptAliveEnv, error) {
+	envs := load_kept_alive_envs()
+	for i := range envs {
+		if envs[i].Group == group {
+			if _, err := os.Stat(envs[i].SetupDir); err != nil {
+				return nil, fmt.Errorf("\nSetup directory %s of environment `%s` doesn't exist anymore.", envs[i].SetupDir, group)
+			}
+			return &envs[i], nil
+		}
+	}
+	groups := []string{}
+	for _, env := range envs {
+		groups = append(groups, env.Group)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("\nEnvironment `%s` wasn't found, no environments kept alive via --keepalive are running.", group)
+	}
+	return nil, fmt.Errorf("\nEnvironment `%s` wasn't found, running environments are:\n%s", group, strings.Join(groups, "\n"))
+}
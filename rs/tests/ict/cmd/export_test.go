@@ -0,0 +1,15 @@
+package cmd
+
+// Unexported functions and state, which the tests of package cmd_test exercise directly.
+type TomlDocument = tomlDocument
+
+var ParseToml = parse_toml
+var SetTomlKey = set_toml_key
+var ResolveTargetAlias = resolve_target_alias
+var CheckRemoteExecutor = check_remote_executor
+var DecodeCbor = decode_cbor
+var ParseReservationDuration = parse_reservation_duration
+
+func GetConfigBazelFlags() []string {
+	return CONFIG_BAZEL_FLAGS
+}
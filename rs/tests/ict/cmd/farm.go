@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/user"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Same as DEFAULT_FARM_BASE_URL of the test driver, see rs/tests/src/driver/constants.rs
+var DEFAULT_FARM_BASE_URL = "https://farm.dfinity.systems"
+
+// Deleting a group waits for the soft shutdown of its VMs, which takes up to 120s.
+var FARM_DELETE_GROUP_TIMEOUT = 130 * time.Second
+
+var FARM_REQUEST_TIMEOUT = 30 * time.Second
+
+// Farm instance and user, whose groups the testnet commands operate on.
+type FarmConfig struct {
+	farmBaseUrl string
+	user        string
+}
+
+func add_farm_flags(cmd *cobra.Command, cfg *FarmConfig) {
+	cmd.Flags().StringVarP(&cfg.farmBaseUrl, "farm-url", "", DEFAULT_FARM_BASE_URL, "Farm URL, on which the groups are managed.")
+	cmd.Flags().StringVarP(&cfg.user, "user", "", get_farm_user(), "User owning the groups.")
+}
+
+func (cfg *FarmConfig) get_base_url() string {
+	return strings.TrimSuffix(cfg.farmBaseUrl, "/")
+}
+
+// Logged by the test driver right after it has created the farm group of the test.
+var FARM_GROUP_CREATED_REGEX = regexp.MustCompile(`Created new Farm group (\S+)`)
+
+// Returns the Farm URL used by the tests of the command, i.e. the one of --farm-url or the default.
+func get_farm_base_url(command []string) string {
+	if url, ok := get_last_flag_value(command, "--test_arg=--farm-base-url"); ok && len(url) > 0 {
+		return strings.TrimSuffix(url, "/")
+	}
+	return DEFAULT_FARM_BASE_URL
+}
+
+func delete_farm_group(farm_base_url string, group string) error {
+	_, err := farm_request(http.MethodDelete, fmt.Sprintf("%s/group/%s", farm_base_url, group), FARM_DELETE_GROUP_TIMEOUT)
+	return err
+}
+
+// Same request as create_group of the test driver, see rs/tests/src/driver/farm.rs
+func create_farm_group(farm_base_url string, group string, ttl time.Duration, user string, test_name string) error {
+	var payload struct {
+		Ttl  int64 `json:"ttl"`
+		Spec struct {
+			RequiredHostFeatures []string `json:"requiredHostFeatures"`
+			Metadata             struct {
+				User        string `json:"user"`
+				JobSchedule string `json:"jobSchedule"`
+				TestName    string `json:"testName"`
+			} `json:"metadata"`
+		} `json:"spec"`
+	}
+	payload.Ttl = int64(ttl.Seconds())
+	payload.Spec.RequiredHostFeatures = []string{}
+	payload.Spec.Metadata.User, payload.Spec.Metadata.JobSchedule, payload.Spec.Metadata.TestName = user, "manual", test_name
+	_, err := farm_json_request(http.MethodPost, fmt.Sprintf("%s/group/%s", farm_base_url, group), payload, FARM_REQUEST_TIMEOUT)
+	return err
+}
+
+// Same endpoint as set_group_ttl of the test driver, the group then expires in ttl from now.
+func set_farm_group_ttl(farm_base_url string, group string, ttl time.Duration) error {
+	_, err := farm_request(http.MethodPut, fmt.Sprintf("%s/group/%s/ttl/%d", farm_base_url, group, int64(ttl.Seconds())), FARM_REQUEST_TIMEOUT)
+	return err
+}
+
+// Group as returned by Farm, the metadata is set by the test driver, see GroupMetadata in rs/tests/src/driver/farm.rs
+type farmGroup struct {
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Spec      struct {
+		Metadata struct {
+			User        string `json:"user"`
+			JobSchedule string `json:"jobSchedule"`
+			TestName    string `json:"testName"`
+		} `json:"metadata"`
+	} `json:"spec"`
+	Vms []struct {
+		Name     string `json:"name"`
+		Hostname string `json:"hostname"`
+		Ipv6     string `json:"ipv6"`
+	} `json:"vms"`
+}
+
+// The test driver sets the user of the group to USER of the workspace status, see bazel/workspace_status.sh
+func get_farm_user() string {
+	if name := os.Getenv("USER"); len(name) > 0 {
+		return name
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+func farm_request(method string, url string, timeout time.Duration) ([]byte, error) {
+	return farm_json_request(method, url, nil, timeout)
+}
+
+func farm_json_request(method string, url string, payload interface{}, timeout time.Duration) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		content, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(content)
+	}
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response from Farm: %s %s", resp.Status, strings.TrimSpace(string(content)))
+	}
+	return content, nil
+}
+
+func list_farm_groups(farm_base_url string) ([]farmGroup, error) {
+	body, err := farm_request(http.MethodGet, fmt.Sprintf("%s/group", farm_base_url), FARM_REQUEST_TIMEOUT)
+	if err != nil {
+		return []farmGroup{}, err
+	}
+	groups := []farmGroup{}
+	if err := json.Unmarshal(body, &groups); err != nil {
+		return []farmGroup{}, fmt.Errorf("unexpected list of groups from Farm: %s", err)
+	}
+	return groups, nil
+}
+
+func get_farm_group(farm_base_url string, name string) (*farmGroup, error) {
+	groups, err := list_farm_groups(farm_base_url)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].Name == name {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Farm group %s wasn't found", name)
+}
+
+// Returns the groups of the user, which haven't expired yet, the ones expiring first come first.
+func list_user_farm_groups(farm_base_url string, user string) ([]farmGroup, error) {
+	groups, err := list_farm_groups(farm_base_url)
+	if err != nil {
+		return groups, err
+	}
+	owned := []farmGroup{}
+	for _, group := range groups {
+		if group.Spec.Metadata.User == user && time.Now().Before(group.ExpiresAt) {
+			owned = append(owned, group)
+		}
+	}
+	sort.SliceStable(owned, func(i, j int) bool {
+		return owned[i].ExpiresAt.Before(owned[j].ExpiresAt)
+	})
+	return owned, nil
+}
@@ -1,45 +1,176 @@
 package cmd
 
 import (
-	"bytes"
+	"bufio"
+	"encoding/json"
 	"fmt"
-	"os/exec"
+	"io"
+	"regexp"
 	"strings"
 
-	"github.com/schollz/closestmatch"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
 )
 
 var RED = "\033[1;31m"
 var GREEN = "\033[1;32m"
+var YELLOW = "\033[1;33m"
 var CYAN = "\033[0;36m"
 var NC = "\033[0m"
 
+// Colors are disabled via --no-color, NO_COLOR (see https://no-color.org) or if stdout isn't a terminal, e.g. piped into a log.
+func disable_colors() {
+	RED, GREEN, YELLOW, CYAN, NC = "", "", "", "", ""
+	for i := range NODE_COLORS {
+		NODE_COLORS[i] = ""
+	}
+	color.NoColor = true
+}
+
 // Max number of results displayed in the fuzzy search.
 var FUZZY_MATCHES_COUNT = 7
 // see https://github.com/schollz/closestmatch
 var FUZZY_SEARCH_BAG_SIZES = []int{2, 3, 4}
 
-func find_matching_target(all_targets []string, target string, is_fuzzy_search bool) (string, string, error) {
-	if is_fuzzy_search {
-		closest_matches := get_closest_target_matches(all_targets, target)
-		if len(closest_matches) == 0 {
-			return "", "", fmt.Errorf("\nNo fuzzy matches for target `%s` were found.", target)
-		} else if len(closest_matches) == 1 {
-			msg := fmt.Sprintf("Target `%s` doesn't exist, a single fuzzy match `%s` was found and will be used ...\n", target, closest_matches[0])
-			return closest_matches[0], msg, nil
+func find_matching_target(all_targets []string, target string, cfg *MatchConfig) (string, string, error) {
+	if err := cfg.validate_fuzzy_params(); err != nil {
+		return "", "", err
+	}
+	alias, ok := resolve_target_alias(target)
+	if !ok {
+		return find_matching_target_with(cfg.get_matchers(), all_targets, target, cfg.pickBest)
+	}
+	match_target, msg, err := find_matching_target_with(cfg.get_matchers(), all_targets, alias, cfg.pickBest)
+	return match_target, fmt.Sprintf("Alias `%s` stands for `%s` ...\n", target, alias) + msg, err
+}
+
+// Aliases of the config files take precedence over targets of the same name.
+func resolve_target_alias(target string) (string, bool) {
+	alias, ok := TARGET_ALIASES[target]
+	return alias, ok
+}
+
+// Applies matchers in the given order, the first one yielding any matches decides.
+func find_matching_target_with(matchers []Matcher, all_targets []string, target string, pick_best bool) (string, string, error) {
+	note := ""
+	for i, matcher := range matchers {
+		matches, err := matcher.Match(all_targets, target)
+		if err != nil {
+			return "", "", fmt.Errorf("\n%s", err)
+		}
+		if _, is_exact := matcher.(*ExactMatcher); is_exact && len(matches) == 1 {
+			// Existing labels (or unique label names) are used as is, even if they are substrings of other labels.
+			if matches[0] == target {
+				return target, "", nil
+			}
+			return matches[0], fmt.Sprintf("Target `%s` was resolved to the label `%s` ...\n", target, matches[0]), nil
+		} else if is_exact {
+			continue
+		}
+		if len(matches) == 0 {
+			if i+1 < len(matchers) {
+				note += fmt.Sprintf("No %s matches for target `%s` were found among %d existing targets, falling back to %s matching.\n", matcher.Name(), target, len(all_targets), matchers[i+1].Name())
+				continue
+			}
+			return "", "", fmt.Errorf("\n%sNo %s matches for target `%s` were found.", note, matcher.Name(), target)
+		} else if len(matches) == 1 {
+			msg := fmt.Sprintf("%sTarget `%s` doesn't exist. However, a single %s match `%s` was found and will be used ...\n", note, target, matcher.Name(), matches[0])
+			return matches[0], msg, nil
+		} else if pick_best && is_best_match_distinct(matches, target) {
+			msg := fmt.Sprintf("%sTarget `%s` doesn't exist. The closest of %d %s matches `%s` will be used ...\n", note, target, len(matches), matcher.Name(), matches[0])
+			return matches[0], msg, nil
 		} else {
-			return "", "", fmt.Errorf("\nMultiple fuzzy matches were found for `%s`:\n%s", target, strings.Join(closest_matches, "\n"))
+			return "", "", &AmbiguousMatchError{Matches: matches, note: note, msg: fmt.Sprintf("\n%sTarget `%s` doesn't exist. However, the following %s matches were found:\n%s", note, target, matcher.Name(), strings.Join(matches, "\n"))}
 		}
+	}
+	return "", "", fmt.Errorf("\nNo matches for target `%s` were found.", target)
+}
+
+// Prints a note to the user, if the target had to be matched against the existing ones.
+func resolve_target(cmd *cobra.Command, all_targets []string, target string, cfg *MatchConfig) (string, error) {
+	match_target, msg, err := find_matching_target(all_targets, target, cfg)
+	if ambiguous_err, ok := err.(*AmbiguousMatchError); ok {
+		return pick_target(cmd, target, ambiguous_err)
+	} else if err != nil {
+		return "", err
+	}
+	if len(msg) > 0 {
+		cmd.Printf(CYAN + msg + NC)
+	}
+	return match_target, nil
+}
+
+// Readers are kept per input, so that input buffered for one question isn't lost for the next one, e.g. if piped.
+var INPUT_READERS = map[io.Reader]*bufio.Reader{}
+
+func get_input_reader(input io.Reader) *bufio.Reader {
+	if _, ok := INPUT_READERS[input]; !ok {
+		INPUT_READERS[input] = bufio.NewReader(input)
+	}
+	return INPUT_READERS[input]
+}
+
+func read_answer(cmd *cobra.Command) string {
+	answer, _ := get_input_reader(cmd.InOrStdin()).ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+func ask_confirmation(cmd *cobra.Command, question string) bool {
+	cmd.Printf("%s%s [y/N]: %s", CYAN, question, NC)
+	answer := strings.ToLower(read_answer(cmd))
+	return answer == "y" || answer == "yes"
+}
+
+// Returns the given default, if the answer is empty.
+func ask_input(cmd *cobra.Command, question string, default_answer string) string {
+	if len(default_answer) > 0 {
+		cmd.Printf("%s%s [%s]: %s", CYAN, question, default_answer, NC)
 	} else {
-		substring_matches := find_substring_matches_in_array(all_targets, target)
-		if len(substring_matches) == 0 {
-			return "", "", fmt.Errorf("\nNone of the %d existing targets matches the substring `%s`.\nTry fuzzy match: 'ict test %s --fuzzy'", len(all_targets),  target, target)
-		} else if len(substring_matches) == 1 {
-			msg := fmt.Sprintf("Target `%s` doesn't exist. However, a single substring match `%s` was found and will be used  ...\n", target, substring_matches[0])
-			return substring_matches[0], msg, nil
+		cmd.Printf("%s%s: %s", CYAN, question, NC)
+	}
+	if answer := read_answer(cmd); len(answer) > 0 {
+		return answer
+	}
+	return default_answer
+}
+
+// Quotes arguments only if needed, so that the printed command can be copy-pasted into a shell.
+func shell_quote(args []string) string {
+	safe := regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if safe.MatchString(arg) {
+			quoted = append(quoted, arg)
 		} else {
-			return "", "", fmt.Errorf("\nTarget `%s` doesn't exist. However, the following substring matches found:\n%s", target, strings.Join(substring_matches, "\n"))
+			quoted = append(quoted, "'"+strings.ReplaceAll(arg, "'", `'\''`)+"'")
+		}
+	}
+	return strings.Join(quoted, " ")
+}
+
+// Print Bazel command for debugging purposes and for copy-pasting it.
+func print_bazel_command(cmd *cobra.Command, command []string) {
+	cmd.Println(CYAN + "Raw Bazel command to be invoked: \n$ " + shell_quote(command) + NC)
+}
+
+// Splits args into positional ones and Bazel args following the --, i.e. "ict test target -- --runs_per_test=3".
+func split_bazel_args(cmd *cobra.Command, args []string) ([]string, []string) {
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		return args[:dash], args[dash:]
+	}
+	return args, []string{}
+}
+
+// Applies validators only to positional args, so that Bazel args following the -- are not counted.
+func positional_args(validators ...cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		positional, _ := split_bazel_args(cmd, args)
+		for _, validator := range validators {
+			if err := validator(cmd, positional); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
 }
 
@@ -71,61 +202,49 @@ func any_equals(vs []string, v string) bool {
 	return false
 }
 
-func find_substring_matches_in_array(vs []string, substr string) []string {
-	matches := filter(vs, func(s string) bool {
-		return strings.Contains(s, substr)
+func find_name_matches_in_array(vs []string, name string) []string {
+	return filter(vs, func(s string) bool {
+		return get_label_name(s) == name
 	})
-	return matches
 }
 
-func get_all_system_test_targets() ([]string, error) {
-	command := []string{"bazel", "query", "tests(//rs/tests/...)"}
-	queryCmd := exec.Command(command[0], command[1:]...)
-	outputBuffer := &bytes.Buffer{}
-	stdErrBuffer := &bytes.Buffer{}
-	queryCmd.Stdout = outputBuffer
-	queryCmd.Stderr = stdErrBuffer
-	if err := queryCmd.Run(); err != nil {
-		return []string{}, fmt.Errorf("Bazel command: [%s] failed: %s", strings.Join(command, " "), stdErrBuffer.String())
-	}
-	cmdOutput := strings.Split(outputBuffer.String(), "\n")
-	all_targets := filter(cmdOutput, func(s string) bool {
-		return len(s) > 0
+// Substrings are matched case-insensitively. If nothing matches, the query is split into
+// tokens, which are then matched against tokens of the labels (see find_token_matches_in_array).
+func find_substring_matches_in_array(vs []string, substr string) []string {
+	lower_substr := strings.ToLower(substr)
+	matches := filter(vs, func(s string) bool {
+		return strings.Contains(strings.ToLower(s), lower_substr)
 	})
-	return all_targets, nil
-}
-
-func get_all_testnets() ([]string, error) {
-	command := []string{"bazel", "query", "attr(tags, 'dynamic_testnet', tests(//rs/tests/...))"}
-	queryCmd := exec.Command(command[0], command[1:]...)
-	outputBuffer := &bytes.Buffer{}
-	stdErrBuffer := &bytes.Buffer{}
-	queryCmd.Stdout = outputBuffer
-	queryCmd.Stderr = stdErrBuffer
-	if err := queryCmd.Run(); err != nil {
-		return []string{}, fmt.Errorf("Bazel command: [%s] failed: %s", strings.Join(command, " "), stdErrBuffer.String())
+	if len(matches) == 0 {
+		return find_token_matches_in_array(vs, substr)
 	}
-	cmdOutput := strings.Split(outputBuffer.String(), "\n")
-	all_targets := filter(cmdOutput, func(s string) bool {
-		return len(s) > 0
-	})
-	return all_targets, nil
+	return matches
 }
 
-func get_closest_target_matches(all_targets []string, target string) []string {
-	closest_matches := closestmatch.New(all_targets, FUZZY_SEARCH_BAG_SIZES).ClosestN(target, FUZZY_MATCHES_COUNT)
+func get_closest_target_matches(all_targets []string, target string, matches_count int, bag_sizes []int) []string {
+	closest_matches := get_closestmatch_index(all_targets, bag_sizes).ClosestN(target, matches_count)
 	return filter(closest_matches, func(s string) bool {
 		return len(s) > 0
 	})
 }
 
-func get_closest_testnet_matches(target string) ([]string, error) {
-	all_testnets, err := get_all_testnets()
+func get_closest_testnet_matches(target string, cfg *QueryConfig) ([]string, error) {
+	all_testnets, err := get_all_testnets(cfg)
 	if err != nil {
 		return []string{}, err
 	}
-	closest_matches := closestmatch.New(all_testnets, FUZZY_SEARCH_BAG_SIZES).ClosestN(target, FUZZY_MATCHES_COUNT)
+	closest_matches := get_closestmatch_index(all_testnets, FUZZY_SEARCH_BAG_SIZES).ClosestN(target, FUZZY_MATCHES_COUNT)
 	return filter(closest_matches, func(s string) bool {
 		return len(s) > 0
 	}), nil
-}
\ No newline at end of file
+}
+
+// Prints a value as indented JSON to the command's output, e.g. for consumption by scripts.
+func print_json(cmd *cobra.Command, v interface{}) error {
+	content, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	cmd.Println(string(content))
+	return nil
+}
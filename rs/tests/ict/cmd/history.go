@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Max number of invocations kept in the history, older ones are dropped.
+var HISTORY_MAX_COUNT = 200
+
+// Invocations of these commands aren't recorded, so that `ict rerun` repeats the last actual run.
+// Neither are the ones of hidden commands, e.g. the ones of cobra, which the shell runs on each completion.
+var UNRECORDED_COMMANDS = []string{"recent", "rerun", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}
+
+// Targets run by the current invocation, set once they are resolved.
+var HISTORY_TARGETS = []string{}
+
+type historyEntry struct {
+	StartedAt time.Time `json:"started_at"`
+	// Working directory, against which relative paths of the args are resolved on a rerun.
+	Dir          string   `json:"dir"`
+	Args         []string `json:"args"`
+	Targets      []string `json:"targets"`
+	Result       string   `json:"result"`
+	DurationSecs float64  `json:"duration_secs"`
+}
+
+func get_history_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "history.json"), nil
+}
+
+// Returns previous invocations, the most recent first, none if there is no history yet.
+func load_history() ([]historyEntry, error) {
+	history := []historyEntry{}
+	history_file, err := get_history_file()
+	if err != nil {
+		return history, err
+	}
+	content, err := os.ReadFile(history_file)
+	if os.IsNotExist(err) {
+		return history, nil
+	} else if err != nil {
+		return history, err
+	}
+	if err := json.Unmarshal(content, &history); err != nil {
+		return []historyEntry{}, fmt.Errorf("history %s is unreadable: %s", history_file, err)
+	}
+	return history, nil
+}
+
+// An unreadable history is moved aside, instead of being overwritten, and the history restarts with the entry.
+func record_history(cmd *cobra.Command, entry historyEntry) error {
+	history_file, err := get_history_file()
+	if err != nil {
+		return err
+	}
+	previous, err := load_history()
+	if err != nil {
+		if err := os.Rename(history_file, history_file+".bak"); err != nil {
+			return err
+		}
+		cmd.PrintErrf("%sThe %s, it was moved to %s.bak.%s\n", YELLOW, err, history_file, NC)
+	}
+	history := append([]historyEntry{entry}, previous...)
+	if len(history) > HISTORY_MAX_COUNT {
+		history = history[:HISTORY_MAX_COUNT]
+	}
+	content, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(history_file), 0755); err != nil {
+		return err
+	}
+	// Concurrent invocations each replace the file as a whole, so that it is never left half written.
+	file, err := os.CreateTemp(filepath.Dir(history_file), "history.*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), history_file)
+}
+
+// Runs with targets are PASSED or FAILED, all other invocations OK or ERROR.
+func get_history_result(err error) string {
+	switch {
+	case len(HISTORY_TARGETS) > 0 && err == nil:
+		return "PASSED"
+	case len(HISTORY_TARGETS) > 0:
+		return "FAILED"
+	case err == nil:
+		return "OK"
+	}
+	return "ERROR"
+}
+
+// Executes the root command with the args, e.g. the ones of the command line, and records the invocation in the history,
+// unless it only printed help.
+func Execute(rootCmd *cobra.Command, args []string) error {
+	started := time.Now()
+	rootCmd.SetArgs(args)
+	executed, err := rootCmd.ExecuteC()
+	if executed == rootCmd || executed.Hidden || any_equals(UNRECORDED_COMMANDS, executed.Name()) {
+		return err
+	}
+	if help, _ := executed.Flags().GetBool("help"); help {
+		return err
+	}
+	dir, _ := os.Getwd()
+	record_history(executed, historyEntry{
+		StartedAt:    started,
+		Dir:          dir,
+		Args:         args,
+		Targets:      HISTORY_TARGETS,
+		Result:       get_history_result(err),
+		DurationSecs: time.Since(started).Seconds(),
+	})
+	return err
+}
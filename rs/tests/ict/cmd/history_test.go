@@ -0,0 +1,126 @@
+package cmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type historyEntry struct {
+	Args   []string `json:"args"`
+	Result string   `json:"result"`
+}
+
+// Besides `ict recent` and `ict rerun`, the root has a command, which succeeds, and one, which fails.
+func newHistoryRootCmd(actual *bytes.Buffer) *cobra.Command {
+	var command = cmd.NewRootCmd()
+	var recent = cmd.NewRecentCmd()
+	var rerun = cmd.NewRerunCmd()
+	for _, sub := range []*cobra.Command{
+		recent,
+		rerun,
+		{Use: "noop", Args: cobra.ArbitraryArgs, RunE: func(cmd *cobra.Command, args []string) error { return nil }},
+		{Use: "fail", RunE: func(cmd *cobra.Command, args []string) error { return fmt.Errorf("failed") }},
+	} {
+		command.AddCommand(sub)
+		sub.SetOut(actual)
+	}
+	command.SetOut(actual)
+	command.SetErr(actual)
+	return command
+}
+
+func loadHistory(t *testing.T) []historyEntry {
+	actual := new(bytes.Buffer)
+	assert.Nil(t, cmd.Execute(newHistoryRootCmd(actual), []string{"recent", "--json"}))
+	history := []historyEntry{}
+	assert.Nil(t, json.Unmarshal(actual.Bytes(), &history), actual.String())
+	return history
+}
+
+func Test_ExecuteRecordsInvocations(t *testing.T) {
+	expected := []historyEntry{
+		{Args: []string{"fail"}, Result: "ERROR"},
+		{Args: []string{"noop", "first"}, Result: "OK"},
+	}
+	isolateConfig(t)
+
+	assert.Nil(t, cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"noop", "first"}))
+	assert.NotNil(t, cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"fail"}))
+
+	assert.Equal(t, expected, loadHistory(t))
+}
+
+func Test_ExecuteSkipsHelpCompletionAndHistory(t *testing.T) {
+	isolateConfig(t)
+	for _, args := range [][]string{
+		{},
+		{"noop", "-h"},
+		{"__complete", "no"},
+		{"__completeNoDesc", "noop", ""},
+		{"recent"},
+		{"rerun", "--dry-run"},
+	} {
+		cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), args)
+	}
+
+	assert.Empty(t, loadHistory(t))
+}
+
+func Test_RerunDryRun(t *testing.T) {
+	expected := "$ ict noop first"
+	isolateConfig(t)
+	assert.Nil(t, cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"noop", "first"}))
+	assert.Nil(t, cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"noop", "second"}))
+	actual := new(bytes.Buffer)
+
+	err := cmd.Execute(newHistoryRootCmd(actual), []string{"rerun", "2", "--dry-run"})
+
+	assert.Nil(t, err)
+	assert.Contains(t, actual.String(), expected)
+}
+
+func Test_RerunBeyondHistory(t *testing.T) {
+	expected := "Only 1 invocations of ict were recorded"
+	isolateConfig(t)
+	assert.Nil(t, cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"noop", "first"}))
+
+	err := cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"rerun", "3", "--dry-run"})
+
+	assert.ErrorContains(t, err, expected)
+}
+
+// The unreadable history is kept next to the new one, which starts with the invocation.
+func Test_ExecuteMovesUnreadableHistoryAside(t *testing.T) {
+	expected := []historyEntry{{Args: []string{"noop", "first"}, Result: "OK"}}
+	isolateConfig(t)
+	historyFile := filepath.Join(os.Getenv("HOME"), ".ict", "history.json")
+	writeConfig(t, historyFile, "[{\"args\": [\"noop\"")
+	actual := new(bytes.Buffer)
+
+	err := cmd.Execute(newHistoryRootCmd(actual), []string{"noop", "first"})
+
+	assert.Nil(t, err)
+	assert.Contains(t, actual.String(), "it was moved to "+historyFile+".bak")
+	content, err := os.ReadFile(historyFile + ".bak")
+	assert.Nil(t, err)
+	assert.Equal(t, "[{\"args\": [\"noop\"", string(content))
+	assert.Equal(t, expected, loadHistory(t))
+}
+
+func Test_RecentFailsOnUnreadableHistory(t *testing.T) {
+	expected := "Failed to load the history"
+	isolateConfig(t)
+	writeConfig(t, filepath.Join(os.Getenv("HOME"), ".ict", "history.json"), "{")
+
+	err := cmd.Execute(newHistoryRootCmd(new(bytes.Buffer)), []string{"recent"})
+
+	assert.ErrorContains(t, err, expected)
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Shells, for which the completion of ict can be installed, the one of $SHELL by default.
+var COMPLETION_SHELLS = []string{"bash", "zsh", "fish"}
+
+type InitConfig struct {
+	shell    string
+	isDryRun bool
+}
+
+func ValidateInitCommand(cfg *InitConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(cfg.shell) > 0 && !any_equals(COMPLETION_SHELLS, cfg.shell) {
+			return fmt.Errorf("option --shell should be one of: %s.", strings.Join(COMPLETION_SHELLS, ", "))
+		}
+		return cobra.NoArgs(cmd, args)
+	}
+}
+
+func get_data_home() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); len(dir) > 0 {
+		return dir, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share"), nil
+}
+
+// Locations, from which the shells load completions without changes of their rc files, except for zsh's fpath.
+func get_completion_file(shell string) (string, error) {
+	if shell == "fish" {
+		config_file, err := get_user_config_file()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(filepath.Dir(filepath.Dir(config_file)), "fish", "completions", "ict.fish"), nil
+	}
+	data_home, err := get_data_home()
+	if err != nil {
+		return "", err
+	}
+	if shell == "zsh" {
+		return filepath.Join(data_home, "zsh", "site-functions", "_ict"), nil
+	}
+	return filepath.Join(data_home, "bash-completion", "completions", "ict"), nil
+}
+
+func generate_completion(root *cobra.Command, shell string) ([]byte, error) {
+	var script bytes.Buffer
+	var err error
+	switch shell {
+	case "zsh":
+		err = root.GenZshCompletion(&script)
+	case "fish":
+		err = root.GenFishCompletion(&script, true)
+	default:
+		err = root.GenBashCompletionV2(&script, true)
+	}
+	return script.Bytes(), err
+}
+
+func install_completion(cmd *cobra.Command, shell string, is_dry_run bool) error {
+	completion_file, err := get_completion_file(shell)
+	if err != nil {
+		return err
+	}
+	if !ask_confirmation(cmd, fmt.Sprintf("Install the completion of ict for %s into %s?", shell, completion_file)) {
+		return nil
+	}
+	script, err := generate_completion(cmd.Root(), shell)
+	if err != nil {
+		return err
+	}
+	if is_dry_run {
+		cmd.Printf("%sThe completion of %d bytes would be written to %s%s\n", CYAN, len(script), completion_file, NC)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(completion_file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(completion_file, script, 0644); err != nil {
+		return err
+	}
+	if shell == "zsh" {
+		cmd.Printf("%sAdd its directory to the fpath in ~/.zshrc, before compinit is called:\nfpath=(%s $fpath)%s\n", CYAN, filepath.Dir(completion_file), NC)
+	} else {
+		cmd.Printf("%sThe completion is loaded by new shells.%s\n", GREEN, NC)
+	}
+	return nil
+}
+
+// The build container pulls from Docker Hub with the credentials in ~/.docker/config.json, see gitlab-ci/container/container-run.sh
+func configure_docker_login(cmd *cobra.Command, is_dry_run bool) error {
+	if check_docker_credentials().Status == CHECK_PASS {
+		cmd.Printf("%sAlready logged in to Docker Hub.%s\n", GREEN, NC)
+		return nil
+	}
+	login := []string{}
+	if docker, err := exec.LookPath("docker"); err == nil {
+		login = []string{docker, "login"}
+	} else if podman, err := exec.LookPath("podman"); err == nil {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		login = []string{podman, "login", "--authfile", filepath.Join(home, ".docker", "config.json"), "docker.io"}
+	} else {
+		cmd.Printf("%sNeither docker nor podman was found on PATH to log in to Docker Hub.%s\n", YELLOW, NC)
+		return nil
+	}
+	if !ask_confirmation(cmd, "Log in to Docker Hub, so that pulls of the build container aren't rate-limited?") {
+		return nil
+	}
+	cmd.Printf("%s$ %s%s\n", CYAN, shell_quote(login), NC)
+	if is_dry_run {
+		return nil
+	}
+	loginCmd := exec.Command(login[0], login[1:]...)
+	loginCmd.Stdin = os.Stdin
+	loginCmd.Stdout = os.Stdout
+	loginCmd.Stderr = os.Stderr
+	traced := trace_command(login)
+	err := loginCmd.Run()
+	traced(err)
+	if err != nil {
+		return fmt.Errorf("\nFailed to log in to Docker Hub: %s", err)
+	}
+	return nil
+}
+
+// Answers default to the values of the existing config, which is updated, keeping all of its other keys.
+func configure_defaults(cmd *cobra.Command, config_file string, is_dry_run bool) error {
+	content, err := os.ReadFile(config_file)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	// An invalid config is fixed up as far as possible, e.g. the keys are still set, its other lines are kept as they are.
+	config, err := load_config_files([]string{config_file})
+	if err != nil {
+		cmd.PrintErrf("%sIgnoring the values of the invalid config %s: %s%s\n", YELLOW, config_file, err, NC)
+	}
+	get_value := func(key string) string {
+		if config == nil {
+			return ""
+		}
+		if value, ok := config.values[key]; ok {
+			return value.values[0]
+		}
+		return ""
+	}
+	farm_dc := ask_input(cmd, "Preferred Farm datacenter for the VMs of tests, e.g. zh1 (empty for any)", get_value("farm-dc"))
+	artifacts_dir := ask_input(cmd, "Directory for logs and outputs of test runs (empty for ~/.ict/artifacts)", get_value("artifacts-dir"))
+	if strings.HasPrefix(artifacts_dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			artifacts_dir = filepath.Join(home, artifacts_dir[2:])
+		}
+	}
+	updated := set_toml_key(set_toml_key(string(content), "farm-dc", farm_dc), "artifacts-dir", artifacts_dir)
+	// Updating a previously unparseable config can't make it parseable.
+	if _, err := parse_toml(string(content)); err == nil {
+		if _, err := parse_toml(updated); err != nil {
+			return fmt.Errorf("\nFailed to update %s: %s", config_file, err)
+		}
+	}
+	if is_dry_run {
+		cmd.Printf("%sThe config %s would be:%s\n%s", CYAN, config_file, NC, updated)
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(config_file), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(config_file, []byte(updated), 0644); err != nil {
+		return err
+	}
+	cmd.Printf("%sWrote %s%s\n", GREEN, config_file, NC)
+	return nil
+}
+
+func InitCommand(cfg *InitConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		config_file, err := get_user_config_file()
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%sSetting up ict, press enter to keep the value in brackets.%s\n", CYAN, NC)
+		if err := configure_docker_login(cmd, cfg.isDryRun); err != nil {
+			return err
+		}
+		if err := configure_defaults(cmd, config_file, cfg.isDryRun); err != nil {
+			return err
+		}
+		shell := cfg.shell
+		if len(shell) == 0 {
+			shell = filepath.Base(os.Getenv("SHELL"))
+		}
+		if any_equals(COMPLETION_SHELLS, shell) {
+			if err := install_completion(cmd, shell, cfg.isDryRun); err != nil {
+				return err
+			}
+		} else {
+			cmd.Printf("%sThe completion of ict can't be installed for the shell `%s`, pass one of %s via --shell.%s\n", YELLOW, shell, strings.Join(COMPLETION_SHELLS, ", "), NC)
+		}
+		// The SSH key for the VMs of tests can't be set up by ict, only checked.
+		if ssh_agent := check_ssh_agent(); ssh_agent.Status != CHECK_PASS {
+			cmd.Printf("%sSSH agent: %s\n%s%s\n", YELLOW, ssh_agent.Message, ssh_agent.Hint, NC)
+		}
+		cmd.Printf("%sCheck that the environment is ready to run system tests via:\n$ ict doctor%s\n", CYAN, NC)
+		return nil
+	}
+}
+
+func NewInitCmd() *cobra.Command {
+	var cfg = InitConfig{}
+	var cmd = &cobra.Command{
+		Use:     "init",
+		Short:   "Interactively log in to Docker Hub, set the preferred datacenter and the artifacts directory, and install the shell completion of ict",
+		Example: "  ict init\n  ict init --shell zsh --dry-run",
+		Args:    ValidateInitCommand(&cfg),
+		RunE:    InitCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.shell, "shell", "", "", fmt.Sprintf("Shell to install the completion for, one of: %s (default from $SHELL).", strings.Join(COMPLETION_SHELLS, ", ")))
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the config and commands without writing or executing them.")
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
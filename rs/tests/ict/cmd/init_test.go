@@ -0,0 +1,85 @@
+package cmd_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+// Neither docker nor podman are found, so that the login to Docker Hub is skipped, answers are read from the input.
+func runInit(t *testing.T, input string, actual *bytes.Buffer) error {
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("SSH_AUTH_SOCK", "")
+	var command = cmd.NewRootCmd()
+	var initCmd = cmd.NewInitCmd()
+	command.AddCommand(initCmd)
+	command.SetIn(strings.NewReader(input))
+	command.SetOut(actual)
+	command.SetErr(actual)
+	initCmd.SetOut(actual)
+	command.SetArgs([]string{"init", "--shell", "bash"})
+	return command.Execute()
+}
+
+func Test_InitWritesConfigAndCompletion(t *testing.T) {
+	userConfig, _ := isolateConfig(t)
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	expected := "farm-dc = \"zh1\"\nartifacts-dir = \"" + filepath.Join(os.Getenv("HOME"), "artifacts") + "\"\n"
+	actual := new(bytes.Buffer)
+
+	err := runInit(t, "zh1\n~/artifacts\ny\n", actual)
+
+	assert.Nil(t, err, actual.String())
+	assert.Contains(t, actual.String(), "Neither docker nor podman was found")
+	assert.Contains(t, actual.String(), "SSH agent: SSH_AUTH_SOCK isn't set")
+	content, err := os.ReadFile(userConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(content))
+	completion, err := os.ReadFile(filepath.Join(dataHome, "bash-completion", "completions", "ict"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(completion), "bash completion V2 for ict")
+}
+
+// An invalid config doesn't keep `ict init` from updating it, answers default to its values.
+func Test_InitKeepsExistingConfig(t *testing.T) {
+	userConfig, _ := isolateConfig(t)
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+	writeConfig(t, userConfig, "# mine\nfarm_dc = \"sf1\"\nbogus = 1\n\n[alias]\nnnsup = \"//rs/tests/nns:nns_upgrade_test\"\n")
+	expected := "# mine\nfarm-dc = \"sf1\"\nbogus = 1\nartifacts-dir = \"/tmp/artifacts\"\n\n[alias]\nnnsup = \"//rs/tests/nns:nns_upgrade_test\"\n"
+	actual := new(bytes.Buffer)
+
+	err := runInit(t, "\n/tmp/artifacts\nn\n", actual)
+
+	assert.Nil(t, err, actual.String())
+	assert.Contains(t, actual.String(), "[sf1]")
+	content, err := os.ReadFile(userConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(content))
+	_, err = os.Stat(filepath.Join(dataHome, "bash-completion", "completions", "ict"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+// An unparseable config falls back to empty defaults, its keys are still updated and its other lines kept as they are.
+func Test_InitUpdatesUnparseableConfig(t *testing.T) {
+	userConfig, _ := isolateConfig(t)
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	writeConfig(t, userConfig, "farm-dc = \"sf1\"\nretries = [1,\n")
+	expected := "farm-dc = \"zh1\"\nretries = [1,\nartifacts-dir = \"/tmp/artifacts\"\n"
+	actual := new(bytes.Buffer)
+
+	err := runInit(t, "zh1\n/tmp/artifacts\nn\n", actual)
+
+	assert.Nil(t, err, actual.String())
+	assert.Contains(t, actual.String(), "Ignoring the values of the invalid config "+userConfig)
+	assert.NotContains(t, actual.String(), "[sf1]")
+	content, err := os.ReadFile(userConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(content))
+}
@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// Returned, if Bazel was interrupted via SIGINT or SIGTERM, together with the farm groups the tests had created until then.
+type bazelInterruptedError struct {
+	signal      os.Signal
+	groups      []string
+	farmBaseUrl string
+}
+
+func (e *bazelInterruptedError) Error() string {
+	name := "SIGTERM"
+	if e.signal == syscall.SIGINT {
+		name = "SIGINT"
+	}
+	return fmt.Sprintf("\nThe run was interrupted by %s.", name)
+}
+
+// Forwards the output and collects the farm groups created by the tests.
+type farmGroupsWriter struct {
+	out    io.Writer
+	line   []byte
+	groups []string
+	phase  *testPhase
+}
+
+func (w *farmGroupsWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.line = append(w.line, p[:n]...)
+	for {
+		idx := bytes.IndexByte(w.line, '\n')
+		if idx < 0 {
+			break
+		}
+		if match := FARM_GROUP_CREATED_REGEX.FindSubmatch(w.line[:idx]); match != nil && !any_equals(w.groups, string(match[1])) {
+			w.groups = append(w.groups, string(match[1]))
+		}
+		w.phase.update(w.line[:idx])
+		w.line = w.line[idx+1:]
+	}
+	// Phase changes are printed between lines of the output only.
+	if w.phase.pending && len(w.line) == 0 {
+		io.WriteString(w.out, w.phase.format())
+	}
+	return n, err
+}
+
+// Bazel running in its own process group, to which signals received by ict are forwarded via interrupt.
+// This way Bazel is interrupted cleanly, i.e. it stops the tests, while ict survives to clean up after them.
+type interruptibleBazelRun struct {
+	command  []string
+	testCmd  *exec.Cmd
+	writer   *farmGroupsWriter
+	done     chan error
+	received os.Signal
+}
+
+func start_interruptible_bazel_command(command []string, stdout io.Writer) (*interruptibleBazelRun, error) {
+	run := &interruptibleBazelRun{command: command, writer: &farmGroupsWriter{out: stdout, phase: new_test_phase()}, done: make(chan error, 1)}
+	io.WriteString(stdout, run.writer.phase.format())
+	run.testCmd = exec.Command(command[0], command[1:]...)
+	run.testCmd.Stdout = run.writer
+	run.testCmd.Stderr = get_bazel_stderr()
+	run.testCmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	traced := trace_command(command)
+	if err := run.testCmd.Start(); err != nil {
+		traced(err)
+		return nil, err
+	}
+	go func() {
+		err := run.testCmd.Wait()
+		traced(err)
+		run.done <- err
+	}()
+	return run, nil
+}
+
+// Repeated signals are forwarded as well, Bazel escalates its shutdown with each of them.
+func (r *interruptibleBazelRun) interrupt(sig os.Signal) {
+	r.received = sig
+	syscall.Kill(-r.testCmd.Process.Pid, sig.(syscall.Signal))
+}
+
+// Turns the outcome of Bazel into a bazelInterruptedError, if the run was interrupted.
+func (r *interruptibleBazelRun) get_result(err error) error {
+	if r.received == nil {
+		return err
+	}
+	return &bazelInterruptedError{signal: r.received, groups: r.writer.groups, farmBaseUrl: get_farm_base_url(r.command)}
+}
+
+// Waits for Bazel to exit, forwarding SIGINT and SIGTERM received by ict meanwhile.
+func run_interruptible_bazel_command(command []string, stdout io.Writer) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	run, err := start_interruptible_bazel_command(command, stdout)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case sig := <-signals:
+			run.interrupt(sig)
+		case err := <-run.done:
+			return run.get_result(err)
+		}
+	}
+}
+
+// Offers to delete the farm groups of an interrupted run, which would otherwise keep their VMs until their TTL expires.
+// Without SIGINT or SIGTERM the error is returned as is.
+func cleanup_interrupted_run(cmd *cobra.Command, cfg *Config, err error) error {
+	var interrupted *bazelInterruptedError
+	if !errors.As(err, &interrupted) || len(interrupted.groups) == 0 {
+		return err
+	}
+	groups := strings.Join(interrupted.groups, ", ")
+	if !cfg.assumeYes && !ask_confirmation(cmd, fmt.Sprintf("Delete the farm groups %s created by the interrupted run?", groups)) {
+		cmd.Printf("%sFarm groups %s are kept until their TTL expires.%s\n", CYAN, groups, NC)
+		return err
+	}
+	for _, group := range interrupted.groups {
+		cmd.Printf("%sDeleting farm group %s ...%s\n", CYAN, group, NC)
+		if delete_err := delete_farm_group(interrupted.farmBaseUrl, group); delete_err != nil {
+			cmd.PrintErrf("%sFailed to delete farm group %s: %s%s\n", RED, group, delete_err, NC)
+		}
+	}
+	return err
+}
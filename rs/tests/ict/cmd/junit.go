@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+type junitTestSuite struct {
+	Name     string `xml:"name,attr"`
+	Tests    int    `xml:"tests,attr"`
+	Failures int    `xml:"failures,attr"`
+	Errors   int    `xml:"errors,attr"`
+	Time     string `xml:"time,attr,omitempty"`
+	Inner    []byte `xml:",innerxml"`
+}
+
+type junitTestCase struct {
+	Name string `xml:"name,attr"`
+	Time string `xml:"time,attr"`
+}
+
+// Test cases are parsed from the inner XML on demand, so that the merged report keeps the original content.
+func (suite *junitTestSuite) get_test_cases() []junitTestCase {
+	var parsed struct {
+		Cases []junitTestCase `xml:"testcase"`
+	}
+	xml.Unmarshal(append(append([]byte("<testsuite>"), suite.Inner...), []byte("</testsuite>")...), &parsed)
+	return parsed.Cases
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// Returns nil, if the target has no test.xml written since the given time.
+func read_test_xml(target string, since time.Time) (*junitTestSuites, error) {
+	return read_test_xml_in_dir(target, get_test_logs_dir(target), since)
+}
+
+func read_test_xml_in_dir(target string, logs_dir string, since time.Time) (*junitTestSuites, error) {
+	test_xml := filepath.Join(logs_dir, "test.xml")
+	// File modification times are coarser than the wall clock, hence the tolerance.
+	if info, err := os.Stat(test_xml); err != nil || info.ModTime().Before(since.Add(-time.Second)) {
+		return nil, nil
+	}
+	content, err := os.ReadFile(test_xml)
+	if err != nil {
+		return nil, nil
+	}
+	var result junitTestSuites
+	if err := xml.Unmarshal(content, &result); err != nil {
+		return nil, fmt.Errorf("\nFailed to parse the test result of target `%s`: %s", target, err)
+	}
+	return &result, nil
+}
+
+// Merges test.xml files, which Bazel writes for each of the targets, into a single JUnit report.
+// Targets without a test.xml written since the given time, e.g. because their build failed, are skipped.
+func write_junit_report(targets []string, path string, since time.Time) error {
+	report := junitTestSuites{}
+	for _, target := range targets {
+		result, err := read_test_xml(target, since)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			continue
+		}
+		for _, suite := range result.Suites {
+			if len(suite.Name) == 0 {
+				suite.Name = target
+			}
+			report.Tests += suite.Tests
+			report.Failures += suite.Failures
+			report.Errors += suite.Errors
+			report.Suites = append(report.Suites, suite)
+		}
+	}
+	content, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), content...), 0644)
+}
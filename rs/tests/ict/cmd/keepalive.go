@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Lines logged by the test driver, from which the kept alive environment is reconstructed.
+var FARM_VM_CREATED_REGEX = regexp.MustCompile(`VM\((\S+)\) Host: (\S+) IPv6: (\S+)`)
+var FARM_CONSOLE_URL_REGEX = regexp.MustCompile(`Console: \S*/group/([^/\s]+)/vm/`)
+
+// Lines of the IC topology logged by the test driver after the setup, the VMs of IC nodes are named after their node ids.
+var TOPOLOGY_SUBNET_REGEX = regexp.MustCompile(`Subnet id=(\S+), index=(\d+), type=(\w+)`)
+var TOPOLOGY_NODE_REGEX = regexp.MustCompile(`\tNode id=(\S+), index=\d+`)
+var TOPOLOGY_UNASSIGNED_NODE_REGEX = regexp.MustCompile(`Unassigned node id=(\S+), index=\d+`)
+
+// Logged by the test driver once the Prometheus VM is up, see rs/tests/src/driver/prometheus_vm.rs
+var PROMETHEUS_URL_REGEX = regexp.MustCompile(`Prometheus Web UI at (http\S+)`)
+var GRAFANA_URL_REGEX = regexp.MustCompile(`Grafana at (http\S+)`)
+
+// Printed by the test driver once all test functions have finished, the environment is kept alive afterwards.
+var TEST_REPORT_MARKER = "See replica logs in Kibana:"
+
+type keptAliveVm struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+	Ipv6 string `json:"ipv6"`
+	// Subnet of the IC node running on the VM, empty for unassigned nodes and VMs not running a replica.
+	Subnet     string `json:"subnet,omitempty"`
+	SubnetType string `json:"subnet_type,omitempty"`
+	// The NNS runs on the first subnet of the topology, i.e. the root subnet.
+	IsNns        bool `json:"nns,omitempty"`
+	IsUnassigned bool `json:"unassigned,omitempty"`
+}
+
+// Forwards the streamed test output and collects information about the farm group, which is kept alive.
+type keptAliveEnvWriter struct {
+	cmd       *cobra.Command
+	out       io.Writer
+	line      []byte
+	groupName string
+	vms       []keptAliveVm
+	// Subnets of the nodes by node id, the last subnet line logged applies to the node lines following it.
+	subnets       map[string]keptAliveVm
+	lastSubnet    keptAliveVm
+	prometheusUrl string
+	grafanaUrl    string
+	expiresAt     time.Time
+	printed       bool
+	targets       []string
+	// Base directory of TEST_TMPDIR of the tests, in which the test driver keeps its setup.
+	testTmpdir string
+	// Open the Grafana dashboard of the group once the environment is up.
+	openDashboard bool
+}
+
+func (w *keptAliveEnvWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.line = append(w.line, p[:n]...)
+	for {
+		idx := bytes.IndexByte(w.line, '\n')
+		if idx < 0 {
+			break
+		}
+		w.process_line(string(w.line[:idx]))
+		w.line = w.line[idx+1:]
+	}
+	return n, err
+}
+
+func (w *keptAliveEnvWriter) process_line(line string) {
+	if match := FARM_VM_CREATED_REGEX.FindStringSubmatch(line); match != nil {
+		w.vms = append(w.vms, keptAliveVm{Name: match[1], Host: match[2], Ipv6: match[3]})
+	}
+	if match := FARM_CONSOLE_URL_REGEX.FindStringSubmatch(line); match != nil {
+		if len(w.groupName) == 0 {
+			w.record_testnet(match[1])
+		}
+		w.groupName = match[1]
+	}
+	if match := PROMETHEUS_URL_REGEX.FindStringSubmatch(line); match != nil {
+		w.prometheusUrl = match[1]
+	}
+	if match := GRAFANA_URL_REGEX.FindStringSubmatch(line); match != nil {
+		w.grafanaUrl = match[1]
+	}
+	if match := TOPOLOGY_SUBNET_REGEX.FindStringSubmatch(line); match != nil {
+		w.lastSubnet = keptAliveVm{Subnet: match[1], SubnetType: match[3], IsNns: match[2] == "0"}
+	} else if match := TOPOLOGY_NODE_REGEX.FindStringSubmatch(line); match != nil {
+		w.record_subnet(match[1], w.lastSubnet)
+	} else if match := TOPOLOGY_UNASSIGNED_NODE_REGEX.FindStringSubmatch(line); match != nil {
+		w.record_subnet(match[1], keptAliveVm{IsUnassigned: true})
+	}
+	if strings.Contains(line, TEST_REPORT_MARKER) && !w.printed {
+		w.printed = true
+		w.print_env_info()
+		w.record_env()
+	}
+}
+
+func (w *keptAliveEnvWriter) record_subnet(node_id string, subnet keptAliveVm) {
+	if w.subnets == nil {
+		w.subnets = map[string]keptAliveVm{}
+	}
+	w.subnets[node_id] = subnet
+}
+
+func (w *keptAliveEnvWriter) record_testnet(group string) {
+	testnet := localTestnet{Name: group, Target: find_target_of_group(w.targets, group), CreatedAt: time.Now(), ExpiresAt: w.expiresAt, LogDir: w.testTmpdir, Branch: get_current_branch()}
+	if err := record_local_testnet(testnet); err != nil {
+		w.cmd.PrintErrf("%sFailed to record the testnet: %s%s\n", RED, err, NC)
+	}
+}
+
+func (w *keptAliveEnvWriter) print_env_info() {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%sTests have finished, the environment is kept alive for debugging.\n", GREEN)
+	if len(w.groupName) > 0 {
+		fmt.Fprintf(&sb, "Farm group: %s\n", w.groupName)
+	} else {
+		fmt.Fprintf(&sb, "Farm group: unknown\n")
+	}
+	for _, vm := range w.vms {
+		fmt.Fprintf(&sb, "  %s\t%s\t%s\n", vm.Name, vm.Ipv6, vm.Host)
+	}
+	// Grafana runs on the Prometheus VM of the group, so its dashboards only show the nodes of this group.
+	if len(w.grafanaUrl) > 0 {
+		fmt.Fprintf(&sb, "Grafana dashboard: %s\n", get_grafana_dashboard_url(w.grafanaUrl, REPLICA_DASHBOARDS[0]))
+	}
+	fmt.Fprintf(&sb, "Expires at: %s (in %s), press Ctrl-C to tear it down earlier.%s\n", w.expiresAt.Format(time.RFC1123), time.Until(w.expiresAt).Round(time.Minute), NC)
+	w.cmd.Print(sb.String())
+	if w.openDashboard {
+		w.open_dashboard()
+	}
+}
+
+func (w *keptAliveEnvWriter) open_dashboard() {
+	if len(w.grafanaUrl) == 0 {
+		w.cmd.Printf("%sThe environment has no Grafana dashboard, as its setup doesn't start a Prometheus VM.%s\n", CYAN, NC)
+		return
+	}
+	if err := open_in_default_app(get_grafana_dashboard_url(w.grafanaUrl, REPLICA_DASHBOARDS[0])); err != nil {
+		w.cmd.PrintErrf("%sFailed to open the Grafana dashboard: %s%s\n", RED, err, NC)
+	}
+}
+
+// Remembers the setup directory of the environment, so that it can be reused via --reuse-env.
+func (w *keptAliveEnvWriter) record_env() {
+	setup_dir, ok := find_setup_dir(w.testTmpdir, w.groupName)
+	if !ok {
+		return
+	}
+	target := find_target_of_group(w.targets, w.groupName)
+	vms := []keptAliveVm{}
+	for _, vm := range w.vms {
+		if subnet, ok := w.subnets[vm.Name]; ok {
+			vm.Subnet, vm.SubnetType, vm.IsNns, vm.IsUnassigned = subnet.Subnet, subnet.SubnetType, subnet.IsNns, subnet.IsUnassigned
+		}
+		vms = append(vms, vm)
+	}
+	if err := record_kept_alive_env(keptAliveEnv{Group: w.groupName, Target: target, SetupDir: setup_dir, ExpiresAt: w.expiresAt, Vms: vms, PrometheusUrl: w.prometheusUrl, GrafanaUrl: w.grafanaUrl}); err != nil {
+		w.cmd.PrintErrf("%sFailed to record the kept alive environment: %s%s\n", RED, err, NC)
+		return
+	}
+	w.cmd.Printf("%sReuse it for iterative development via:\n$ ict test %s --reuse-env %s%s\n", GREEN, get_label_name(target), w.groupName, NC)
+}
+
+func get_test_timeout(command []string) time.Duration {
+	if value, ok := get_last_flag_value(command, "--test_timeout"); ok {
+		if secs, err := strconv.Atoi(value); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return 0
+}
+
+// Runs the tests with the keepalive test arg and prints the farm group, node IPs and expiry time once the tests have finished.
+// The environment expires once Bazel kills the test because of the test timeout.
+func run_bazel_command_with_keepalive(cmd *cobra.Command, command []string, targets []string, open_dashboard bool) error {
+	test_tmpdir, _ := get_last_flag_value(command, "--test_tmpdir")
+	if !filepath.IsAbs(test_tmpdir) {
+		test_tmpdir = filepath.Join(get_workspace_root(), test_tmpdir)
+	}
+	writer := &keptAliveEnvWriter{cmd: cmd, out: get_bazel_stdout(), expiresAt: time.Now().Add(get_test_timeout(command)), targets: targets, testTmpdir: test_tmpdir, openDashboard: open_dashboard}
+	return run_interruptible_bazel_command(command, writer)
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Verbosity of ict's own messages, set via --quiet, -v and -vv.
+var VERBOSITY_QUIET = -1
+var VERBOSITY_NORMAL = 0
+var VERBOSITY_VERBOSE = 1
+var VERBOSITY_TRACE = 2
+var VERBOSITY = VERBOSITY_NORMAL
+
+// With --quiet, messages of ict and the output of bazel are discarded, so that scripts only get the result line.
+func set_verbosity(root *cobra.Command, verbosity int) {
+	VERBOSITY = verbosity
+	if VERBOSITY > VERBOSITY_QUIET {
+		return
+	}
+	var silence func(cmd *cobra.Command)
+	silence = func(cmd *cobra.Command) {
+		cmd.SetOut(io.Discard)
+		for _, sub := range cmd.Commands() {
+			silence(sub)
+		}
+	}
+	silence(root)
+}
+
+func get_bazel_stdout() io.Writer {
+	if VERBOSITY == VERBOSITY_QUIET {
+		return io.Discard
+	}
+	return os.Stdout
+}
+
+func get_bazel_stderr() io.Writer {
+	if VERBOSITY == VERBOSITY_QUIET {
+		return io.Discard
+	}
+	return os.Stderr
+}
+
+// Printed with -v, e.g. how targets were resolved and where cached results come from.
+func log_verbose(format string, args ...interface{}) {
+	if VERBOSITY >= VERBOSITY_VERBOSE {
+		fmt.Fprintf(os.Stderr, "%s[ict] %s%s\n", CYAN, fmt.Sprintf(format, args...), NC)
+	}
+}
+
+// With -vv, every external command is printed once started and once finished, along with its duration.
+// The returned function is called with the outcome of the command.
+func trace_command(command []string) func(error) {
+	if VERBOSITY < VERBOSITY_TRACE {
+		return func(error) {}
+	}
+	started := time.Now()
+	fmt.Fprintf(os.Stderr, "%s[ict] $ %s%s\n", CYAN, shell_quote(command), NC)
+	return func(err error) {
+		outcome := "succeeded"
+		if err != nil {
+			outcome = fmt.Sprintf("failed (%s)", strings.TrimSpace(err.Error()))
+		}
+		fmt.Fprintf(os.Stderr, "%s[ict] %s %s after %s%s\n", CYAN, command[0], outcome, time.Since(started).Round(time.Millisecond), NC)
+	}
+}
+
+// The final result line of a run is printed at any verbosity, as it is all scripts get with --quiet.
+// Bazel only succeeds if all targets passed, otherwise the outcome of each target is looked up in its test logs.
+// A run, which failed although all test logs say PASSED, e.g. because bazel was interrupted or coverage failed, is an ERROR.
+func print_result_line(targets []string, since time.Time, run_err error) {
+	if run_err == nil {
+		fmt.Fprintf(os.Stdout, "%sPASSED %d/%d%s\n", GREEN, len(targets), len(targets), NC)
+		return
+	}
+	failed := []string{}
+	for _, target := range targets {
+		result, err := get_test_result(target, since)
+		if err != nil || result.Status != "PASSED" {
+			status := result.Status
+			if err != nil || len(status) == 0 {
+				status = "NO_RESULT"
+			}
+			failed = append(failed, fmt.Sprintf("%s (%s)", target, status))
+		}
+	}
+	if len(failed) == 0 {
+		fmt.Fprintf(os.Stdout, "%sERROR %d/%d passed, but the run failed: %s%s\n", RED, len(targets), len(targets), strings.Join(strings.Fields(run_err.Error()), " "), NC)
+		return
+	}
+	fmt.Fprintf(os.Stdout, "%sFAILED %d/%d: %s%s\n", RED, len(failed), len(targets), strings.Join(failed, ", "), NC)
+}
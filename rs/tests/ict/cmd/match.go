@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+type MatchConfig struct {
+	isFuzzyMatch bool
+	isGlobMatch  bool
+	isRegexMatch bool
+	pickBest     bool
+	// Max number of results displayed in the fuzzy search and bag sizes of its index.
+	fuzzyMatchesCount int
+	fuzzyBagSizes     []int
+}
+
+func add_match_flags(cmd *cobra.Command, cfg *MatchConfig) {
+	cmd.Flags().BoolVarP(&cfg.isFuzzyMatch, "fuzzy", "", false, "Use fuzzy matching to find similar target names. Default: substring match, falling back to fuzzy match.")
+	cmd.Flags().BoolVarP(&cfg.isGlobMatch, "glob", "", false, "Match target names with shell-style wildcards, e.g. '*nns*upgrade*'.")
+	cmd.Flags().BoolVarP(&cfg.isRegexMatch, "regex", "", false, "Match target names with a Go regular expression, e.g. 'nns_(upgrade|token).*_test$'.")
+	cmd.MarkFlagsMutuallyExclusive("fuzzy", "glob", "regex")
+	cmd.Flags().IntVarP(&cfg.fuzzyMatchesCount, "fuzzy-matches", "", FUZZY_MATCHES_COUNT, "Max number of results displayed in the fuzzy search.")
+	cmd.Flags().IntSliceVarP(&cfg.fuzzyBagSizes, "fuzzy-bag-sizes", "", FUZZY_SEARCH_BAG_SIZES, "Substring sizes used to index targets for the fuzzy search (see https://github.com/schollz/closestmatch).")
+	cmd.Flags().BoolVarP(&cfg.pickBest, "pick-best", "", false, "Use the closest of multiple matches, if it is much closer than the others.")
+}
+
+func (cfg *MatchConfig) validate_fuzzy_params() error {
+	if cfg.fuzzyMatchesCount < 1 {
+		return fmt.Errorf("option --fuzzy-matches should be >= 1.")
+	}
+	if len(cfg.fuzzyBagSizes) == 0 {
+		return fmt.Errorf("option --fuzzy-bag-sizes should contain at least one size.")
+	}
+	for _, size := range cfg.fuzzyBagSizes {
+		if size < 1 {
+			return fmt.Errorf("option --fuzzy-bag-sizes should contain only sizes >= 1.")
+		}
+	}
+	return nil
+}
+
+// Translates a shell-style pattern into an anchored regexp. Unlike path.Match, `*` also matches `/` and `:`.
+func glob_to_regexp(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	in_class := false
+	for _, r := range pattern {
+		switch {
+		case in_class:
+			if r == ']' {
+				in_class = false
+			}
+			sb.WriteRune(r)
+		case r == '*':
+			sb.WriteString(".*")
+		case r == '?':
+			sb.WriteString(".")
+		case r == '[':
+			in_class = true
+			sb.WriteRune(r)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Returns the name part of a bazel label, e.g. `basic_health_test` for `//rs/tests:basic_health_test`.
+func get_label_name(label string) string {
+	if idx := strings.LastIndex(label, ":"); idx >= 0 {
+		return label[idx+1:]
+	}
+	return label[strings.LastIndex(label, "/")+1:]
+}
+
+// A target matches, if the pattern matches either the full label or only its name.
+func find_glob_matches_in_array(vs []string, pattern string) ([]string, error) {
+	re, err := glob_to_regexp(pattern)
+	if err != nil {
+		return []string{}, err
+	}
+	return filter(vs, func(s string) bool {
+		return re.MatchString(s) || re.MatchString(get_label_name(s))
+	}), nil
+}
+
+func find_regex_matches_in_array(vs []string, expr string) ([]string, error) {
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return []string{}, err
+	}
+	return filter(vs, re.MatchString), nil
+}
+
+// Splits a string into lowercase tokens at whitespace, `_`, `-`, `:`, `/` and camelCase boundaries,
+// e.g. `NnsUpgrade` and `nns upgrade` both yield [nns upgrade].
+func tokenize(s string) []string {
+	tokens := []string{}
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			tokens = append(tokens, strings.ToLower(string(current)))
+			current = nil
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		if unicode.IsSpace(r) || strings.ContainsRune("_-:/.", r) {
+			flush()
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) {
+			flush()
+		}
+		current = append(current, r)
+	}
+	flush()
+	return tokens
+}
+
+// A label matches, if every query token is a prefix of one of the label tokens, in the same order.
+func find_token_matches_in_array(vs []string, query string) []string {
+	query_tokens := tokenize(query)
+	if len(query_tokens) == 0 {
+		return []string{}
+	}
+	return filter(vs, func(s string) bool {
+		i := 0
+		for _, token := range tokenize(s) {
+			if i < len(query_tokens) && strings.HasPrefix(token, query_tokens[i]) {
+				i++
+			}
+		}
+		return i == len(query_tokens)
+	})
+}
+
+// The best match is picked with --pick-best, if its distance is at most this fraction of the second best.
+var PICK_BEST_DISTANCE_RATIO = 0.5
+
+func levenshtein_distance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min_int(min_int(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func min_int(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func get_match_distance(query string, label string) int {
+	return levenshtein_distance(strings.ToLower(query), strings.ToLower(get_label_name(label)))
+}
+
+// Sorts matches best-first by the edit distance between the query and the label name.
+// Recently run targets are ranked before all others.
+func rank_matches(matches []string, query string) []string {
+	ranked := append([]string{}, matches...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return get_match_distance(query, ranked[i]) < get_match_distance(query, ranked[j])
+	})
+	return boost_recent_targets(ranked)
+}
+
+// Reports whether the first of the ranked matches is dramatically closer to the query than the second one.
+func is_best_match_distinct(ranked []string, query string) bool {
+	if len(ranked) < 2 {
+		return len(ranked) == 1
+	}
+	best, second := get_match_distance(query, ranked[0]), get_match_distance(query, ranked[1])
+	return best < second && float64(best) <= PICK_BEST_DISTANCE_RATIO*float64(second)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+)
+
+// Matcher selects those of all targets, which match a user query, best matches first.
+type Matcher interface {
+	// Name used in messages to the user, e.g. "substring".
+	Name() string
+	Match(all_targets []string, query string) ([]string, error)
+}
+
+// Matches existing labels, or label names if they are unique, e.g. `basic_health_test`.
+type ExactMatcher struct{}
+
+func (m *ExactMatcher) Name() string { return "exact" }
+
+func (m *ExactMatcher) Match(all_targets []string, query string) ([]string, error) {
+	if any_equals(all_targets, query) {
+		return []string{query}, nil
+	}
+	if name_matches := find_name_matches_in_array(all_targets, query); len(name_matches) == 1 {
+		return name_matches, nil
+	}
+	return []string{}, nil
+}
+
+// Case-insensitive and token-aware substring matching, ranked by edit distance.
+type SubstringMatcher struct{}
+
+func (m *SubstringMatcher) Name() string { return "substring" }
+
+func (m *SubstringMatcher) Match(all_targets []string, query string) ([]string, error) {
+	return rank_matches(find_substring_matches_in_array(all_targets, query), query), nil
+}
+
+// Shell-style wildcards, e.g. `*nns*upgrade*`.
+type GlobMatcher struct{}
+
+func (m *GlobMatcher) Name() string { return "glob" }
+
+func (m *GlobMatcher) Match(all_targets []string, query string) ([]string, error) {
+	matches, err := find_glob_matches_in_array(all_targets, query)
+	if err != nil {
+		return []string{}, fmt.Errorf("Invalid glob pattern `%s`: %s", query, err)
+	}
+	return boost_recent_targets(matches), nil
+}
+
+// Go regular expressions, e.g. `nns_(upgrade|token)`.
+type RegexMatcher struct{}
+
+func (m *RegexMatcher) Name() string { return "regex" }
+
+func (m *RegexMatcher) Match(all_targets []string, query string) ([]string, error) {
+	matches, err := find_regex_matches_in_array(all_targets, query)
+	if err != nil {
+		return []string{}, fmt.Errorf("Invalid regular expression `%s`: %s", query, err)
+	}
+	return boost_recent_targets(matches), nil
+}
+
+// Bag-of-words based fuzzy search, see https://github.com/schollz/closestmatch
+type FuzzyMatcher struct {
+	MatchesCount int
+	BagSizes     []int
+}
+
+func (m *FuzzyMatcher) Name() string { return "fuzzy" }
+
+func (m *FuzzyMatcher) Match(all_targets []string, query string) ([]string, error) {
+	closest_matches := get_closest_target_matches(all_targets, query, m.MatchesCount, m.BagSizes)
+	return boost_recent_targets(closest_matches), nil
+}
+
+// Returns matchers in the order of their precedence, later ones are only used if earlier ones find nothing.
+// The exact matcher always comes first.
+func (cfg *MatchConfig) get_matchers() []Matcher {
+	fuzzy := &FuzzyMatcher{MatchesCount: cfg.fuzzyMatchesCount, BagSizes: cfg.fuzzyBagSizes}
+	switch {
+	case cfg.isGlobMatch:
+		return []Matcher{&ExactMatcher{}, &GlobMatcher{}}
+	case cfg.isRegexMatch:
+		return []Matcher{&ExactMatcher{}, &RegexMatcher{}}
+	case cfg.isFuzzyMatch:
+		return []Matcher{&ExactMatcher{}, fuzzy}
+	default:
+		return []Matcher{&ExactMatcher{}, &SubstringMatcher{}, fuzzy}
+	}
+}
+
+// Returns all targets matching the query (e.g. for --all) instead of insisting on a single one.
+// Fuzzy matching is only used if requested explicitly, as it would select unrelated targets otherwise.
+func find_all_matching_targets(all_targets []string, target string, cfg *MatchConfig) ([]string, error) {
+	if alias, ok := resolve_target_alias(target); ok {
+		target = alias
+	}
+	for _, matcher := range cfg.get_matchers() {
+		if _, is_fuzzy := matcher.(*FuzzyMatcher); is_fuzzy && !cfg.isFuzzyMatch {
+			continue
+		}
+		matches, err := matcher.Match(all_targets, target)
+		if err != nil {
+			return []string{}, err
+		}
+		if len(matches) > 0 {
+			return matches, nil
+		}
+	}
+	return []string{}, fmt.Errorf("No targets match `%s`.", target)
+}
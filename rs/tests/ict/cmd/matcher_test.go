@@ -0,0 +1,97 @@
+package cmd_test
+
+import (
+	"testing"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+var allTargets = []string{
+	"//rs/tests/testing_verification:basic_health_test",
+	"//rs/tests/nns:nns_upgrade_test",
+	"//rs/tests/nns:nns_upgrade_test_colocate",
+	"//rs/tests/nns:nns_token_balance_test",
+}
+
+// Matchers may persist their state (e.g. recently run targets, fuzzy index), keep it out of the real home.
+func isolateState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+}
+
+func Test_ExactMatcherMatchesLabelAndUniqueName(t *testing.T) {
+	matcher := &cmd.ExactMatcher{}
+
+	byLabel, _ := matcher.Match(allTargets, "//rs/tests/nns:nns_upgrade_test")
+	byName, _ := matcher.Match(allTargets, "nns_upgrade_test")
+	bySubstring, _ := matcher.Match(allTargets, "nns_upgrade")
+
+	assert.Equal(t, []string{"//rs/tests/nns:nns_upgrade_test"}, byLabel)
+	assert.Equal(t, []string{"//rs/tests/nns:nns_upgrade_test"}, byName)
+	assert.Empty(t, bySubstring)
+}
+
+func Test_SubstringMatcherIsCaseInsensitiveAndTokenAware(t *testing.T) {
+	isolateState(t)
+	matcher := &cmd.SubstringMatcher{}
+
+	upper, _ := matcher.Match(allTargets, "NNS_TOKEN")
+	camel, _ := matcher.Match(allTargets, "BasicHealth")
+	spaced, _ := matcher.Match(allTargets, "nns balance")
+
+	assert.Equal(t, []string{"//rs/tests/nns:nns_token_balance_test"}, upper)
+	assert.Equal(t, []string{"//rs/tests/testing_verification:basic_health_test"}, camel)
+	assert.Equal(t, []string{"//rs/tests/nns:nns_token_balance_test"}, spaced)
+}
+
+func Test_SubstringMatcherRanksByDistance(t *testing.T) {
+	isolateState(t)
+	matcher := &cmd.SubstringMatcher{}
+
+	matches, _ := matcher.Match(allTargets, "upgrade_test_col")
+
+	assert.Equal(t, []string{"//rs/tests/nns:nns_upgrade_test_colocate"}, matches)
+
+	matches, _ = matcher.Match(allTargets, "upgrade")
+
+	assert.Equal(t, "//rs/tests/nns:nns_upgrade_test", matches[0])
+}
+
+func Test_GlobMatcher(t *testing.T) {
+	isolateState(t)
+	matcher := &cmd.GlobMatcher{}
+
+	matches, err := matcher.Match(allTargets, "*nns*upgrade*")
+
+	assert.Nil(t, err)
+	assert.Len(t, matches, 2)
+
+	matches, _ = matcher.Match(allTargets, "basic_*_test")
+
+	assert.Equal(t, []string{"//rs/tests/testing_verification:basic_health_test"}, matches)
+}
+
+func Test_RegexMatcher(t *testing.T) {
+	isolateState(t)
+	matcher := &cmd.RegexMatcher{}
+
+	matches, err := matcher.Match(allTargets, "nns_(upgrade|token).*_test$")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"//rs/tests/nns:nns_upgrade_test", "//rs/tests/nns:nns_token_balance_test"}, matches)
+
+	_, err = matcher.Match(allTargets, "(")
+
+	assert.NotNil(t, err)
+}
+
+func Test_FuzzyMatcherFindsMisspelledTarget(t *testing.T) {
+	isolateState(t)
+	matcher := &cmd.FuzzyMatcher{MatchesCount: 1, BagSizes: []int{2, 3, 4}}
+
+	matches, err := matcher.Match(allTargets, "basic_helth_tst")
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"//rs/tests/testing_verification:basic_health_test"}, matches)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Bazel writes logs of each of the runs of --runs_per_test into its own subdirectory.
+func get_run_logs_dir(target string, run int, runs int) string {
+	return filepath.Join(get_test_logs_dir(target), fmt.Sprintf("run_%d_of_%d", run, runs))
+}
+
+// Runs copies of the target at the same time via --runs_per_test, each of them sets up its own farm group.
+// Unlike --repeat, this deliberately makes the runs compete for Farm resources, e.g. to shake out races and capacity problems.
+func run_parallel_tests(cmd *cobra.Command, command []string, target string, parallel int) error {
+	started := time.Now()
+	err := run_bazel_command(command)
+	if exit_err, ok := err.(*exec.ExitError); err != nil && !(ok && exit_err.ExitCode() == BAZEL_TESTS_FAILED_EXIT_CODE) {
+		return err
+	}
+	results := make([]testResult, 0, parallel)
+	for run := 1; run <= parallel; run++ {
+		result, err := get_test_result_in_dir(target, get_run_logs_dir(target, run, parallel), started)
+		if err != nil {
+			return err
+		}
+		results = append(results, result)
+	}
+	print_parallel_summary(cmd, results)
+	failed := 0
+	for run, result := range results {
+		if result.Status == "PASSED" {
+			continue
+		}
+		if failed == 0 {
+			print_failed_run_info(cmd, run+1, result)
+		}
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("\n%d of %d parallel runs of %s didn't pass.", failed, parallel, target)
+	}
+	return nil
+}
+
+func print_parallel_summary(cmd *cobra.Command, results []testResult) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "RUN\tRESULT\tDURATION\tFARM GROUP")
+	for run, result := range results {
+		group := result.FarmGroup
+		if len(group) == 0 {
+			group = "unknown"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", run+1, result.Status, (time.Duration(result.DurationSecs) * time.Second).Round(time.Second), group)
+	}
+	w.Flush()
+}
+
+func print_failed_run_info(cmd *cobra.Command, run int, result testResult) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%sRun %d is the first one, which didn't pass.\n", RED, run)
+	if len(result.FarmGroup) > 0 {
+		fmt.Fprintf(&sb, "Farm group: %s\n", result.FarmGroup)
+	}
+	fmt.Fprintf(&sb, "Test log: %s\n", result.TestLog)
+	for _, url := range result.DashboardUrls {
+		fmt.Fprintf(&sb, "Dashboard: %s\n", url)
+	}
+	fmt.Fprintf(&sb, "%s", NC)
+	cmd.PrintErr(sb.String())
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// Returned by the matcher, if more than one target matches, so that the user can be asked to pick one.
+type AmbiguousMatchError struct {
+	Matches []string
+	// Printed before the selection, e.g. to explain that matching fell back to fuzzy search.
+	note string
+	msg  string
+}
+
+func (e *AmbiguousMatchError) Error() string {
+	return e.msg
+}
+
+func is_terminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Lets the user select one of the ambiguous matches, or returns the original error if no choice was made.
+func pick_target(cmd *cobra.Command, target string, ambiguous_err *AmbiguousMatchError) (string, error) {
+	var choice int
+	var err error
+	question := fmt.Sprintf("Multiple targets match `%s`, please select one:", target)
+	if len(ambiguous_err.note) > 0 {
+		cmd.Printf(CYAN + ambiguous_err.note + NC)
+	}
+	if cmd.InOrStdin() == os.Stdin && is_terminal(os.Stdin) && is_terminal(os.Stdout) {
+		choice, err = pick_option_interactively(cmd.OutOrStdout(), question, ambiguous_err.Matches)
+	} else {
+		choice, err = pick_option_by_number(cmd.InOrStdin(), cmd.OutOrStdout(), question, ambiguous_err.Matches)
+	}
+	if err != nil {
+		return "", ambiguous_err
+	}
+	cmd.Printf("%sTarget `%s` was selected ...%s\n", CYAN, ambiguous_err.Matches[choice], NC)
+	return ambiguous_err.Matches[choice], nil
+}
+
+func pick_option_by_number(in io.Reader, out io.Writer, question string, options []string) (int, error) {
+	fmt.Fprintf(out, "%s%s%s\n", CYAN, question, NC)
+	for i, option := range options {
+		fmt.Fprintf(out, "  [%d] %s\n", i+1, option)
+	}
+	fmt.Fprintf(out, "%sTarget number: %s", CYAN, NC)
+	answer, err := get_input_reader(in).ReadString('\n')
+	if err != nil && len(answer) == 0 {
+		fmt.Fprintln(out)
+		return 0, err
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || choice < 1 || choice > len(options) {
+		return 0, fmt.Errorf("invalid choice `%s`", strings.TrimSpace(answer))
+	}
+	return choice - 1, nil
+}
+
+func run_stty(args ...string) (string, error) {
+	sttyCmd := exec.Command("stty", args...)
+	sttyCmd.Stdin = os.Stdin
+	output, err := sttyCmd.Output()
+	return strings.TrimSpace(string(output)), err
+}
+
+// Renders an arrow-key navigable list. Enter selects, q/Esc/Ctrl-C cancels, digits jump to an option.
+func pick_option_interactively(out io.Writer, question string, options []string) (int, error) {
+	saved_state, err := run_stty("-g")
+	if err != nil {
+		return pick_option_by_number(os.Stdin, out, question, options)
+	}
+	if _, err := run_stty("raw", "-echo"); err != nil {
+		return pick_option_by_number(os.Stdin, out, question, options)
+	}
+	defer run_stty(saved_state)
+
+	render := func(selected int) {
+		for i, option := range options {
+			// In raw mode a line feed doesn't return the carriage.
+			if i == selected {
+				fmt.Fprintf(out, "\033[2K%s> %s%s\r\n", GREEN, option, NC)
+			} else {
+				fmt.Fprintf(out, "\033[2K  %s\r\n", option)
+			}
+		}
+	}
+	fmt.Fprintf(out, "%s%s%s (use arrow keys, enter to select, q to cancel)\r\n", CYAN, question, NC)
+	selected := 0
+	render(selected)
+	reader := get_input_reader(os.Stdin)
+	for {
+		key, err := reader.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case key == '\r' || key == '\n':
+			return selected, nil
+		case key == 'q' || key == 3: // Ctrl-C
+			return 0, fmt.Errorf("selection was cancelled")
+		case key == 27: // Escape sequences of arrow keys: ESC [ A/B
+			if reader.Buffered() == 0 {
+				return 0, fmt.Errorf("selection was cancelled")
+			}
+			if next, _ := reader.ReadByte(); next != '[' {
+				continue
+			}
+			switch arrow, _ := reader.ReadByte(); arrow {
+			case 'A':
+				selected = (selected - 1 + len(options)) % len(options)
+			case 'B':
+				selected = (selected + 1) % len(options)
+			}
+		case key == 'k':
+			selected = (selected - 1 + len(options)) % len(options)
+		case key == 'j':
+			selected = (selected + 1) % len(options)
+		case key >= '1' && key <= '9' && int(key-'1') < len(options):
+			selected = int(key - '1')
+		default:
+			continue
+		}
+		// Move the cursor back to the first option and redraw.
+		fmt.Fprintf(out, "\033[%dA", len(options))
+		render(selected)
+	}
+}
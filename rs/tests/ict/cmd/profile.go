@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Number of the longest actions and critical path components, which are printed from the profile.
+var PROFILE_SUMMARY_ACTIONS = 10
+
+// Categories of the events in Bazel's JSON trace profile.
+var PROFILE_ACTION_CATEGORY = "action processing"
+var PROFILE_CRITICAL_PATH_CATEGORY = "critical path component"
+
+// Files of Bazel's profile end with these suffixes, see --bazel-profile.
+var BAZEL_PROFILE_SUFFIXES = []string{".json", ".gz"}
+
+type profileEvent struct {
+	Category string `json:"cat"`
+	Name     string `json:"name"`
+	Phase    string `json:"ph"`
+	// Duration in microseconds.
+	Duration int64 `json:"dur"`
+}
+
+type profileTrace struct {
+	TraceEvents []profileEvent `json:"traceEvents"`
+}
+
+// Bazel compresses the profile, if the file name ends with .gz.
+func read_profile(path string) (*profileTrace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+	var trace profileTrace
+	if err := json.NewDecoder(reader).Decode(&trace); err != nil {
+		return nil, err
+	}
+	return &trace, nil
+}
+
+func get_profile_events(trace *profileTrace, category string) []profileEvent {
+	events := []profileEvent{}
+	for _, event := range trace.TraceEvents {
+		if event.Category == category && event.Phase == "X" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func print_profile_events(w io.Writer, events []profileEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Duration > events[j].Duration
+	})
+	if len(events) > PROFILE_SUMMARY_ACTIONS {
+		events = events[:PROFILE_SUMMARY_ACTIONS]
+	}
+	for _, event := range events {
+		fmt.Fprintf(w, "  %s\t%s\n", (time.Duration(event.Duration) * time.Microsecond).Round(time.Millisecond), event.Name)
+	}
+}
+
+// Prints the longest actions and the critical path of the invocation, the raw profile is kept for chrome://tracing.
+func print_profile_summary(cmd *cobra.Command, path string) {
+	trace, err := read_profile(path)
+	if err != nil {
+		cmd.PrintErrf("%sFailed to read Bazel profile %s: %s%s\n", RED, path, err, NC)
+		return
+	}
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Longest actions:\n")
+	print_profile_events(w, get_profile_events(trace, PROFILE_ACTION_CATEGORY))
+	critical_path := get_profile_events(trace, PROFILE_CRITICAL_PATH_CATEGORY)
+	var critical_path_duration int64
+	for _, event := range critical_path {
+		critical_path_duration += event.Duration
+	}
+	fmt.Fprintf(w, "Critical path (%s, %d components), its longest components:\n", (time.Duration(critical_path_duration) * time.Microsecond).Round(time.Millisecond), len(critical_path))
+	print_profile_events(w, critical_path)
+	w.Flush()
+	cmd.Printf("%s%sThe raw profile %s can be loaded in chrome://tracing.%s\n", CYAN, sb.String(), path, NC)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+func QueryCommandWithConfig(cfg *Config) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, bazel_args := split_bazel_args(cmd, args)
+		cfg.queryCfg.expression = args[0]
+		all_targets, err := get_all_system_test_targets(&cfg.queryCfg)
+		if err != nil {
+			return err
+		}
+		// Without a target, only list the tests selected by the query expression.
+		if len(args) == 1 {
+			cmd.Printf("%sThe following %d system_test targets match the query:\n%s%s\n", CYAN, len(all_targets), strings.Join(all_targets, "\n"), NC)
+			return nil
+		}
+		if target, err := resolve_target(cmd, all_targets, args[1], &cfg.matchCfg); err == nil {
+			return run_system_tests(cmd, cfg, []string{target}, bazel_args)
+		} else {
+			return err
+		}
+	}
+}
+
+func NewQueryCmd() *cobra.Command {
+	var cfg = Config{}
+	var cmd = &cobra.Command{
+		Use:     "query <bazel_query_expr> [<system_test_target>] [flags] [-- <bazel_args>]",
+		Short:   "Select system_test targets with a custom Bazel query and run one of them",
+		Example: "  ict query 'rdeps(//rs/tests/..., //rs/nns/...)'\n  ict query 'attr(tags, system_test_nightly, //rs/tests/...)' upgrade --dry-run",
+		Args:    positional_args(cobra.MinimumNArgs(1), cobra.MaximumNArgs(2)),
+		RunE:    QueryCommandWithConfig(&cfg),
+	}
+	add_test_flags(cmd, &cfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type RdepsConfig struct {
+	queryCfg QueryConfig
+	isJson   bool
+}
+
+func RdepsCommandWithConfig(cfg *RdepsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if dependent, err := get_dependent_tests(&cfg.queryCfg, args); err == nil {
+			if cfg.isJson {
+				return print_json(cmd, dependent)
+			}
+			targets := target_names(dependent)
+			cmd.Printf("%sThe following %d system_test targets depend on %s:\n%s%s\n", CYAN, len(targets), strings.Join(args, ", "), strings.Join(targets, "\n"), NC)
+			return nil
+		} else {
+			return err
+		}
+	}
+}
+
+func NewRdepsCmd() *cobra.Command {
+	var cfg = RdepsConfig{}
+	var cmd = &cobra.Command{
+		Use:     "rdeps <label>...",
+		Short:   "List system_test targets, which transitively depend on the given labels",
+		Example: "  ict rdeps //rs/consensus/...\n  ict rdeps //rs/nns/governance //rs/registry/canister --json",
+		Args:    cobra.MinimumNArgs(1),
+		RunE:    RdepsCommandWithConfig(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print targets with their attributes as JSON.")
+	add_universe_flags(cmd, &cfg.queryCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
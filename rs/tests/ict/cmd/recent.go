@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Max number of recently run targets remembered for boosting them in the match ranking.
+var RECENT_TARGETS_MAX_COUNT = 50
+
+type recentTarget struct {
+	Target  string    `json:"target"`
+	LastRun time.Time `json:"last_run"`
+	Runs    int       `json:"runs"`
+}
+
+// Returns the directory, in which ict keeps its local state, i.e. ~/.ict
+func get_ict_dir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".ict"), nil
+}
+
+func get_recent_targets_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "recent_targets.json"), nil
+}
+
+// Returns recently run targets, the most recent first.
+func load_recent_targets() []recentTarget {
+	recent := []recentTarget{}
+	recent_file, err := get_recent_targets_file()
+	if err != nil {
+		return recent
+	}
+	if content, err := os.ReadFile(recent_file); err == nil {
+		json.Unmarshal(content, &recent)
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].LastRun.After(recent[j].LastRun)
+	})
+	return recent
+}
+
+func record_recent_targets(targets []string) error {
+	recent_file, err := get_recent_targets_file()
+	if err != nil {
+		return err
+	}
+	HISTORY_TARGETS = targets
+	recent := load_recent_targets()
+	now := time.Now()
+	for _, target := range targets {
+		found := false
+		for i := range recent {
+			if recent[i].Target == target {
+				recent[i].LastRun = now
+				recent[i].Runs++
+				found = true
+			}
+		}
+		if !found {
+			recent = append(recent, recentTarget{Target: target, LastRun: now, Runs: 1})
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].LastRun.After(recent[j].LastRun)
+	})
+	if len(recent) > RECENT_TARGETS_MAX_COUNT {
+		recent = recent[:RECENT_TARGETS_MAX_COUNT]
+	}
+	content, err := json.MarshalIndent(recent, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(recent_file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(recent_file, content, 0644)
+}
+
+// Moves recently run targets to the front (most recent first), keeping the order of all others.
+func boost_recent_targets(matches []string) []string {
+	recency := map[string]int{}
+	for i, r := range load_recent_targets() {
+		recency[r.Target] = i
+	}
+	boosted := append([]string{}, matches...)
+	sort.SliceStable(boosted, func(i, j int) bool {
+		ri, is_recent_i := recency[boosted[i]]
+		rj, is_recent_j := recency[boosted[j]]
+		if is_recent_i && is_recent_j {
+			return ri < rj
+		}
+		return is_recent_i && !is_recent_j
+	})
+	return boosted
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type RecentConfig struct {
+	limit  int
+	isJson bool
+}
+
+type RerunConfig struct {
+	isDryRun bool
+}
+
+func ValidateRecentCommand(cfg *RecentConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.limit < 1 {
+			return fmt.Errorf("option --limit should be >= 1.")
+		}
+		return cobra.NoArgs(cmd, args)
+	}
+}
+
+func get_history_result_color(result string) string {
+	switch result {
+	case "PASSED", "OK":
+		return GREEN
+	case "FAILED", "ERROR":
+		return RED
+	}
+	return NC
+}
+
+func RecentCommand(cfg *RecentConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		history, err := load_history()
+		if err != nil {
+			return fmt.Errorf("\nFailed to load the history: %s", err)
+		}
+		if len(history) > cfg.limit {
+			history = history[:cfg.limit]
+		}
+		if cfg.isJson {
+			return print_json(cmd, history)
+		}
+		if len(history) == 0 {
+			cmd.Printf("%sNo invocations of ict were recorded yet.%s\n", CYAN, NC)
+			return nil
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "#\tSTARTED\tRESULT\tDURATION\tCOMMAND\tTARGETS")
+		for i, entry := range history {
+			duration := time.Duration(entry.DurationSecs * float64(time.Second)).Round(time.Second)
+			result := get_history_result_color(entry.Result) + entry.Result + NC
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", i+1, entry.StartedAt.Format("2006-01-02 15:04:05"), result, duration, shell_quote(append([]string{"ict"}, entry.Args...)), strings.Join(entry.Targets, ", "))
+		}
+		return w.Flush()
+	}
+}
+
+func ValidateRerunCommand(cmd *cobra.Command, args []string) error {
+	if err := cobra.MaximumNArgs(1)(cmd, args); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	if n, err := strconv.Atoi(args[0]); err != nil || n < 1 {
+		return fmt.Errorf("the index of the invocation should be >= 1, as listed by `ict recent`, got `%s`.", args[0])
+	}
+	return nil
+}
+
+// Replaces the process with the repeated invocation, which is then recorded in the history itself.
+func RerunCommand(cfg *RerunConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		index := 1
+		if len(args) > 0 {
+			index, _ = strconv.Atoi(args[0])
+		}
+		history, err := load_history()
+		if err != nil {
+			return fmt.Errorf("\nFailed to load the history: %s", err)
+		}
+		if len(history) < index {
+			return fmt.Errorf("\nOnly %d invocations of ict were recorded, see `ict recent`.", len(history))
+		}
+		entry := history[index-1]
+		command := append([]string{"ict"}, entry.Args...)
+		cmd.Printf("%sRerunning the invocation of %s in %s:\n$ %s%s\n", CYAN, entry.StartedAt.Format("2006-01-02 15:04:05"), entry.Dir, shell_quote(command), NC)
+		if cfg.isDryRun {
+			return nil
+		}
+		if err := os.Chdir(entry.Dir); err != nil {
+			return fmt.Errorf("\nFailed to change into the directory of the invocation: %s", err)
+		}
+		ict, err := os.Executable()
+		if err != nil {
+			return err
+		}
+		trace_command(command)
+		return syscall.Exec(ict, command, os.Environ())
+	}
+}
+
+func NewRecentCmd() *cobra.Command {
+	var cfg = RecentConfig{}
+	var cmd = &cobra.Command{
+		Use:     "recent",
+		Short:   "List previous invocations of ict with their targets and results, the most recent first",
+		Example: "  ict recent\n  ict recent --limit 50 --json",
+		Args:    ValidateRecentCommand(&cfg),
+		RunE:    RecentCommand(&cfg),
+	}
+	cmd.Flags().IntVarP(&cfg.limit, "limit", "", 20, "Max number of invocations to list.")
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the invocations with their attributes as JSON.")
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
+
+func NewRerunCmd() *cobra.Command {
+	var cfg = RerunConfig{}
+	var cmd = &cobra.Command{
+		Use:     "rerun [<n>]",
+		Short:   "Repeat the n-th most recent invocation of ict listed by `ict recent`, the last one by default",
+		Example: "  ict rerun\n  ict rerun 3 --dry-run",
+		Args:    ValidateRerunCommand,
+		RunE:    RerunCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the invocation to be repeated without execution.")
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
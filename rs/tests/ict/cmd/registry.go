@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Testnet created by ict on this machine, recorded as soon as its Farm group is known, so that it is listed even if Farm can't be queried.
+type localTestnet struct {
+	Name      string    `json:"name"`
+	Target    string    `json:"target"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Test tmpdir of the run, in which the test driver keeps the setup and the logs of the group.
+	LogDir string `json:"log_dir,omitempty"`
+	// Git branch checked out when the testnet was created, its deletion makes the testnet obsolete.
+	Branch  string `json:"branch,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+func get_local_testnets_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "testnets.json"), nil
+}
+
+// Returns all recorded testnets, including expired and deleted ones, the most recently created first.
+func load_local_testnets() []localTestnet {
+	testnets := []localTestnet{}
+	testnets_file, err := get_local_testnets_file()
+	if err != nil {
+		return testnets
+	}
+	if content, err := os.ReadFile(testnets_file); err == nil {
+		json.Unmarshal(content, &testnets)
+	}
+	sort.SliceStable(testnets, func(i, j int) bool {
+		return testnets[i].CreatedAt.After(testnets[j].CreatedAt)
+	})
+	return testnets
+}
+
+func save_local_testnets(testnets []localTestnet) error {
+	testnets_file, err := get_local_testnets_file()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(testnets_file), 0755); err != nil {
+		return err
+	}
+	content, err := json.Marshal(testnets)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(testnets_file, content, 0644)
+}
+
+func record_local_testnet(testnet localTestnet) error {
+	testnets := []localTestnet{testnet}
+	for _, t := range load_local_testnets() {
+		if t.Name != testnet.Name {
+			testnets = append(testnets, t)
+		}
+	}
+	return save_local_testnets(testnets)
+}
+
+// Applies the change to the recorded testnet, testnets not created by ict on this machine are ignored.
+func update_local_testnet(name string, update func(*localTestnet)) error {
+	testnets := load_local_testnets()
+	for i := range testnets {
+		if testnets[i].Name == name {
+			update(&testnets[i])
+			return save_local_testnets(testnets)
+		}
+	}
+	return nil
+}
+
+func remove_local_testnets(names []string) error {
+	testnets := []localTestnet{}
+	for _, t := range load_local_testnets() {
+		if !any_equals(names, t.Name) {
+			testnets = append(testnets, t)
+		}
+	}
+	return save_local_testnets(testnets)
+}
+
+// Returns the checked out branch of the workspace, empty for a detached HEAD.
+func get_current_branch() string {
+	branch, err := run_git("-C", get_workspace_root(), "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil || branch == "HEAD" {
+		return ""
+	}
+	return branch
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Bazel test exits with this code, if the build succeeded but some of the tests failed.
+var BAZEL_TESTS_FAILED_EXIT_CODE = 3
+
+type testRunStats struct {
+	Target string
+	Passed int
+	Failed int
+}
+
+type xmlTestSuite struct {
+	Failures int `xml:"failures,attr"`
+	Errors   int `xml:"errors,attr"`
+}
+
+type xmlTestSuites struct {
+	Suites []xmlTestSuite `xml:"testsuite"`
+}
+
+// Directory, in which Bazel stores test.log and test.xml of the last run of the target.
+func get_test_logs_dir(target string) string {
+	label := strings.TrimPrefix(target, "//")
+	pkg, name, _ := strings.Cut(label, ":")
+	return filepath.Join(get_workspace_root(), "bazel-testlogs", pkg, name)
+}
+
+// Reads the outcome of the last run of the target from the test.xml written by Bazel.
+func is_last_test_run_passed(target string) (bool, error) {
+	content, err := os.ReadFile(filepath.Join(get_test_logs_dir(target), "test.xml"))
+	if err != nil {
+		return false, fmt.Errorf("\nFailed to read the test result of target `%s`: %s", target, err)
+	}
+	var result xmlTestSuites
+	if err := xml.Unmarshal(content, &result); err != nil {
+		return false, fmt.Errorf("\nFailed to parse the test result of target `%s`: %s", target, err)
+	}
+	for _, suite := range result.Suites {
+		if suite.Failures > 0 || suite.Errors > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func run_bazel_command(command []string) error {
+	// Start Bazel test Command with stdout, stderr streaming.
+	return run_interruptible_bazel_command(command, get_bazel_stdout())
+}
+
+// Runs the Bazel command once and collects the outcome for each of the targets.
+// Only failures of the tests themselves are reported per target, other failures, e.g. of the build, are returned as errors.
+func run_bazel_test_iteration(command []string, targets []string) (map[string]bool, error) {
+	err := run_bazel_command(command)
+	if exit_err, ok := err.(*exec.ExitError); err != nil && !(ok && exit_err.ExitCode() == BAZEL_TESTS_FAILED_EXIT_CODE) {
+		return nil, err
+	}
+	passed := map[string]bool{}
+	for _, target := range targets {
+		if err == nil {
+			passed[target] = true
+		} else if passed[target], err = is_last_test_run_passed(target); err != nil {
+			return nil, err
+		}
+	}
+	return passed, nil
+}
+
+// Runs the targets sequentially the given number of times and prints a summary of their flakiness.
+// Runs are sequential rather than via --runs_per_test, so that concurrent runs don't compete for Farm resources.
+// With stop_on_failure the loop ends with the first failed run, repeat = 0 then means no limit on the number of runs.
+func run_repeated_tests(cmd *cobra.Command, command []string, targets []string, repeat int, stop_on_failure bool) error {
+	stats := make([]testRunStats, len(targets))
+	for i, target := range targets {
+		stats[i].Target = target
+	}
+	for iteration := 1; repeat == 0 || iteration <= repeat; iteration++ {
+		if repeat == 0 {
+			cmd.Printf("%sRun %d ...%s\n", CYAN, iteration, NC)
+		} else {
+			cmd.Printf("%sRun %d of %d ...%s\n", CYAN, iteration, repeat, NC)
+		}
+		passed, err := run_bazel_test_iteration(command, targets)
+		if err != nil {
+			print_flakiness_summary(cmd, stats)
+			return fmt.Errorf("\nRun %d couldn't be completed: %w", iteration, err)
+		}
+		failed_targets := []string{}
+		for i := range stats {
+			if passed[stats[i].Target] {
+				stats[i].Passed++
+			} else {
+				stats[i].Failed++
+				failed_targets = append(failed_targets, stats[i].Target)
+			}
+		}
+		if stop_on_failure && len(failed_targets) > 0 {
+			print_flakiness_summary(cmd, stats)
+			return preserved_failure_error(cmd, failed_targets, iteration)
+		}
+	}
+	print_flakiness_summary(cmd, stats)
+	failed := 0
+	for _, s := range stats {
+		failed += s.Failed
+	}
+	if failed > 0 {
+		return fmt.Errorf("\n%d of %d test runs failed.", failed, repeat*len(targets))
+	}
+	return nil
+}
+
+// Copies logs and outputs of the failed run out of bazel-testlogs, which are overwritten by the next Bazel invocation.
+func preserved_failure_error(cmd *cobra.Command, failed_targets []string, iteration int) error {
+	preserved_dir, err := os.MkdirTemp("", "ict_failed_run_")
+	if err != nil {
+		return fmt.Errorf("\nTargets %s failed in run %d, their logs are in %s", strings.Join(failed_targets, ", "), iteration, filepath.Dir(get_test_logs_dir(failed_targets[0])))
+	}
+	for _, target := range failed_targets {
+		dst := filepath.Join(preserved_dir, get_target_dir_name(target))
+		if err := copy_dir(get_test_logs_dir(target), dst); err != nil {
+			cmd.PrintErrf("%sFailed to preserve logs of target `%s`: %s%s\n", RED, target, err, NC)
+		}
+	}
+	return fmt.Errorf("\nTargets %s failed in run %d, logs and artifacts of the failed run were preserved in %s", strings.Join(failed_targets, ", "), iteration, preserved_dir)
+}
+
+func copy_dir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0644)
+	})
+}
+
+func print_flakiness_summary(cmd *cobra.Command, stats []testRunStats) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tRUNS\tPASSED\tFAILED\tFLAKE RATE")
+	for _, s := range stats {
+		runs := s.Passed + s.Failed
+		rate := 0.0
+		if runs > 0 {
+			rate = 100 * float64(s.Failed) / float64(runs)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f%%\n", s.Target, runs, s.Passed, s.Failed, rate)
+	}
+	w.Flush()
+}
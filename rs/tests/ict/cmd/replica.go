@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Public API port of the replica, see PublicApi in rs/tests/src/driver/port_allocator.rs
+var REPLICA_PUBLIC_API_PORT = 8080
+
+var REPLICA_STATUS_TIMEOUT = 5 * time.Second
+
+// Subset of HttpStatusResponse, see rs/types/types/src/messages/http.rs
+type replicaStatus struct {
+	ImplVersion         string `json:"impl_version"`
+	ReplicaHealthStatus string `json:"replica_health_status"`
+	CertifiedHeight     uint64 `json:"certified_height"`
+}
+
+func get_replica_status(ipv6 string) (*replicaStatus, error) {
+	client := http.Client{Timeout: REPLICA_STATUS_TIMEOUT}
+	resp, err := client.Get(fmt.Sprintf("http://[%s]:%d/api/v2/status", ipv6, REPLICA_PUBLIC_API_PORT))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status check failed with %s", resp.Status)
+	}
+	value, _, err := decode_cbor(body)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected status response: %s", err)
+	}
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected status response: not a map")
+	}
+	status := replicaStatus{}
+	status.ImplVersion, _ = fields["impl_version"].(string)
+	status.ReplicaHealthStatus, _ = fields["replica_health_status"].(string)
+	status.CertifiedHeight, _ = fields["certified_height"].(uint64)
+	return &status, nil
+}
+
+// Decodes the CBOR data items the replica uses in its status response, i.e. maps with text keys, text and byte strings,
+// unsigned integers and the self-describing tag. Returns the value and the number of bytes it spans.
+func decode_cbor(data []byte) (interface{}, int, error) {
+	if len(data) == 0 {
+		return nil, 0, fmt.Errorf("unexpected end of CBOR data")
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	arg, n := uint64(info), 1
+	// Arguments 24 to 27 follow in the next 1, 2, 4 or 8 bytes.
+	if size := 1 << (info - 24); info >= 24 && info <= 27 && len(data) < 1+size {
+		return nil, 0, fmt.Errorf("unexpected end of CBOR data")
+	}
+	switch {
+	case info == 24:
+		arg, n = uint64(data[1]), 2
+	case info == 25:
+		arg, n = uint64(binary.BigEndian.Uint16(data[1:])), 3
+	case info == 26:
+		arg, n = uint64(binary.BigEndian.Uint32(data[1:])), 5
+	case info == 27:
+		arg, n = binary.BigEndian.Uint64(data[1:]), 9
+	case info == 31:
+		return nil, 0, fmt.Errorf("indefinite-length CBOR items aren't supported")
+	case info >= 24:
+		return nil, 0, fmt.Errorf("unsupported CBOR argument %d", info)
+	}
+	switch major {
+	case 0:
+		return arg, n, nil
+	case 1:
+		return -1 - int64(arg), n, nil
+	case 2, 3:
+		if uint64(len(data)-n) < arg {
+			return nil, 0, fmt.Errorf("unexpected end of CBOR data")
+		}
+		if major == 2 {
+			return data[n : n+int(arg)], n + int(arg), nil
+		}
+		return string(data[n : n+int(arg)]), n + int(arg), nil
+	case 4:
+		items := []interface{}{}
+		for i := uint64(0); i < arg; i++ {
+			item, m, err := decode_cbor(data[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			n += m
+		}
+		return items, n, nil
+	case 5:
+		fields := map[string]interface{}{}
+		for i := uint64(0); i < arg; i++ {
+			key, m, err := decode_cbor(data[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			n += m
+			value, m, err := decode_cbor(data[n:])
+			if err != nil {
+				return nil, 0, err
+			}
+			n += m
+			fields[fmt.Sprint(key)] = value
+		}
+		return fields, n, nil
+	case 6:
+		// Tags, e.g. the self-describing 55799 the replica prefixes responses with, don't change the value.
+		value, m, err := decode_cbor(data[n:])
+		return value, n + m, err
+	default:
+		switch info {
+		case 20:
+			return false, n, nil
+		case 21:
+			return true, n, nil
+		case 22, 23:
+			return nil, n, nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), n, nil
+		case 27:
+			return math.Float64frombits(arg), n, nil
+		}
+		return nil, 0, fmt.Errorf("unsupported CBOR simple value %d", info)
+	}
+}
@@ -0,0 +1,103 @@
+package cmd_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+// Response of /api/v2/status of a replica, prefixed with the self-describing tag 55799.
+var replicaStatusCbor = "d9d9f7a66e69635f6170695f76657273696f6e66302e31382e3068726f6f745f6b65795885308182301d060d2b0601040182" +
+	"dc7c0503010201060c2b0601040182dc7c05030201036100000102030405060708090a0b0c0d0e0f10111213141516171819" +
+	"1a1b1c1d1e1f202122232425262728292a2b2c2d2e2f303132333435363738393a3b3c3d3e3f404142434445464748494a4b" +
+	"4c4d4e4f505152535455565758595a5b5c5d5e5f6c696d706c5f76657273696f6e7828336263636365663037343038393231" +
+	"6665383439633932646432343337616463313537656639633369696d706c5f68617368784063386435623863346538613762" +
+	"6362623562303135316133616435343364366437323137643563346365326331613461306630613162323965613364356538" +
+	"63757265706c6963615f6865616c74685f737461747573676865616c746879706365727469666965645f6865696768741a00" +
+	"12d687"
+
+func decodeHex(t *testing.T, s string) []byte {
+	data, err := hex.DecodeString(s)
+	assert.Nil(t, err)
+	return data
+}
+
+func Test_DecodeCborReplicaStatus(t *testing.T) {
+	data := decodeHex(t, replicaStatusCbor)
+
+	value, n, err := cmd.DecodeCbor(data)
+
+	assert.Nil(t, err)
+	assert.Equal(t, len(data), n)
+	fields, ok := value.(map[string]interface{})
+	assert.True(t, ok)
+	assert.Equal(t, "0.18.0", fields["ic_api_version"])
+	assert.Equal(t, "3bcccef07408921fe849c92dd2437adc157ef9c3", fields["impl_version"])
+	assert.Equal(t, "healthy", fields["replica_health_status"])
+	assert.Equal(t, uint64(1234567), fields["certified_height"])
+	assert.Len(t, fields["root_key"], 133)
+}
+
+func Test_DecodeCbor(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected interface{}
+		n        int
+	}{
+		{"17", uint64(23), 1},
+		{"1818", uint64(24), 2},
+		{"1903e8", uint64(1000), 3},
+		{"1a000f4240", uint64(1000000), 5},
+		{"1b000000e8d4a51000", uint64(1000000000000), 9},
+		{"29", int64(-10), 1},
+		{"6449455446", "IETF", 5},
+		{"60", "", 1},
+		{"4401020304", []byte{1, 2, 3, 4}, 5},
+		{"83010203", []interface{}{uint64(1), uint64(2), uint64(3)}, 4},
+		{"a26161016162820203", map[string]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}}, 9},
+		{"f4", false, 1},
+		{"f5", true, 1},
+		{"f6", nil, 1},
+		{"fb3ff199999999999a", 1.1, 9},
+		// Only the first item is decoded, trailing bytes are left to the caller.
+		{"0102", uint64(1), 1},
+	}
+	for _, test := range tests {
+		value, n, err := cmd.DecodeCbor(decodeHex(t, test.data))
+
+		assert.Nil(t, err, test.data)
+		assert.Equal(t, test.expected, value, test.data)
+		assert.Equal(t, test.n, n, test.data)
+	}
+}
+
+func Test_DecodeCborErrors(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"", "unexpected end of CBOR data"},
+		{"18", "unexpected end of CBOR data"},
+		{"1903", "unexpected end of CBOR data"},
+		{"1b0000", "unexpected end of CBOR data"},
+		{"64494554", "unexpected end of CBOR data"},
+		{"5bffffffffffffffff00", "unexpected end of CBOR data"},
+		{"830102", "unexpected end of CBOR data"},
+		{"a26161016162", "unexpected end of CBOR data"},
+		{"a161", "unexpected end of CBOR data"},
+		{"d9d9f7", "unexpected end of CBOR data"},
+		{replicaStatusCbor[:len(replicaStatusCbor)-2], "unexpected end of CBOR data"},
+		{"5f42010243030405ff", "indefinite-length CBOR items aren't supported"},
+		{"9f0102ff", "indefinite-length CBOR items aren't supported"},
+		{"bf616101ff", "indefinite-length CBOR items aren't supported"},
+		{"1c", "unsupported CBOR argument 28"},
+		{"f0", "unsupported CBOR simple value 16"},
+	}
+	for _, test := range tests {
+		_, _, err := cmd.DecodeCbor(decodeHex(t, test.data))
+
+		assert.EqualError(t, err, test.expected, test.data)
+	}
+}
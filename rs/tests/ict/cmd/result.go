@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// The test driver prints links to dashboards of the farm group, e.g. "See replica logs in Kibana: https://...".
+var DASHBOARD_URL_REGEX = regexp.MustCompile(`(?:Kibana|Grafana)[^:]*(?::| at) *(https?://\S+)`)
+var FARM_GROUP_TAG_REGEX = regexp.MustCompile(`tags:"([^"]+)"`)
+
+type testResult struct {
+	Target        string   `json:"target"`
+	Status        string   `json:"status"`
+	DurationSecs  float64  `json:"duration_secs"`
+	TestLog       string   `json:"test_log"`
+	TestXml       string   `json:"test_xml"`
+	OutputsDir    string   `json:"outputs_dir"`
+	FarmGroup     string   `json:"farm_group,omitempty"`
+	DashboardUrls []string `json:"dashboard_urls"`
+}
+
+type runSummary struct {
+	StartedAt time.Time    `json:"started_at"`
+	Results   []testResult `json:"results"`
+}
+
+// Collects the outcome of the last run of the target from its test.xml and test.log.
+func get_test_result(target string, since time.Time) (testResult, error) {
+	return get_test_result_in_dir(target, get_test_logs_dir(target), since)
+}
+
+func get_test_result_in_dir(target string, logs_dir string, since time.Time) (testResult, error) {
+	result := testResult{
+		Target:        target,
+		Status:        "NO_RESULT",
+		TestLog:       filepath.Join(logs_dir, "test.log"),
+		TestXml:       filepath.Join(logs_dir, "test.xml"),
+		OutputsDir:    filepath.Join(logs_dir, "test.outputs"),
+		DashboardUrls: []string{},
+	}
+	suites, err := read_test_xml_in_dir(target, logs_dir, since)
+	if err != nil || suites == nil {
+		return result, err
+	}
+	result.Status = "PASSED"
+	for _, suite := range suites.Suites {
+		if suite.Failures > 0 || suite.Errors > 0 {
+			result.Status = "FAILED"
+		}
+		for _, test_case := range suite.get_test_cases() {
+			if secs, err := strconv.ParseFloat(test_case.Time, 64); err == nil {
+				result.DurationSecs += secs
+			}
+		}
+	}
+	if content, err := os.ReadFile(result.TestLog); err == nil {
+		for _, match := range DASHBOARD_URL_REGEX.FindAllStringSubmatch(string(content), -1) {
+			if !any_equals(result.DashboardUrls, match[1]) {
+				result.DashboardUrls = append(result.DashboardUrls, match[1])
+			}
+			unescaped, err := url.QueryUnescape(match[1])
+			if group := FARM_GROUP_TAG_REGEX.FindStringSubmatch(unescaped); err == nil && group != nil {
+				result.FarmGroup = group[1]
+			}
+		}
+	}
+	return result, nil
+}
+
+func write_result_json(targets []string, path string, since time.Time) error {
+	summary := runSummary{StartedAt: since, Results: []testResult{}}
+	for _, target := range targets {
+		result, err := get_test_result(target, since)
+		if err != nil {
+			return err
+		}
+		summary.Results = append(summary.Results, result)
+	}
+	content, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// State of the last interrupted multi-target run, from which `ict test --resume` continues.
+type resumeState struct {
+	StartedAt time.Time `json:"started_at"`
+	Completed []string  `json:"completed"`
+	Remaining []string  `json:"remaining"`
+	// Bazel args following the --, which are passed again by the resumed run.
+	BazelArgs []string `json:"bazel_args"`
+}
+
+func get_resume_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "resume.json"), nil
+}
+
+func load_resume_state() (*resumeState, error) {
+	resume_file, err := get_resume_file()
+	if err != nil {
+		return nil, err
+	}
+	content, err := os.ReadFile(resume_file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there is no interrupted run to resume.")
+	} else if err != nil {
+		return nil, err
+	}
+	var state resumeState
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, fmt.Errorf("resume file %s is corrupted: %s", resume_file, err)
+	}
+	return &state, nil
+}
+
+func write_resume_state(state *resumeState) error {
+	resume_file, err := get_resume_file()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(resume_file), 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(resume_file, content, 0644)
+}
+
+func remove_resume_state() {
+	if resume_file, err := get_resume_file(); err == nil {
+		os.Remove(resume_file)
+	}
+}
+
+func run_resumed_tests(cmd *cobra.Command, cfg *Config, bazel_args []string) error {
+	state := cfg.resumeState
+	cmd.Printf("%sResuming the run started at %s, %d targets have passed already, %d remain:\n%s%s\n", CYAN, state.StartedAt.Format(time.RFC1123), len(state.Completed), len(state.Remaining), strings.Join(state.Remaining, "\n"), NC)
+	return run_system_tests(cmd, cfg, state.Remaining, append(append([]string{}, state.BazelArgs...), bazel_args...))
+}
+
+// Once a multi-target run is interrupted, the targets which haven't passed yet are remembered for `ict test --resume`.
+// Completing a resumed run, whether its targets pass or not, removes the resume file.
+func update_resume_state(cmd *cobra.Command, cfg *Config, targets []string, bazel_args []string, since time.Time, err error) {
+	var interrupted *bazelInterruptedError
+	if !errors.As(err, &interrupted) {
+		if cfg.resumeState != nil {
+			remove_resume_state()
+		}
+		return
+	}
+	if len(targets) < 2 && cfg.resumeState == nil {
+		return
+	}
+	state := resumeState{StartedAt: since, Completed: []string{}, Remaining: []string{}, BazelArgs: bazel_args}
+	if cfg.resumeState != nil {
+		state.StartedAt = cfg.resumeState.StartedAt
+		state.Completed = append(state.Completed, cfg.resumeState.Completed...)
+		// The bazel args of the resumed run already include those of the original one.
+		state.BazelArgs = bazel_args
+	}
+	for _, target := range targets {
+		if result, err := get_test_result(target, since); err == nil && result.Status == "PASSED" {
+			state.Completed = append(state.Completed, target)
+		} else {
+			state.Remaining = append(state.Remaining, target)
+		}
+	}
+	if len(state.Remaining) == 0 {
+		remove_resume_state()
+		return
+	}
+	if write_err := write_resume_state(&state); write_err != nil {
+		cmd.PrintErrf("%sFailed to write the resume file: %s%s\n", RED, write_err, NC)
+		return
+	}
+	cmd.Printf("%s%d of %d targets have passed, continue with the remaining %d ones via:\n$ ict test --resume%s\n", CYAN, len(state.Completed), len(state.Completed)+len(state.Remaining), len(state.Remaining), NC)
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Failures with these messages in the test log are caused by the infrastructure rather than by the test itself.
+var INFRA_FAILURE_SIGNATURES = []string{
+	// Farm
+	"Retried too many times",
+	"Invalid response",
+	"error sending request for url",
+	"Could not allocate",
+	"Insufficient resources",
+	// Downloads of images and other artifacts
+	"Failed to download",
+	"download timed out",
+	"operation timed out",
+	"connection reset by peer",
+}
+
+// Returns the first infra failure signature found in the test log of the last run of the target.
+func get_infra_failure_signature(target string) (string, bool) {
+	content, err := os.ReadFile(filepath.Join(get_test_logs_dir(target), "test.log"))
+	if err != nil {
+		return "", false
+	}
+	log := strings.ToLower(string(content))
+	for _, signature := range INFRA_FAILURE_SIGNATURES {
+		if strings.Contains(log, strings.ToLower(signature)) {
+			return signature, true
+		}
+	}
+	return "", false
+}
+
+type testAttempts struct {
+	Target   string
+	Attempts int
+	Passed   bool
+	// Signature of the infra failure of the last retried attempt, if any.
+	Signature string
+}
+
+// Runs the targets and re-runs only those of them, which failed because of the infrastructure.
+func run_tests_with_retries(cmd *cobra.Command, targets []string, retries int, get_command func(targets []string) []string) error {
+	attempts := map[string]*testAttempts{}
+	for _, target := range targets {
+		attempts[target] = &testAttempts{Target: target}
+	}
+	pending := targets
+	for attempt := 0; attempt <= retries && len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			cmd.Printf("%sRetry %d of %d for targets with infra failures:\n%s%s\n", CYAN, attempt, retries, strings.Join(pending, "\n"), NC)
+		}
+		passed, err := run_bazel_test_iteration(get_command(pending), pending)
+		if err != nil {
+			return err
+		}
+		retried := []string{}
+		for _, target := range pending {
+			attempts[target].Attempts++
+			attempts[target].Passed = passed[target]
+			if passed[target] {
+				continue
+			}
+			if signature, ok := get_infra_failure_signature(target); ok {
+				attempts[target].Signature = signature
+				retried = append(retried, target)
+			}
+		}
+		pending = retried
+	}
+	print_retries_summary(cmd, targets, attempts)
+	failed := 0
+	for _, a := range attempts {
+		if !a.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("\n%d of %d targets failed.", failed, len(targets))
+	}
+	return nil
+}
+
+func print_retries_summary(cmd *cobra.Command, targets []string, attempts map[string]*testAttempts) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tATTEMPTS\tRESULT\tINFRA FAILURE")
+	for _, target := range targets {
+		a := attempts[target]
+		result := "FAILED"
+		if a.Passed && a.Attempts > 1 {
+			result = "PASSED (on retry)"
+		} else if a.Passed {
+			result = "PASSED"
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", a.Target, a.Attempts, result, a.Signature)
+	}
+	w.Flush()
+}
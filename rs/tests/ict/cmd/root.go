@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"os"
 	"regexp"
 	"strings"
 
@@ -8,12 +10,58 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// Commands, for which an invalid config is only a warning, so that it doesn't stand in the way of fixing it, e.g. via `ict init`.
+var CONFIG_WARNING_COMMANDS = []string{"init", "help", "completion", "recent", "rerun"}
+
+// Hidden commands of cobra, which complete the command line for the shell, run without the config.
+var CONFIGLESS_COMMANDS = []string{cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}
+
+// PersistentPreRunE of the root, commands with a PersistentPreRunE of their own, e.g. `ict testnet`, have to call it first.
+func prepare_command(cmd *cobra.Command, args []string) error {
+	// `ict test --profile <file>` used to write Bazel's trace profile, which is --bazel-profile since --profile selects profiles of the config.
+	if profile, _ := cmd.Flags().GetString(PROFILE_FLAG); any_has_suffix(profile, BAZEL_PROFILE_SUFFIXES) {
+		return fmt.Errorf("option --%s selects a profile of the config, Bazel's trace profile `%s` is written via --bazel-profile.", PROFILE_FLAG, profile)
+	}
+	applied := []string{}
+	var err error
+	if !any_equals(CONFIGLESS_COMMANDS, cmd.Name()) {
+		applied, err = load_config(cmd.Root())
+	}
+	if no_color, _ := cmd.Flags().GetBool("no-color"); no_color || len(os.Getenv("NO_COLOR")) > 0 || !is_terminal(os.Stdout) {
+		disable_colors()
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		set_verbosity(cmd.Root(), VERBOSITY_QUIET)
+	} else {
+		verbose, _ := cmd.Flags().GetCount("verbose")
+		set_verbosity(cmd.Root(), verbose)
+	}
+	if err != nil && any_equals(CONFIG_WARNING_COMMANDS, cmd.Name()) {
+		fmt.Fprintf(cmd.ErrOrStderr(), "%sIgnoring the invalid config: %s%s\n", YELLOW, err, NC)
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("\nInvalid config: %s", err)
+	}
+	for _, line := range applied {
+		log_verbose("Config: %s", line)
+	}
+	// Cobra validates the args before this runs, so they are validated again with the defaults of the config.
+	if len(applied) > 0 {
+		return cmd.ValidateArgs(args)
+	}
+	return nil
+}
+
 func NewRootCmd() *cobra.Command {
 	var version = "0.1.0"
+	var noColor bool
+	var verbose int
+	var quiet bool
+	var profile string
 	var rootCmd = &cobra.Command{
 		Version: version,
 		Use:     "ict",
-		Long:    "ict " + version + "\nA simple CLI for running system_tests in Bazel.",
+		Long:    "ict " + version + "\nA simple CLI for running system_tests in Bazel.\n\nDefaults of the flags, e.g. `farm-dc = \"zh1\"` and `bazel-flags = [\"--config=local\"]`, are read from ~/.config/ict/config.toml\nand from .ict.toml at the root of the repo, flags on the command line take precedence over the config of the repo, which takes precedence over the one of the user.\nEach flag can also be set via an ICT_* environment variable, e.g. ICT_KEEPALIVE=1 or ICT_FARM_DC=zh1, which take precedence over the config.\nProfiles bundle flags, e.g. `env = [\"FEATURE_FLAG=1\"]`, in [profile.<name>] sections to be selected via --profile <name>.\nTargets can be given short names in the [alias] section, e.g. `nnsup = \"//rs/tests/nns:nns_upgrade_test\"`.",
 		Example: "ict test //rs/tests:basic_health_test",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Print help by default, i.e. if no args are provided.
@@ -44,5 +92,11 @@ func NewRootCmd() *cobra.Command {
 	usageTemplate = re.ReplaceAllLiteralString(usageTemplate, `{{StyleHeading "Flags:"}}`)
 	rootCmd.SetUsageTemplate(usageTemplate)
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.PersistentFlags().BoolVarP(&noColor, "no-color", "", false, "Print without colors, as when NO_COLOR is set or stdout isn't a terminal.")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Print more of ict's own messages, -vv additionally prints every external command executed with its duration.")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "", false, "Only print the final result line, e.g. for scripts.")
+	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet")
+	rootCmd.PersistentFlags().StringVarP(&profile, PROFILE_FLAG, "", "", fmt.Sprintf("Name of the profile, whose flags of the [%s<name>] section of the config are applied, e.g. debug (Bazel's trace profile is written via `ict test --bazel-profile`).", PROFILE_SECTION_PREFIX))
+	rootCmd.PersistentPreRunE = prepare_command
 	return rootCmd
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Subset of the targets, which is run by one of several workers via --shard <index>/<count>.
+type testShard struct {
+	index int
+	count int
+}
+
+func parse_test_shard(value string) (*testShard, error) {
+	index, count, found := strings.Cut(value, "/")
+	shard := &testShard{}
+	var index_err, count_err error
+	shard.index, index_err = strconv.Atoi(index)
+	shard.count, count_err = strconv.Atoi(count)
+	if !found || index_err != nil || count_err != nil || shard.count < 1 || shard.index < 1 || shard.index > shard.count {
+		return nil, fmt.Errorf("option --shard should be of the form <index>/<count> with 1 <= index <= count, got `%s`.", value)
+	}
+	return shard, nil
+}
+
+// Targets are sorted and dealt out round-robin, so that all workers agree on the split regardless of the order of matching.
+func (s *testShard) select_targets(targets []string) []string {
+	sorted := append([]string{}, targets...)
+	sort.Strings(sorted)
+	selected := []string{}
+	for i, target := range sorted {
+		if i%s.count == s.index-1 {
+			selected = append(selected, target)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+var SPINNER_FRAMES = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+var SPINNER_INTERVAL = 100 * time.Millisecond
+
+// Logged by the test driver when it spawns the process of a task, i.e. the setup or one of the tests, see rs/tests/src/driver/subprocess_task.rs
+var TASK_SPAWNED_REGEX = regexp.MustCompile(`Spawning .*"spawn-child" "([^"]+)"`)
+
+// Same as SETUP_TASK_NAME of the test driver, see rs/tests/src/driver/group.rs
+var SETUP_TASK_NAME = "setup"
+
+// Spinner with the elapsed time on stderr, while ict waits silently, e.g. for bazel query.
+// It is only drawn on terminals, so that logs aren't polluted.
+type spinner struct {
+	label   string
+	started time.Time
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func start_spinner(label string) *spinner {
+	s := &spinner{label: label, started: time.Now(), done: make(chan struct{})}
+	if !is_terminal(os.Stderr) || VERBOSITY == VERBOSITY_QUIET {
+		return s
+	}
+	s.stopped.Add(1)
+	go func() {
+		defer s.stopped.Done()
+		ticker := time.NewTicker(SPINNER_INTERVAL)
+		defer ticker.Stop()
+		for frame := 0; ; frame++ {
+			fmt.Fprintf(os.Stderr, "\r\033[2K%s%s %s (%s)%s", CYAN, SPINNER_FRAMES[frame%len(SPINNER_FRAMES)], s.label, time.Since(s.started).Round(time.Second), NC)
+			select {
+			case <-ticker.C:
+			case <-s.done:
+				fmt.Fprint(os.Stderr, "\r\033[2K")
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *spinner) stop() {
+	close(s.done)
+	s.stopped.Wait()
+}
+
+// Phase of a bazel test run, as told by its streamed output, i.e. building → provisioning → running.
+// Bazel draws its own progress on the terminal, so phase changes are printed as lines instead of a spinner.
+type testPhase struct {
+	name    string
+	started time.Time
+	pending bool
+}
+
+func new_test_phase() *testPhase {
+	return &testPhase{name: "building", started: time.Now(), pending: true}
+}
+
+func (p *testPhase) update(line []byte) {
+	name := p.name
+	if FARM_GROUP_CREATED_REGEX.Match(line) {
+		name = "provisioning"
+	} else if match := TASK_SPAWNED_REGEX.FindSubmatch(line); match != nil {
+		if string(match[1]) == SETUP_TASK_NAME {
+			name = "provisioning"
+		} else {
+			name = "running " + string(match[1])
+		}
+	}
+	if name != p.name {
+		p.name, p.pending = name, true
+	}
+}
+
+func (p *testPhase) format() string {
+	p.pending = false
+	return fmt.Sprintf("%s[ict %s] %s ...%s\n", CYAN, time.Since(p.started).Round(time.Second), p.name, NC)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Printed by the test driver with --list-tests, one line per setup or test function of the group.
+var TEST_STEP_REGEX = regexp.MustCompile(`(?m)^(Setup|Test) function: (\S+)$`)
+
+// Names of the setup and test functions of the target, i.e. the steps of the scenario.
+func get_test_steps(target string, bazel_args []string) ([]string, error) {
+	command := append([]string{"bazel", "test", target, "--config=systest", "--cache_test_results=no", "--test_output=streamed", "--test_arg=--list-tests"}, bazel_args...)
+	listCmd := exec.Command(command[0], command[1:]...)
+	var stdout bytes.Buffer
+	listCmd.Stdout = &stdout
+	listCmd.Stderr = get_bazel_stderr()
+	traced := trace_command(command)
+	err := listCmd.Run()
+	traced(err)
+	if err != nil {
+		return []string{}, fmt.Errorf("\nFailed to list the steps of %s: %s", target, err)
+	}
+	steps := []string{}
+	for _, match := range TEST_STEP_REGEX.FindAllStringSubmatch(stdout.String(), -1) {
+		steps = append(steps, match[2])
+	}
+	return steps, nil
+}
+
+func print_test_steps(cmd *cobra.Command, target string, bazel_args []string) error {
+	steps, err := get_test_steps(target, bazel_args)
+	if err != nil {
+		return err
+	}
+	if len(steps) == 0 {
+		return fmt.Errorf("\nTarget %s has no steps, it might not be a system test group.", target)
+	}
+	cmd.Printf("%sSteps of %s:\n  %s\nRerun a single step against an environment kept alive via --keepalive with:\n$ ict test %s --step <name> --reuse-env <farm_group>%s\n", CYAN, target, strings.Join(steps, "\n  "), get_label_name(target), NC)
+	return nil
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// All system_test targets are discovered in these packages, testnets are then selected by tag.
+var DEFAULT_QUERY_UNIVERSE = []string{"//rs/tests/..."}
+var DYNAMIC_TESTNET_TAG = "dynamic_testnet"
+
+// Upper bounds of bazel test timeouts in seconds, see https://bazel.build/reference/test-encyclopedia
+var BAZEL_TEST_TIMEOUT_SECS = map[string]int{
+	"short":    60,
+	"moderate": 300,
+	"long":     900,
+	"eternal":  3600,
+}
+
+type TestTarget struct {
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Size    string   `json:"size"`
+	Timeout string   `json:"timeout"`
+	Flaky   bool     `json:"flaky"`
+}
+
+func (cfg *QueryConfig) get_universe() []string {
+	if len(cfg.universe) == 0 {
+		return DEFAULT_QUERY_UNIVERSE
+	}
+	return cfg.universe
+}
+
+func (t *TestTarget) HasTag(tag string) bool {
+	return any_equals(t.Tags, tag)
+}
+
+func any_has_tag(t *TestTarget, tags []string) bool {
+	for _, tag := range tags {
+		if t.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+type xmlQueryResult struct {
+	Rules []xmlRule `xml:"rule"`
+}
+
+type xmlAttribute struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type xmlList struct {
+	Name   string         `xml:"name,attr"`
+	Values []xmlAttribute `xml:"string"`
+}
+
+type xmlRule struct {
+	Name     string         `xml:"name,attr"`
+	Strings  []xmlAttribute `xml:"string"`
+	Booleans []xmlAttribute `xml:"boolean"`
+	Lists    []xmlList      `xml:"list"`
+}
+
+func parse_xml_query_output(output []byte) ([]TestTarget, error) {
+	// Bazel emits an XML 1.1 header, which encoding/xml refuses to decode.
+	if idx := bytes.Index(output, []byte("?>")); bytes.HasPrefix(output, []byte("<?xml")) && idx >= 0 {
+		output = output[idx+2:]
+	}
+	var result xmlQueryResult
+	if err := xml.Unmarshal(output, &result); err != nil {
+		return []TestTarget{}, fmt.Errorf("Failed to parse Bazel query output: %s", err)
+	}
+	targets := make([]TestTarget, 0, len(result.Rules))
+	for _, rule := range result.Rules {
+		target := TestTarget{Name: rule.Name, Tags: []string{}}
+		for _, attr := range rule.Strings {
+			switch attr.Name {
+			case "size":
+				target.Size = attr.Value
+			case "timeout":
+				target.Timeout = attr.Value
+			}
+		}
+		for _, attr := range rule.Booleans {
+			if attr.Name == "flaky" {
+				target.Flaky = attr.Value == "true"
+			}
+		}
+		for _, list := range rule.Lists {
+			if list.Name == "tags" {
+				for _, tag := range list.Values {
+					target.Tags = append(target.Tags, tag.Value)
+				}
+			}
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// Bazel query exits with this code, if --keep_going was set and only some of the targets could be resolved.
+var BAZEL_PARTIAL_RESULT_EXIT_CODE = 3
+
+func run_bazel_xml_query(query string, flags ...string) ([]TestTarget, error) {
+	workspace, err := get_ic_workspace_root()
+	if err != nil {
+		return []TestTarget{}, err
+	}
+	command := []string{"bazel", "query", query, "--output=xml"}
+	command = append(command, flags...)
+	spinner := start_spinner("querying bazel targets")
+	defer spinner.stop()
+	queryCmd := exec.Command(command[0], command[1:]...)
+	// Relative file labels, e.g. from git diff, are resolved against the workspace root.
+	queryCmd.Dir = workspace
+	outputBuffer := &bytes.Buffer{}
+	stdErrBuffer := &bytes.Buffer{}
+	queryCmd.Stdout = outputBuffer
+	queryCmd.Stderr = stdErrBuffer
+	traced := trace_command(command)
+	err = queryCmd.Run()
+	traced(err)
+	if err != nil {
+		exit_err, is_exit_err := err.(*exec.ExitError)
+		is_partial := is_exit_err && exit_err.ExitCode() == BAZEL_PARTIAL_RESULT_EXIT_CODE && any_equals(flags, "--keep_going")
+		if !is_partial {
+			return []TestTarget{}, fmt.Errorf("Bazel command: [%s] failed: %s", strings.Join(command, " "), stdErrBuffer.String())
+		}
+	}
+	return parse_xml_query_output(outputBuffer.Bytes())
+}
+
+func target_names(targets []TestTarget) []string {
+	names := make([]string, 0, len(targets))
+	for _, t := range targets {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+func filter_targets(targets []TestTarget, f func(*TestTarget) bool) []TestTarget {
+	filtered := make([]TestTarget, 0)
+	for i := range targets {
+		if f(&targets[i]) {
+			filtered = append(filtered, targets[i])
+		}
+	}
+	return filtered
+}
+
+// A target is excluded, if its name contains the pattern or it has a tag equal to the pattern.
+// Wildcards at both ends of the pattern, e.g. `*_nightly`, are ignored.
+func is_excluded(t *TestTarget, excludes []string) bool {
+	for _, pattern := range excludes {
+		if t.HasTag(pattern) || strings.Contains(t.Name, strings.Trim(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func get_all_system_tests(cfg *QueryConfig) ([]TestTarget, error) {
+	query := fmt.Sprintf("tests(%s)", strings.Join(cfg.get_universe(), " + "))
+	if len(cfg.expression) > 0 {
+		query = fmt.Sprintf("tests(%s)", cfg.expression)
+	}
+	all_tests, err := run_cached_bazel_query(query, cfg)
+	if err != nil || len(cfg.excludes) == 0 {
+		return all_tests, err
+	}
+	return filter_targets(all_tests, func(t *TestTarget) bool {
+		return !is_excluded(t, cfg.excludes)
+	}), nil
+}
+
+func get_all_testnet_targets(cfg *QueryConfig) ([]TestTarget, error) {
+	all_tests, err := get_all_system_tests(cfg)
+	if err != nil {
+		return []TestTarget{}, err
+	}
+	return filter_targets(all_tests, func(t *TestTarget) bool {
+		return t.HasTag(DYNAMIC_TESTNET_TAG)
+	}), nil
+}
+
+func get_all_system_test_targets(cfg *QueryConfig) ([]string, error) {
+	all_tests, err := get_all_system_tests(cfg)
+	return target_names(all_tests), err
+}
+
+func get_all_testnets(cfg *QueryConfig) ([]string, error) {
+	all_testnets, err := get_all_testnet_targets(cfg)
+	return target_names(all_testnets), err
+}
+
+// Renders targets as an aligned table with their size, timeout, flakiness and tags.
+func print_targets_table(cmd *cobra.Command, targets []TestTarget) error {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tSIZE\tTIMEOUT\tFLAKY\tTAGS")
+	for _, t := range targets {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", t.Name, t.Size, t.Timeout, t.Flaky, strings.Join(t.Tags, ","))
+	}
+	return w.Flush()
+}
+
+// Estimates the runtime of the given targets as the sum of their bazel timeouts, i.e. the worst case.
+func estimate_total_runtime(all_tests []TestTarget, targets []string) time.Duration {
+	total := 0
+	for _, t := range all_tests {
+		if any_equals(targets, t.Name) {
+			if secs, ok := BAZEL_TEST_TIMEOUT_SECS[t.Timeout]; ok {
+				total += secs
+			} else {
+				total += BAZEL_TEST_TIMEOUT_SECS["moderate"]
+			}
+		}
+	}
+	return time.Duration(total) * time.Second
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var DEFAULT_TEST_ALL_JOBS = 4
+
+type TestAllConfig struct {
+	testCfg Config
+	jobs    int
+}
+
+func ValidateTestAllCommand(cfg *TestAllConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.jobs < 1 {
+			return fmt.Errorf("option --jobs should be >= 1.")
+		}
+		return ValidateTestCommand(&cfg.testCfg)(cmd, args)
+	}
+}
+
+func TestAllCommandWithConfig(cfg *TestAllConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		patterns, bazel_args := split_bazel_args(cmd, args)
+		all_tests, err := get_all_system_tests(&cfg.testCfg.queryCfg)
+		if err != nil {
+			return err
+		}
+		targets, err := find_targets_by_name_or_tag(all_tests, patterns, &cfg.testCfg.matchCfg)
+		if err != nil {
+			return err
+		}
+		runtime := estimate_total_runtime(all_tests, targets)
+		cmd.Printf("%sThe following %d targets match `%s` and will be run with up to %d of them at a time (estimated total runtime up to %s):\n%s%s\n", CYAN, len(targets), strings.Join(patterns, "`, `"), cfg.jobs, runtime, strings.Join(targets, "\n"), NC)
+		if !cfg.testCfg.isDryRun && !cfg.testCfg.assumeYes && !ask_confirmation(cmd, "Run all of them?") {
+			return nil
+		}
+		// Bazel runs the tests of a single invocation concurrently, the number of local test jobs caps the concurrency.
+		if !any_contains_substring(bazel_args, "--local_test_jobs") {
+			bazel_args = append([]string{fmt.Sprintf("--local_test_jobs=%d", cfg.jobs)}, bazel_args...)
+		}
+		started := time.Now()
+		err = run_system_tests(cmd, &cfg.testCfg, targets, bazel_args)
+		if !cfg.testCfg.isDryRun {
+			print_test_results_table(cmd, targets, started)
+		}
+		return err
+	}
+}
+
+// Each pattern matches targets having it as a tag, as well as targets whose names match it.
+func find_targets_by_name_or_tag(all_tests []TestTarget, patterns []string, cfg *MatchConfig) ([]string, error) {
+	targets := []string{}
+	for _, pattern := range patterns {
+		matches := target_names(filter_targets(all_tests, func(t *TestTarget) bool {
+			return t.HasTag(pattern)
+		}))
+		if by_name, err := find_all_matching_targets(target_names(all_tests), pattern, cfg); err == nil {
+			matches = append(matches, by_name...)
+		} else if len(matches) == 0 {
+			return []string{}, fmt.Errorf("No targets have tag `%s` or match it by name.", pattern)
+		}
+		for _, match := range matches {
+			if !any_equals(targets, match) {
+				targets = append(targets, match)
+			}
+		}
+	}
+	return targets, nil
+}
+
+func print_test_results_table(cmd *cobra.Command, targets []string, since time.Time) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TARGET\tRESULT\tDURATION")
+	passed := 0
+	for _, target := range targets {
+		result, err := get_test_result(target, since)
+		if err != nil {
+			fmt.Fprintf(w, "%s\t%s\t-\n", target, result.Status)
+			continue
+		}
+		if result.Status == "PASSED" {
+			passed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", target, result.Status, (time.Duration(result.DurationSecs) * time.Second).Round(time.Second))
+	}
+	w.Flush()
+	cmd.Printf("%s%d of %d targets passed.%s\n", CYAN, passed, len(targets), NC)
+}
+
+func NewTestAllCmd() *cobra.Command {
+	var cfg = TestAllConfig{}
+	var testAllCmd = &cobra.Command{
+		Use:     "test-all <substring|tag>... [flags] [-- <bazel_args>]",
+		Short:   "Run all system_test targets matching a substring or having a tag with Bazel",
+		Example: "  ict test-all nns_upgrade\n  ict test-all system_test_nightly --jobs=2 --yes\n  ict test-all upgrade --dry-run -- --test_output=errors",
+		Args:    ValidateTestAllCommand(&cfg),
+		RunE:    TestAllCommandWithConfig(&cfg),
+	}
+	add_test_flags(testAllCmd, &cfg.testCfg)
+	testAllCmd.Flags().IntVarP(&cfg.jobs, "jobs", "j", DEFAULT_TEST_ALL_JOBS, "Max number of targets, which are run at the same time.")
+	testAllCmd.SetOut(os.Stdout)
+	return testAllCmd
+}
@@ -1,86 +1,580 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
 var DEFAULT_TEST_KEEPALIVE_MINS = 60
 
+// Colocated variants of system tests, which run the test driver on a Farm VM, are named after the test with this suffix.
+var COLOCATE_TARGET_SUFFIX = "_colocate"
+
+// Seed of the random number generator of the test driver, unless set via RNG_SEED, see rs/tests/src/driver/test_env.rs
+var DEFAULT_RNG_SEED uint64 = 42
+
+// Log levels of the node software, see rs/config/src/logger.rs
+var REPLICA_LOG_LEVELS = []string{"critical", "error", "warning", "info", "debug", "trace"}
+
+// Bounds of the --timeout flag.
+var MIN_TEST_TIMEOUT = time.Minute
+var MAX_TEST_TIMEOUT = 12 * time.Hour
+
 type Config struct {
-	isFuzzyMatch bool
+	queryCfg    QueryConfig
+	matchCfg    MatchConfig
 	isDryRun    bool
 	keepAlive   bool
 	filterTests string
 	farmBaseUrl string
+	farmDc      string
+	isAffected  bool
+	baseRef     string
+	runAll      bool
+	assumeYes   bool
+	repeat      int
+	// Number of copies of the target, which are run at the same time.
+	parallel int
+	// Number of runs until the first failure, 0 means no limit and -1 disables the mode.
+	untilFailure int
+	retries      int
+	junitOut     string
+	resultJson   string
+	reuseEnvName string
+	reuseEnv     *keptAliveEnv
+	timeout      time.Duration
+	// GuestOS version to test, instead of the one built from HEAD.
+	icVersion      string
+	icVersionFlags []string
+	// Version, to which upgrade tests upgrade the IC.
+	toVersion string
+	// Variables in the KEY=VALUE form, which are set in the environment of the tests.
+	testEnv []string
+	// Run only a deterministic subset of the targets, e.g. on one of several CI workers.
+	shardValue string
+	shard      *testShard
+	colocate   bool
+	noColocate bool
+	// Build the GuestOS image under test with debug assertions.
+	debugReplica bool
+	// Execute the actions of the build remotely or locally, instead of locally with the remote cache.
+	remote bool
+	local  bool
+	// Log level of the deployed nodes, instead of the default info.
+	replicaLogLevel string
+	// Minutes of journald logs collected from the nodes of failed tests, 0 disables the collection.
+	crashLogMinutes int
+	// Directory, into which logs and outputs of the targets are copied, instead of ~/.ict/artifacts.
+	artifactsDir string
+	// File, into which Bazel writes the JSON trace profile of the invocation.
+	bazelProfile string
+	// Continue the last interrupted multi-target run with the targets, which haven't passed yet.
+	resume      bool
+	resumeState *resumeState
+	// Run the targets via `bazel coverage` and summarize the line coverage of the crates they exercise.
+	coverage     bool
+	coverageHtml string
+	// Seed of the random number generator of the test driver, e.g. to reproduce a failure.
+	seed uint64
+	// Name of the only test function to execute, e.g. the failing step of a scenario.
+	step      string
+	listSteps bool
+}
+
+func ValidateTestCommand(cfg *Config) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.retries < 0 {
+			return fmt.Errorf("option --retries should be >= 0.")
+		}
+		if len(cfg.reuseEnvName) > 0 {
+			env, err := find_kept_alive_env(cfg.reuseEnvName)
+			if err != nil {
+				return err
+			}
+			if env.Imported {
+				return fmt.Errorf("\nEnvironment `%s` was imported, it can only be reused on the machine that created it.", env.Group)
+			}
+			cfg.reuseEnv = env
+		}
+		if cmd.Flags().Changed("timeout") && (cfg.timeout < MIN_TEST_TIMEOUT || cfg.timeout > MAX_TEST_TIMEOUT) {
+			return fmt.Errorf("option --timeout should be between %s and %s.", MIN_TEST_TIMEOUT, MAX_TEST_TIMEOUT)
+		}
+		if cfg.repeat < 1 {
+			return fmt.Errorf("option --repeat should be >= 1.")
+		}
+		if cfg.parallel < 1 {
+			return fmt.Errorf("option --parallel should be >= 1.")
+		}
+		if len(cfg.shardValue) > 0 {
+			shard, err := parse_test_shard(cfg.shardValue)
+			if err != nil {
+				return err
+			}
+			cfg.shard = shard
+		}
+		if cfg.crashLogMinutes < 0 {
+			return fmt.Errorf("option --crash-log-minutes should be >= 0.")
+		}
+		if len(cfg.replicaLogLevel) > 0 && !any_equals(REPLICA_LOG_LEVELS, cfg.replicaLogLevel) {
+			return fmt.Errorf("option --replica-log-level should be one of: %s.", strings.Join(REPLICA_LOG_LEVELS, ", "))
+		}
+		for _, env := range cfg.testEnv {
+			if key, _, found := strings.Cut(env, "="); !found || len(key) == 0 {
+				return fmt.Errorf("option --env should be of the form KEY=VALUE, got `%s`.", env)
+			}
+		}
+		// Bazel resolves a relative profile path against the workspace, rather than the current directory.
+		if len(cfg.bazelProfile) > 0 {
+			profile, err := filepath.Abs(cfg.bazelProfile)
+			if err != nil {
+				return err
+			}
+			cfg.bazelProfile = profile
+		}
+		if len(cfg.coverageHtml) > 0 {
+			if !cfg.coverage {
+				return fmt.Errorf("option --coverage-html requires --coverage.")
+			}
+			html_dir, err := filepath.Abs(cfg.coverageHtml)
+			if err != nil {
+				return err
+			}
+			cfg.coverageHtml = html_dir
+		}
+		if cfg.resume {
+			state, err := load_resume_state()
+			if err != nil {
+				return err
+			}
+			cfg.resumeState = state
+		}
+		// With --affected or --resume targets are derived from git diff or the resume file, only Bazel args are accepted.
+		if cfg.isAffected || cfg.resume {
+			return positional_args(cobra.MaximumNArgs(0))(cmd, args)
+		}
+		return positional_args(cobra.MinimumNArgs(1))(cmd, args)
+	}
 }
 
 func TestCommandWithConfig(cfg *Config) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		target := args[0]
-		if all_targets, err := get_all_system_test_targets(); err != nil {
+		args, bazel_args := split_bazel_args(cmd, args)
+		if cfg.isAffected {
+			return run_affected_tests(cmd, cfg, bazel_args)
+		}
+		if cfg.resume {
+			return run_resumed_tests(cmd, cfg, bazel_args)
+		}
+		if cfg.runAll {
+			return run_all_matching_tests(cmd, cfg, args, bazel_args)
+		}
+		if cfg.listSteps && len(args) > 1 {
+			return fmt.Errorf("\nOption --list-steps lists the steps of a single target, but %d targets were given.", len(args))
+		}
+		all_targets, err := get_all_system_test_targets(&cfg.queryCfg)
+		if err != nil {
 			return err
-		} else {
-			if match_target, msg, err := find_matching_target(all_targets, target, cfg.isFuzzyMatch); err == nil {
-				if len(msg) > 0 {
-					cmd.Printf(CYAN + msg + NC)
-				}
-				target = match_target
-			} else {
+		}
+		// Each target is resolved separately, all of them are then run in a single Bazel invocation.
+		targets := make([]string, 0, len(args))
+		for _, arg := range args {
+			target, err := resolve_target(cmd, all_targets, arg, &cfg.matchCfg)
+			if err != nil {
 				return err
 			}
+			if !any_equals(targets, target) {
+				targets = append(targets, target)
+			}
 		}
-		command := []string{"bazel", "test", target, "--config=systest"}
-		// Append all bazel args following the --, i.e. "ict test target -- --verbose_explanations ..."
-		command = append(command, args[1:]...)
-		if !any_contains_substring(command, "--cache_test_results") {
-			command = append(command, "--cache_test_results=no")
+		if cfg.listSteps {
+			return print_test_steps(cmd, targets[0], bazel_args)
+		}
+		return run_system_tests(cmd, cfg, targets, bazel_args)
+	}
+}
+
+func run_all_matching_tests(cmd *cobra.Command, cfg *Config, patterns []string, bazel_args []string) error {
+	all_tests, err := get_all_system_tests(&cfg.queryCfg)
+	if err != nil {
+		return err
+	}
+	targets := []string{}
+	for _, pattern := range patterns {
+		matches, err := find_all_matching_targets(target_names(all_tests), pattern, &cfg.matchCfg)
+		if err != nil {
+			return err
 		}
-		if len(cfg.filterTests) > 0 {
-			command = append(command, "--test_arg=--include-tests="+cfg.filterTests)
+		for _, match := range matches {
+			if !any_equals(targets, match) {
+				targets = append(targets, match)
+			}
 		}
-		if len(cfg.farmBaseUrl) > 0 {
-			command = append(command, "--test_arg=--farm-base-url="+cfg.farmBaseUrl)
+	}
+	runtime := estimate_total_runtime(all_tests, targets)
+	cmd.Printf("%sThe following %d targets match `%s` (estimated total runtime up to %s):\n%s%s\n", CYAN, len(targets), strings.Join(patterns, "`, `"), runtime, strings.Join(targets, "\n"), NC)
+	if !cfg.isDryRun && !cfg.assumeYes && !ask_confirmation(cmd, "Run all of them?") {
+		return nil
+	}
+	return run_system_tests(cmd, cfg, targets, bazel_args)
+}
+
+// Without an executor --config=remote builds locally, so that --remote would silently do nothing.
+// The executor isn't part of the shared config, see .bazelrc, but is set in one of these files of the workspace or in ~/.bazelrc.
+var REMOTE_EXECUTOR_BAZELRC_FILES = []string{"user.bazelrc", ".bazelrc"}
+var REMOTE_EXECUTOR_REGEX = regexp.MustCompile(`(?m)^[ \t]*(build|test|common)(:remote)?[ \t].*--remote_executor[= ]+[^ \t\n#]`)
+
+func check_remote_executor(bazel_args []string) error {
+	if any_contains_substring(bazel_args, "--remote_executor=") {
+		return nil
+	}
+	files := []string{}
+	for _, file := range REMOTE_EXECUTOR_BAZELRC_FILES {
+		files = append(files, filepath.Join(get_workspace_root(), file))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		files = append(files, filepath.Join(home, ".bazelrc"))
+	}
+	for _, file := range files {
+		if content, err := os.ReadFile(file); err == nil && REMOTE_EXECUTOR_REGEX.Match(content) {
+			return nil
 		}
-		if cfg.keepAlive {
+	}
+	return fmt.Errorf("\nOption --remote requires a remote executor, which isn't part of the shared config, set it in %s, e.g. `build:remote --remote_executor=grpcs://<host>`.", files[0])
+}
+
+func get_bazel_test_command(cfg *Config, targets []string, bazel_args []string) []string {
+	bazel_command := "test"
+	if cfg.coverage {
+		bazel_command = "coverage"
+	}
+	command := append([]string{"bazel", bazel_command}, targets...)
+	command = append(command, "--config=systest")
+	if cfg.debugReplica {
+		command = append(command, "--config=debug_replica")
+	}
+	if cfg.remote {
+		command = append(command, "--config=remote")
+	}
+	if cfg.local {
+		command = append(command, "--config=local")
+	}
+	if !any_contains_substring(bazel_args, "--cache_test_results") {
+		command = append(command, "--cache_test_results=no")
+	}
+	if len(cfg.filterTests) > 0 {
+		command = append(command, "--test_arg=--include-tests="+cfg.filterTests)
+	}
+	if len(cfg.step) > 0 {
+		command = append(command, "--test_arg=--exact-test="+cfg.step)
+	}
+	if len(cfg.farmBaseUrl) > 0 {
+		command = append(command, "--test_arg=--farm-base-url="+cfg.farmBaseUrl)
+	}
+	// The test driver requires all VMs of the farm group to be placed in this datacenter.
+	if len(cfg.farmDc) > 0 {
+		command = append(command, "--test_env=FARM_DC="+cfg.farmDc)
+	}
+	// With --keepalive the timeout determines, how long the environment is kept alive.
+	if cfg.timeout > 0 {
+		command = append(command, fmt.Sprintf("--test_timeout=%d", int(cfg.timeout.Seconds())))
+	}
+	if cfg.keepAlive {
+		if cfg.timeout == 0 {
 			keepAlive := fmt.Sprintf("--test_timeout=%s", strconv.Itoa(DEFAULT_TEST_KEEPALIVE_MINS * 60))
 			command = append(command, keepAlive)
-			command = append(command, "--test_arg=--debug-keepalive")
 		}
-		// Print Bazel command for debugging puroposes.
-		cmd.Println(CYAN + "Raw Bazel command to be invoked: \n$ " + strings.Join(command, " ") + NC)
-		if cfg.isDryRun {
+		command = append(command, "--test_arg=--debug-keepalive")
+		// Farm group and VMs are printed from the streamed test output.
+		if !any_contains_substring(bazel_args, "--test_output") {
+			command = append(command, "--test_output=streamed")
+		}
+		// Setup directories of the test driver are kept, so that the environment can be reused via --reuse-env.
+		if !any_contains_substring(bazel_args, "--test_tmpdir") {
+			if test_tmpdir, err := get_kept_alive_test_tmpdir(); err == nil {
+				command = append(command, "--test_tmpdir="+test_tmpdir)
+			}
+		}
+	}
+	if cfg.reuseEnv != nil {
+		command = append(command, "--test_arg=--reuse-setup-dir="+cfg.reuseEnv.SetupDir)
+	}
+	if cfg.parallel > 1 {
+		command = append(command, fmt.Sprintf("--runs_per_test=%d", cfg.parallel))
+		if !any_contains_substring(bazel_args, "--local_test_jobs") {
+			command = append(command, fmt.Sprintf("--local_test_jobs=%d", cfg.parallel))
+		}
+	}
+	command = append(command, cfg.icVersionFlags...)
+	if len(cfg.replicaLogLevel) > 0 {
+		command = append(command, "--test_env=REPLICA_LOG_LEVEL="+cfg.replicaLogLevel)
+	}
+	if cfg.seed != DEFAULT_RNG_SEED {
+		command = append(command, fmt.Sprintf("--test_env=RNG_SEED=%d", cfg.seed))
+	}
+	// The test driver collects artifacts of the nodes into the undeclared test outputs, which are kept unzipped for browsing.
+	if cfg.crashLogMinutes > 0 {
+		command = append(command, fmt.Sprintf("--test_arg=--crash-artifacts-log-minutes=%d", cfg.crashLogMinutes))
+		if !any_contains_substring(bazel_args, "zip_undeclared_test_outputs") {
+			command = append(command, "--nozip_undeclared_test_outputs")
+		}
+	}
+	for _, env := range cfg.testEnv {
+		command = append(command, "--test_env="+env)
+	}
+	// Build events are the source of the timing breakdown printed after the run.
+	if !any_contains_substring(bazel_args, "--build_event_json_file") {
+		if build_events_file, err := get_build_events_file(); err == nil {
+			command = append(command, "--build_event_json_file="+build_events_file)
+		}
+	}
+	if len(cfg.bazelProfile) > 0 {
+		command = append(command, "--profile="+cfg.bazelProfile)
+	}
+	if cfg.coverage {
+		command = append(command, get_coverage_flags(bazel_args)...)
+	}
+	// Bazel flags of the config files come before the ones on the command line, so that the latter take precedence.
+	command = append(command, CONFIG_BAZEL_FLAGS...)
+	// Append all bazel args following the --, i.e. "ict test target -- --verbose_explanations ...".
+	// These come last, so that they take precedence over the flags set by ict.
+	return append(command, bazel_args...)
+}
+
+func run_system_tests(cmd *cobra.Command, cfg *Config, targets []string, bazel_args []string) error {
+	if cfg.remote {
+		if err := check_remote_executor(bazel_args); err != nil {
+			return err
+		}
+	}
+	if cfg.colocate || cfg.noColocate {
+		variants, err := get_colocation_variants(cfg, targets)
+		if err != nil {
+			return err
+		}
+		targets = variants
+	}
+	if cfg.parallel > 1 && len(targets) > 1 {
+		return fmt.Errorf("\nOption --parallel runs copies of a single target, but %d targets were given.", len(targets))
+	}
+	if cfg.shard != nil {
+		all_count := len(targets)
+		targets = cfg.shard.select_targets(targets)
+		if len(targets) == 0 {
+			cmd.Printf("%sShard %d/%d has none of the %d targets to run.%s\n", CYAN, cfg.shard.index, cfg.shard.count, all_count, NC)
 			return nil
-		} else {
-			// Start Bazel test Command with stdout, stderr streaming.
-			testCmd := exec.Command(command[0], command[1:]...)
-			testCmd.Stdout = os.Stdout
-			testCmd.Stderr = os.Stderr
-			return testCmd.Run()
 		}
+		cmd.Printf("%sShard %d/%d runs %d of the %d targets:\n%s%s\n", CYAN, cfg.shard.index, cfg.shard.count, len(targets), all_count, strings.Join(targets, "\n"), NC)
+	}
+	if len(cfg.step) > 0 && cfg.reuseEnv == nil {
+		cmd.Printf("%sWithout --reuse-env the setup runs before step `%s`.%s\n", CYAN, cfg.step, NC)
+	}
+	if cfg.reuseEnv != nil {
+		if len(targets) > 1 {
+			return fmt.Errorf("\nOnly a single target can reuse the environment `%s`.", cfg.reuseEnv.Group)
+		}
+		if len(cfg.reuseEnv.Target) > 0 && cfg.reuseEnv.Target != targets[0] {
+			cmd.PrintErrf("%sEnvironment `%s` was set up by target `%s`, its setup might not fit `%s`.%s\n", RED, cfg.reuseEnv.Group, cfg.reuseEnv.Target, targets[0], NC)
+		}
+	}
+	if flags, err := resolve_ic_version_flags(cmd, cfg.icVersion); err != nil {
+		return err
+	} else {
+		cfg.icVersionFlags = flags
+	}
+	if len(cfg.toVersion) > 0 {
+		to_version, err := resolve_ic_version(cfg.toVersion)
+		if err != nil {
+			return err
+		}
+		if to_version != cfg.toVersion {
+			cmd.Printf("%sIC version `%s` was resolved to %s%s\n", CYAN, cfg.toVersion, to_version, NC)
+		}
+		// Read by rs/tests/src/orchestrator/upgrade_downgrade.rs and downgrade_with_ecdsa.rs instead of the mainnet version.
+		// Unlike TARGET_VERSION, it keeps them blessing the branch version from the locally built image.
+		cfg.icVersionFlags = append(cfg.icVersionFlags, "--test_env=UPGRADE_TARGET_VERSION="+to_version)
+	}
+	command := get_bazel_test_command(cfg, targets, bazel_args)
+	print_bazel_command(cmd, command)
+	cmd.Printf("%sTests use the RNG seed %d, rerun them with --seed=%d to reproduce a failure.%s\n", CYAN, cfg.seed, cfg.seed, NC)
+	if cfg.isDryRun {
+		return nil
+	} else {
+		record_recent_targets(targets)
+		started := time.Now()
+		err := cleanup_interrupted_run(cmd, cfg, run_bazel_tests(cmd, cfg, command, targets, bazel_args))
+		update_resume_state(cmd, cfg, targets, bazel_args, started, err)
+		if err != nil && cfg.crashLogMinutes > 0 {
+			print_crash_artifacts(cmd, targets, started)
+		}
+		save_artifacts(cmd, targets, cfg.artifactsDir, started)
+		if build_events_file, err := get_build_events_file(); err == nil {
+			print_run_timing(cmd, build_events_file, started)
+		}
+		if len(cfg.bazelProfile) > 0 {
+			print_profile_summary(cmd, cfg.bazelProfile)
+		}
+		if cfg.coverage && err == nil {
+			err = print_coverage_summary(cmd, cfg.coverageHtml)
+		}
+		if len(cfg.junitOut) > 0 {
+			if junit_err := write_junit_report(targets, cfg.junitOut, started); junit_err != nil {
+				cmd.PrintErrf("%sFailed to write JUnit report to %s: %s%s\n", RED, cfg.junitOut, junit_err, NC)
+			} else {
+				cmd.Printf("%sJUnit report was written to %s%s\n", CYAN, cfg.junitOut, NC)
+			}
+		}
+		if len(cfg.resultJson) > 0 {
+			if json_err := write_result_json(targets, cfg.resultJson, started); json_err != nil {
+				cmd.PrintErrf("%sFailed to write JSON summary to %s: %s%s\n", RED, cfg.resultJson, json_err, NC)
+			} else {
+				cmd.Printf("%sJSON summary was written to %s%s\n", CYAN, cfg.resultJson, NC)
+			}
+		}
+		print_result_line(targets, started, err)
+		return err
+	}
+}
+
+// Replaces the targets with their colocated or non-colocated variants, targets already being the requested variant are kept.
+func get_colocation_variants(cfg *Config, targets []string) ([]string, error) {
+	all_targets, err := get_all_system_test_targets(&cfg.queryCfg)
+	if err != nil {
+		return []string{}, err
+	}
+	variants := []string{}
+	for _, target := range targets {
+		variant := strings.TrimSuffix(target, COLOCATE_TARGET_SUFFIX)
+		if cfg.colocate {
+			variant += COLOCATE_TARGET_SUFFIX
+		}
+		if !any_equals(all_targets, variant) {
+			if cfg.colocate {
+				return []string{}, fmt.Errorf("\nTarget `%s` has no colocated variant `%s`.", target, variant)
+			}
+			return []string{}, fmt.Errorf("\nTarget `%s` has no non-colocated variant `%s`.", target, variant)
+		}
+		if !any_equals(variants, variant) {
+			variants = append(variants, variant)
+		}
+	}
+	return variants, nil
+}
+
+func run_bazel_tests(cmd *cobra.Command, cfg *Config, command []string, targets []string, bazel_args []string) error {
+	if cfg.parallel > 1 {
+		return run_parallel_tests(cmd, command, targets[0], cfg.parallel)
 	}
+	if cfg.untilFailure >= 0 {
+		return run_repeated_tests(cmd, command, targets, cfg.untilFailure, true)
+	}
+	if cfg.repeat > 1 {
+		return run_repeated_tests(cmd, command, targets, cfg.repeat, false)
+	}
+	if cfg.retries > 0 {
+		return run_tests_with_retries(cmd, targets, cfg.retries, func(targets []string) []string {
+			return get_bazel_test_command(cfg, targets, bazel_args)
+		})
+	}
+	if cfg.keepAlive {
+		return run_bazel_command_with_keepalive(cmd, command, targets, false)
+	}
+	return run_bazel_command(command)
 }
 
 func NewTestCmd() *cobra.Command {
 	var cfg = Config{}
 	var testCmd = &cobra.Command{
-		Use:     "test <system_test_target> [flags] [-- <bazel_args>]",
+		Use:     "test <system_test_target>... [flags] [-- <bazel_args>]",
 		Aliases: []string{"system_test", "t"},
 		Short:   "Run system_test target with Bazel",
-		Example: "  ict test //rs/tests/testing_verification:basic_health_test\n  ict test basic_health_test --dry-run -- --test_tmpdir=./tmp --test_output=errors",
-		Args:    cobra.MinimumNArgs(1),
+		Example: "  ict test //rs/tests/testing_verification:basic_health_test\n  ict test basic_health_test --dry-run -- --test_tmpdir=./tmp --test_output=errors\n  ict test --affected --base=origin/master\n  ict test basic_health_test nns_upgrade_test\n  ict test nns --all\n  ict test --resume\n  ict test upgrade_downgrade_app_subnet_test --from-version=latest-mainnet --to-version=<git_revision>",
+		Args:    ValidateTestCommand(&cfg),
 		RunE:    TestCommandWithConfig(&cfg),
 	}
-	testCmd.Flags().BoolVarP(&cfg.isFuzzyMatch, "fuzzy", "", false, "Use fuzzy matching to find similar target names. Default: substring match.")
+	add_test_flags(testCmd, &cfg)
+	testCmd.Flags().BoolVarP(&cfg.isAffected, "affected", "", false, "Run only system tests affected by local changes (see git diff against --base).")
+	testCmd.Flags().BoolVarP(&cfg.runAll, "all", "", false, "Run all targets matching the given ones in a single Bazel invocation.")
+	testCmd.Flags().BoolVarP(&cfg.resume, "resume", "", false, "Continue the last interrupted run of multiple targets, without re-running the targets which have passed.")
+	testCmd.MarkFlagsMutuallyExclusive("resume", "affected")
+	testCmd.MarkFlagsMutuallyExclusive("resume", "all")
+	testCmd.MarkFlagsMutuallyExclusive("resume", "shard")
+	testCmd.Flags().BoolVarP(&cfg.listSteps, "list-steps", "", false, "List the setup and test functions of the target, which can be run one by one via --step.")
+	testCmd.MarkFlagsMutuallyExclusive("list-steps", "all")
+	testCmd.Flags().StringVarP(&cfg.baseRef, "base", "", DEFAULT_AFFECTED_BASE_REF, "Git ref, against which local changes are computed for --affected.")
+	testCmd.SetOut(os.Stdout)
+	return testCmd
+}
+
+func add_test_flags(testCmd *cobra.Command, cfg *Config) {
+	add_match_flags(testCmd, &cfg.matchCfg)
 	testCmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print raw Bazel command to be invoked without execution.")
-	testCmd.Flags().BoolVarP(&cfg.keepAlive, "keepalive", "k", false, fmt.Sprintf("Keep test system alive for %d minutes.", DEFAULT_TEST_KEEPALIVE_MINS))
+	testCmd.Flags().BoolVarP(&cfg.keepAlive, "keepalive", "k", false, fmt.Sprintf("Keep test system alive for %d minutes and print its farm group, node IPs and expiry time.", DEFAULT_TEST_KEEPALIVE_MINS))
+	testCmd.Flags().IntVarP(&cfg.repeat, "repeat", "", 1, "Run the targets this many times sequentially and print a summary of their flakiness.")
+	testCmd.Flags().IntVarP(&cfg.untilFailure, "until-failure", "", -1, "Run the targets sequentially until one of them fails, at most the given number of times if set.")
+	testCmd.Flags().Lookup("until-failure").NoOptDefVal = "0"
+	testCmd.Flags().IntVarP(&cfg.retries, "retries", "", 0, "Re-run failed targets up to this many times, if their failures look infrastructure-related.")
+	testCmd.Flags().IntVarP(&cfg.parallel, "parallel", "", 1, "Run this many copies of the target at the same time, each with its own farm group.")
+	testCmd.MarkFlagsMutuallyExclusive("repeat", "until-failure", "retries", "parallel")
+	testCmd.Flags().StringVarP(&cfg.junitOut, "junit-out", "", "", "Write a single JUnit XML report with results of all targets (of their last run) to this file.")
+	testCmd.Flags().StringVarP(&cfg.resultJson, "result-json", "", "", "Write a JSON summary with status, duration, logs and dashboards of each target (of their last run) to this file.")
+	testCmd.Flags().StringVarP(&cfg.artifactsDir, "artifacts-dir", "", "", "Copy logs and (undeclared) outputs of the targets to this directory, instead of ~/.ict/artifacts/<target>/<timestamp>.")
+	testCmd.Flags().StringVarP(&cfg.bazelProfile, "bazel-profile", "", "", "Write Bazel's JSON trace profile to this file (for chrome://tracing) and print the longest actions and the critical path (--profile selects a profile of the config instead).")
+	testCmd.Flags().BoolVarP(&cfg.coverage, "coverage", "", false, "Run the targets via `bazel coverage` and print the line coverage of the crates exercised by the test driver.")
+	testCmd.Flags().StringVarP(&cfg.coverageHtml, "coverage-html", "", "", "With --coverage, also render the coverage report as HTML into this directory (requires genhtml).")
+	testCmd.Flags().StringVarP(&cfg.reuseEnvName, "reuse-env", "", "", "Skip the setup and run tests against the farm group of an environment kept alive via --keepalive.")
+	testCmd.MarkFlagsMutuallyExclusive("parallel", "keepalive")
+	testCmd.MarkFlagsMutuallyExclusive("parallel", "reuse-env")
+	testCmd.Flags().DurationVarP(&cfg.timeout, "timeout", "", 0, fmt.Sprintf("Timeout of each test, e.g. 90m, passed to Bazel as --test_timeout (between %s and %s).", MIN_TEST_TIMEOUT, MAX_TEST_TIMEOUT))
+	testCmd.Flags().BoolVarP(&cfg.assumeYes, "yes", "y", false, "Don't ask for confirmation before running multiple targets.")
+	testCmd.Flags().StringVarP(&cfg.icVersion, "ic-version", "", "", fmt.Sprintf("Run against the GuestOS image of this git revision or `%s`, instead of the one built from HEAD.", LATEST_MAINNET_VERSION_ALIAS))
+	testCmd.Flags().StringVarP(&cfg.icVersion, "from-version", "", "", "Initial version of upgrade tests, same as --ic-version.")
+	testCmd.Flags().StringVarP(&cfg.toVersion, "to-version", "", "", fmt.Sprintf("Version (git revision or `%s`) built by CI, to which the upgrade_downgrade_*_subnet_test and downgrade_app_subnet_with_ecdsa_test downgrade the IC and back, instead of the mainnet version.", LATEST_MAINNET_VERSION_ALIAS))
+	testCmd.MarkFlagsMutuallyExclusive("ic-version", "from-version")
+	testCmd.Flags().BoolVarP(&cfg.debugReplica, "debug-replica", "", false, "Build the GuestOS image with debug assertions in the replica, which release builds disable (see --config=debug_replica).")
+	testCmd.MarkFlagsMutuallyExclusive("debug-replica", "ic-version")
+	testCmd.MarkFlagsMutuallyExclusive("debug-replica", "from-version")
+	testCmd.Flags().BoolVarP(&cfg.remote, "remote", "", false, "Execute the build remotely (see --config=remote), the executor has to be set in user.bazelrc.")
+	testCmd.Flags().BoolVarP(&cfg.local, "local", "", false, "Build without the remote cache and the build event service (see --config=local), e.g. without access to the buildfarm.")
+	testCmd.MarkFlagsMutuallyExclusive("remote", "local")
+	testCmd.Flags().IntVarP(&cfg.crashLogMinutes, "crash-log-minutes", "", DEFAULT_CRASH_LOG_MINUTES, "If tests fail, collect core dumps, panics and this many last minutes of journald logs from their nodes (0 disables it).")
+	testCmd.Flags().StringVarP(&cfg.replicaLogLevel, "replica-log-level", "", "", fmt.Sprintf("Log level of the deployed nodes, one of: %s (default info).", strings.Join(REPLICA_LOG_LEVELS, ", ")))
+	testCmd.Flags().BoolVarP(&cfg.colocate, "colocate", "", false, fmt.Sprintf("Run the colocated variants (with suffix `%s`) of the targets, i.e. with the test driver on a Farm VM.", COLOCATE_TARGET_SUFFIX))
+	testCmd.Flags().BoolVarP(&cfg.noColocate, "no-colocate", "", false, "Run the non-colocated variants of the targets, i.e. with the test driver on the local machine.")
+	testCmd.MarkFlagsMutuallyExclusive("colocate", "no-colocate")
+	testCmd.Flags().StringVarP(&cfg.shardValue, "shard", "", "", "Run only the <index>/<count>-th deterministic share of the targets, e.g. 2/4 on the second of four CI workers.")
+	testCmd.Flags().Uint64VarP(&cfg.seed, "seed", "", DEFAULT_RNG_SEED, "Seed of the random number generator of the test driver, which is passed to the tests as RNG_SEED.")
+	testCmd.Flags().StringArrayVarP(&cfg.testEnv, "env", "e", []string{}, "Set an environment variable of the tests, e.g. --env FEATURE_FLAG=1 (can be repeated).")
+	add_query_flags(testCmd, &cfg.queryCfg)
 	testCmd.PersistentFlags().StringVarP(&cfg.filterTests, "include-tests", "i", "", "Execute only those test functions which contain a substring.")
+	testCmd.Flags().StringVarP(&cfg.step, "step", "", "", "Execute only the test function with exactly this name (see --list-steps), e.g. against an environment reused via --reuse-env.")
+	testCmd.MarkFlagsMutuallyExclusive("step", "include-tests")
 	testCmd.PersistentFlags().StringVarP(&cfg.farmBaseUrl, "farm-url", "", "", "Use a custom url for the Farm webservice.")
-	testCmd.SetOut(os.Stdout)
-	return testCmd
+	testCmd.PersistentFlags().StringVarP(&cfg.farmDc, "farm-dc", "", "", "Place all VMs of the farm group in this datacenter, e.g. to reproduce datacenter-specific failures. Datacenters can't be excluded, as Farm can only require one.")
+	testCmd.PersistentFlags().Var(&unsupportedFlag{reason: "Farm can only require a datacenter, not exclude one, pin one via --farm-dc instead"}, "exclude-dc", "")
+	testCmd.PersistentFlags().MarkHidden("exclude-dc")
+}
+
+// Value of a flag, which users might expect, failing with the reason why it isn't supported rather than as an unknown flag.
+type unsupportedFlag struct {
+	reason string
+}
+
+func (f *unsupportedFlag) String() string {
+	return ""
+}
+
+func (f *unsupportedFlag) Set(value string) error {
+	return errors.New(f.reason)
+}
+
+func (f *unsupportedFlag) Type() string {
+	return "string"
 }
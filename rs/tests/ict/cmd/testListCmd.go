@@ -1,29 +1,69 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"regexp"
 	"strings"
 
 	"github.com/spf13/cobra"
 )
 
-func TestListCommand(cmd *cobra.Command, args []string) error {
-	if targets, err := get_all_system_test_targets(); err == nil {
-		cmd.Printf("%sThe following %d system_test targets were found:\n%s%s\n", CYAN, len(targets), strings.Join(targets, "\n"), NC)
-		return nil
-	} else {
-		return err
+type ListConfig struct {
+	queryCfg QueryConfig
+	tags     []string
+	regex    string
+	isJson   bool
+	isLong   bool
+}
+
+func TestListCommand(cfg *ListConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if all_tests, err := get_all_system_tests(&cfg.queryCfg); err == nil {
+			if len(cfg.tags) > 0 {
+				all_tests = filter_targets(all_tests, func(t *TestTarget) bool {
+					return any_has_tag(t, cfg.tags)
+				})
+			}
+			if len(cfg.regex) > 0 {
+				re, err := regexp.Compile(cfg.regex)
+				if err != nil {
+					return fmt.Errorf("Invalid regular expression `%s`: %s", cfg.regex, err)
+				}
+				all_tests = filter_targets(all_tests, func(t *TestTarget) bool {
+					return re.MatchString(t.Name)
+				})
+			}
+			if cfg.isJson {
+				return print_json(cmd, all_tests)
+			}
+			if cfg.isLong {
+				return print_targets_table(cmd, all_tests)
+			}
+			targets := target_names(all_tests)
+			cmd.Printf("%sThe following %d system_test targets were found:\n%s%s\n", CYAN, len(targets), strings.Join(targets, "\n"), NC)
+			return nil
+		} else {
+			return err
+		}
 	}
 }
 
 func NewTestListCmd() *cobra.Command {
+	var cfg = ListConfig{}
 	var cmd = &cobra.Command{
 		Use:     "list",
 		Short:   "List all system_test targets with Bazel",
-		Example: "ict test list",
+		Example: "ict test list\nict test list --tags=system_test_nightly,experimental\nict test list --json\nict test list --regex='^//rs/tests/nns:.*upgrade'",
 		Args:    cobra.ExactArgs(0),
-		RunE:    TestListCommand,
+		RunE:    TestListCommand(&cfg),
 	}
+	cmd.Flags().StringSliceVarP(&cfg.tags, "tags", "", []string{}, "List only targets having at least one of the given (comma-separated) tags.")
+	cmd.Flags().StringVarP(&cfg.regex, "regex", "", "", "List only targets matching a Go regular expression.")
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print targets with their attributes as JSON.")
+	cmd.Flags().BoolVarP(&cfg.isLong, "long", "l", false, "Print size, timeout, flakiness and tags of each target as a table.")
+	cmd.MarkFlagsMutuallyExclusive("json", "long")
+	add_query_flags(cmd, &cfg.queryCfg)
 	cmd.SetOut(os.Stdout)
 	return cmd
 }
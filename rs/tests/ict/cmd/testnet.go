@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Same as SSH_USERNAME and SSH_AUTHORIZED_PRIV_KEYS_DIR of the test driver, see rs/tests/src/driver/constants.rs
+var TESTNET_SSH_USERNAME = "admin"
+var TESTNET_SSH_PRIV_KEYS_DIR = "ssh/authorized_priv_keys"
+
+// The test driver stores each boundary node in <setup_dir>/boundary_node_vms/<name>, see rs/tests/src/driver/boundary_node.rs
+var BOUNDARY_NODE_VMS_DIR = "boundary_node_vms"
+
+type boundaryNode struct {
+	Name     string `json:"name"`
+	Ipv6     string `json:"ipv6"`
+	Hostname string `json:"hostname"`
+	// Domain of the playnet, only set for boundary nodes with real certificates and DNS records.
+	Domain string `json:"domain,omitempty"`
+	Url    string `json:"url"`
+}
+
+// Testnet running on Farm, as seen by the Farm-backed testnet subcommands.
+type runningTestnet struct {
+	Group     string        `json:"group"`
+	Test      string        `json:"test"`
+	ExpiresAt time.Time     `json:"expires_at"`
+	Vms       []keptAliveVm `json:"vms"`
+	// Setup directory of the test driver, only known for testnets kept alive by ict on this machine.
+	SetupDir      string `json:"setup_dir,omitempty"`
+	PrometheusUrl string `json:"prometheus_url,omitempty"`
+	GrafanaUrl    string `json:"grafana_url,omitempty"`
+}
+
+// Farm knows the expiry and the VMs of any group, while the subnets of the nodes and the setup directory
+// are only known for groups kept alive by ict on this machine, which are preferred.
+func find_testnet(cfg *FarmConfig, group string) (*runningTestnet, error) {
+	var found *runningTestnet
+	groups, farm_err := list_farm_groups(cfg.get_base_url())
+	for _, g := range groups {
+		if g.Name != group {
+			continue
+		}
+		found = &runningTestnet{Group: g.Name, Test: g.Spec.Metadata.TestName, ExpiresAt: g.ExpiresAt, Vms: []keptAliveVm{}}
+		for _, vm := range g.Vms {
+			found.Vms = append(found.Vms, keptAliveVm{Name: vm.Name, Host: vm.Hostname, Ipv6: vm.Ipv6})
+		}
+	}
+	for _, env := range load_kept_alive_envs() {
+		if env.Group != group {
+			continue
+		}
+		if found == nil {
+			found = &runningTestnet{Group: env.Group, Test: get_label_name(env.Target), ExpiresAt: env.ExpiresAt}
+		}
+		if len(env.Vms) > 0 {
+			found.Vms = env.Vms
+		}
+		found.SetupDir = env.SetupDir
+		found.PrometheusUrl, found.GrafanaUrl = env.PrometheusUrl, env.GrafanaUrl
+	}
+	if found != nil {
+		return found, nil
+	}
+	if farm_err != nil {
+		return nil, fmt.Errorf("\nFailed to look up Farm group %s: %s", group, farm_err)
+	}
+	return nil, fmt.Errorf("\nFarm group %s wasn't found, list the running ones via:\n$ ict testnet list --running", group)
+}
+
+// Nodes are selected by their node id (or VM name), a unique prefix of it, or their index in the list of VMs.
+// Without a selection, the first node is used.
+func find_testnet_vm(testnet *runningTestnet, node string) (*keptAliveVm, error) {
+	if len(testnet.Vms) == 0 {
+		return nil, fmt.Errorf("\nNo VMs of Farm group %s are known.", testnet.Group)
+	}
+	if len(node) == 0 {
+		return &testnet.Vms[0], nil
+	}
+	if idx, err := strconv.Atoi(node); err == nil {
+		if idx < 0 || idx >= len(testnet.Vms) {
+			return nil, fmt.Errorf("\nNode index %d is out of range, Farm group %s has %d VMs.", idx, testnet.Group, len(testnet.Vms))
+		}
+		return &testnet.Vms[idx], nil
+	}
+	matches := []int{}
+	for i, vm := range testnet.Vms {
+		if vm.Name == node {
+			return &testnet.Vms[i], nil
+		}
+		if strings.HasPrefix(vm.Name, node) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 1 {
+		return &testnet.Vms[matches[0]], nil
+	}
+	names := []string{}
+	for i, vm := range testnet.Vms {
+		names = append(names, fmt.Sprintf("%d: %s", i, vm.Name))
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("\nNode `%s` wasn't found, VMs of Farm group %s are:\n%s", node, testnet.Group, strings.Join(names, "\n"))
+	}
+	return nil, fmt.Errorf("\nNode `%s` is ambiguous, VMs of Farm group %s are:\n%s", node, testnet.Group, strings.Join(names, "\n"))
+}
+
+// The test driver authorizes its own key on all VMs, it is only known for testnets kept alive by ict on this machine.
+func get_testnet_ssh_key(testnet *runningTestnet) (string, bool) {
+	if len(testnet.SetupDir) == 0 {
+		return "", false
+	}
+	key := filepath.Join(testnet.SetupDir, TESTNET_SSH_PRIV_KEYS_DIR, TESTNET_SSH_USERNAME)
+	if _, err := os.Stat(key); err != nil {
+		return "", false
+	}
+	return key, true
+}
+
+// Options shared by ssh and scp, VMs are short-lived and their IPs get reused, so their host keys aren't remembered.
+func get_testnet_ssh_options(testnet *runningTestnet) []string {
+	options := []string{"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "-o", "LogLevel=ERROR"}
+	if key, ok := get_testnet_ssh_key(testnet); ok {
+		options = append(options, "-i", key, "-o", "IdentitiesOnly=yes")
+	}
+	return options
+}
+
+func get_replica_url(vm keptAliveVm) string {
+	return fmt.Sprintf("http://[%s]:%d", vm.Ipv6, REPLICA_PUBLIC_API_PORT)
+}
+
+// URL of the first node of the NNS subnet, the one the test driver and dfx talk to.
+func get_nns_url(testnet *runningTestnet) (string, bool) {
+	for _, vm := range testnet.Vms {
+		if vm.IsNns {
+			return get_replica_url(vm), true
+		}
+	}
+	return "", false
+}
+
+func get_boundary_nodes(testnet *runningTestnet) []boundaryNode {
+	nodes := []boundaryNode{}
+	if len(testnet.SetupDir) == 0 {
+		return nodes
+	}
+	entries, err := os.ReadDir(filepath.Join(testnet.SetupDir, BOUNDARY_NODE_VMS_DIR))
+	if err != nil {
+		return nodes
+	}
+	for _, entry := range entries {
+		dir := filepath.Join(testnet.SetupDir, BOUNDARY_NODE_VMS_DIR, entry.Name())
+		node := boundaryNode{Name: entry.Name()}
+		if content, err := os.ReadFile(filepath.Join(dir, "vm.json")); err == nil {
+			json.Unmarshal(content, &node)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, "playnet.json")); err == nil {
+			json.Unmarshal(content, &node.Domain)
+		}
+		node.Url = fmt.Sprintf("https://[%s]", node.Ipv6)
+		if len(node.Domain) > 0 {
+			node.Url = "https://" + node.Domain
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
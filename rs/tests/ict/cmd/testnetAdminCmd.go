@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetAdminConfig struct {
+	farmCfg  FarmConfig
+	isDryRun bool
+}
+
+// Proposals are submitted by the test neuron, unless another proposer is given, see derive_common_proposal_fields of rs/registry/admin-derive.
+func get_testnet_admin_command(nns_url string, args []string) []string {
+	command := append(get_ic_admin_command(nns_url), args...)
+	if any_contains_substring(args, "proposer") {
+		return command
+	}
+	for i, arg := range command {
+		if strings.HasPrefix(arg, "propose-to-") {
+			return append(append(command[:i+1:i+1], "--test-neuron-proposer"), command[i+1:]...)
+		}
+	}
+	return command
+}
+
+func ValidateTestnetAdminCommand(cmd *cobra.Command, args []string) error {
+	positional, admin_args := split_bazel_args(cmd, args)
+	if err := cobra.ExactArgs(1)(cmd, positional); err != nil {
+		return err
+	}
+	if len(admin_args) == 0 {
+		return fmt.Errorf("pass the ic-admin args after --, e.g. -- get-subnet 0.")
+	}
+	return nil
+}
+
+func TestnetAdminCommand(cfg *TestnetAdminConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, admin_args := split_bazel_args(cmd, args)
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		nns_url, ok := get_nns_url(testnet)
+		if !ok {
+			return fmt.Errorf("\nThe NNS of %s is only known for testnets kept alive by ict on this machine.", testnet.Group)
+		}
+		command := get_testnet_admin_command(nns_url, admin_args)
+		cmd.Printf("%s$ %s%s\n", CYAN, shell_quote(command), NC)
+		if cfg.isDryRun {
+			return nil
+		}
+		adminCmd := exec.Command(command[0], command[1:]...)
+		adminCmd.Stdin = os.Stdin
+		adminCmd.Stdout = os.Stdout
+		adminCmd.Stderr = os.Stderr
+		traced := trace_command(command)
+		err = adminCmd.Run()
+		traced(err)
+		if err != nil {
+			return fmt.Errorf("\nic-admin failed: %s", err)
+		}
+		return nil
+	}
+}
+
+func NewTestnetAdminCmd() *cobra.Command {
+	var cfg = TestnetAdminConfig{}
+	var cmd = &cobra.Command{
+		Use:     "admin <group> -- <ic-admin args>",
+		Short:   "Run ic-admin against the NNS of a testnet kept alive by ict, proposals are submitted by the test neuron",
+		Example: "ict testnet admin small--1690000000000 -- get-subnet 0\nict testnet admin small--1690000000000 -- propose-to-update-subnet --subnet 1 --max-ingress-bytes-per-message 4194304\nict testnet admin small--1690000000000 --dry-run -- get-topology",
+		Args:    ValidateTestnetAdminCommand,
+		RunE:    TestnetAdminCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ic-admin command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
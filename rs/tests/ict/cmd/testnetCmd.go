@@ -3,9 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
-	"strings"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
@@ -14,13 +13,24 @@ var DEFAULT_TESTNET_LIFETIME_MINS = 60
 var MAX_TESTNET_LIFETIME_MINS = 180
 
 type TestnetConfig struct {
+	queryCfg    QueryConfig
 	lifetime int
-	isFuzzyMatch bool
+	matchCfg    MatchConfig
 	isDryRun    bool
+	icVersion   string
+	openDashboard bool
+	fromConfig  string
+	boundaryNodes int
+	withNns     bool
+	withIi      bool
+	prefund     []string
 }
 
 func ValidateTestnetCommand(cfg *TestnetConfig) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
+		if err := prepare_command(cmd, args); err != nil {
+			return err
+		}
 		if cfg.lifetime > MAX_TESTNET_LIFETIME_MINS{
 			return fmt.Errorf("option --lifetime should be <= %d mins.",  MAX_TESTNET_LIFETIME_MINS)
 		}
@@ -28,40 +38,110 @@ func ValidateTestnetCommand(cfg *TestnetConfig) func(cmd *cobra.Command, args []
 	}
 }
 
+// Flags which change the shape of the testnet, they require the testnet to be set up by the generic target.
+var TESTNET_SPEC_FLAGS = []string{"from-config", "with-boundary-nodes", "with-nns", "with-ii", "prefund"}
+
+func has_testnet_spec_flags(cmd *cobra.Command) bool {
+	for _, flag := range TESTNET_SPEC_FLAGS {
+		if cmd.Flags().Changed(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Checked as part of Args rather than ValidateTestnetCommand, since the subcommands of `ict testnet` inherit its PersistentPreRunE.
+func testnet_name_or_config(cfg *TestnetConfig) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if has_testnet_spec_flags(cmd) && len(args) > 0 {
+			return fmt.Errorf("<testnet_name> can't be combined with --%s, as the shape of testnet targets is fixed.", strings.Join(TESTNET_SPEC_FLAGS, " or --"))
+		}
+		if !has_testnet_spec_flags(cmd) && len(args) == 0 {
+			return fmt.Errorf("either <testnet_name> or --from-config is required.")
+		}
+		if cfg.boundaryNodes < 0 {
+			return fmt.Errorf("option --with-boundary-nodes should be >= 0.")
+		}
+		return nil
+	}
+}
+
 func TestnetCommand(cfg *TestnetConfig) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		target := args[0]
-		if all_targets, err := get_all_testnets(); err != nil {
+		args, bazel_args := split_bazel_args(cmd, args)
+		target, spec_flags, err := resolve_testnet_target(cmd, cfg, args)
+		if err != nil {
+			return err
+		}
+		ic_version_flags, err := resolve_ic_version_flags(cmd, cfg.icVersion)
+		if err != nil {
 			return err
-		} else {
-			if match_target, msg, err := find_matching_target(all_targets, target, cfg.isFuzzyMatch); err == nil {
-				if len(msg) > 0 {
-					cmd.Printf(CYAN + msg + NC)
-				}
-				target = match_target
-			} else {
-				return err
-			}
 		}
 		command := []string{"bazel", "test", target, "--config=systest"}
-		// Append all bazel args following the --, i.e. "ict test target -- --verbose_explanations ..."
-		command = append(command, args[1:]...)
 		command = append(command, "--cache_test_results=no")
 		lifetime := fmt.Sprintf("--test_timeout=%s", strconv.Itoa(cfg.lifetime * 60))
 		command = append(command, lifetime)
 		command = append(command, "--test_arg=--debug-keepalive")
-		// Print Bazel command for debugging puroposes.
-		cmd.Println(CYAN + "Raw Bazel command to be invoked: \n$ " + strings.Join(command, " ") + NC)
+		if !any_contains_substring(bazel_args, "--test_output") {
+			command = append(command, "--test_output=streamed")
+		}
+		if !any_contains_substring(bazel_args, "--test_tmpdir") {
+			if test_tmpdir, err := get_kept_alive_test_tmpdir(); err == nil {
+				command = append(command, "--test_tmpdir="+test_tmpdir)
+			}
+		}
+		command = append(command, ic_version_flags...)
+		command = append(command, spec_flags...)
+		// Append all bazel args following the --, i.e. "ict testnet small -- --test_tmpdir=./tmp".
+		// These come last, so that they take precedence over the flags set by ict.
+		command = append(command, bazel_args...)
+		print_bazel_command(cmd, command)
 		if cfg.isDryRun {
 			return nil
 		} else {
-			// Start Bazel test Command with stdout, stderr streaming.
-			testnetCmd := exec.Command(command[0], command[1:]...)
-			testnetCmd.Stdout = os.Stdout
-			testnetCmd.Stderr = os.Stderr
-			return testnetCmd.Run()
+			record_recent_targets([]string{target})
+			return run_bazel_command_with_keepalive(cmd, command, []string{target}, cfg.openDashboard)
+		}
+	}
+}
+
+// Testnets are either picked by name from the testnet targets, or set up by the generic target from a config file and flags.
+func resolve_testnet_target(cmd *cobra.Command, cfg *TestnetConfig, args []string) (string, []string, error) {
+	if has_testnet_spec_flags(cmd) {
+		spec := get_default_testnet_spec()
+		if len(cfg.fromConfig) > 0 {
+			var err error
+			if spec, err = load_testnet_spec(cfg.fromConfig); err != nil {
+				return "", nil, err
+			}
+		}
+		if cmd.Flags().Changed("with-boundary-nodes") {
+			spec.BoundaryNodes = cfg.boundaryNodes
+		}
+		for _, value := range cfg.prefund {
+			account, err := parse_prefund_flag(value)
+			if err != nil {
+				return "", nil, err
+			}
+			spec.Prefund = append(spec.Prefund, account)
 		}
+		spec.Nns = spec.Nns || cfg.withNns || len(cfg.prefund) > 0
+		spec.Ii = spec.Ii || cfg.withIi
+		if err := validate_testnet_spec(spec); err != nil {
+			return "", nil, fmt.Errorf("\nInvalid testnet config: %s", err)
+		}
+		spec_flag, err := get_testnet_spec_flag(spec)
+		if err != nil {
+			return "", nil, err
+		}
+		return FROM_CONFIG_TESTNET_TARGET, []string{spec_flag}, nil
+	}
+	all_targets, err := get_all_testnets(&cfg.queryCfg)
+	if err != nil {
+		return "", nil, err
 	}
+	target, err := resolve_target(cmd, all_targets, args[0], &cfg.matchCfg)
+	return target, []string{}, err
 }
 
 func NewTestnetCmd() *cobra.Command {
@@ -70,13 +150,42 @@ func NewTestnetCmd() *cobra.Command {
 		Use:     "testnet <testnet_name> [flags] [-- <bazel_args>]",
 		Short:   "Spawn IC testnets for desired time periods. This command blocks the terminal.",
 		Example: "ict testnet small\nict testnet small --lifetime=50 -- --test_tmpdir=./tmp (store artifacts, such as SSH keys)",
-		Args:    cobra.MinimumNArgs(1),
+		Args:    positional_args(cobra.MaximumNArgs(1), testnet_name_or_config(&cfg)),
 		PersistentPreRunE: ValidateTestnetCommand(&cfg),
 		RunE:    TestnetCommand(&cfg),
 	}
-	cmd.Flags().IntVar(&cfg.lifetime, "lifetime", DEFAULT_TESTNET_LIFETIME_MINS, "Keep testnet alive for this duration in mins.")
-	cmd.Flags().BoolVarP(&cfg.isFuzzyMatch, "fuzzy", "", false, "Use fuzzy matching to find similar testnet names. Default: substring match.")
-	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print raw Bazel command to be invoked without execution.")
+	add_testnet_flags(cmd, &cfg)
 	cmd.SetOut(os.Stdout)
 	return cmd
 }
+
+// Same as `ict testnet <testnet_name>`, but doesn't clash with names of other testnet subcommands.
+func NewTestnetCreateCmd() *cobra.Command {
+	var cfg = TestnetConfig{}
+	var cmd = &cobra.Command{
+		Use:     "create [<testnet_name> | --from-config <file>] [flags] [-- <bazel_args>]",
+		Short:   "Create an IC testnet for the desired time period. This command blocks the terminal.",
+		Example: "ict testnet create small\nict testnet create small --lifetime=50 --dry-run\nict testnet create --from-config topology.yaml\nict testnet create --with-boundary-nodes=2 --with-nns --with-ii\nict testnet create --prefund=<principal>=1000",
+		Args:    positional_args(cobra.MaximumNArgs(1), testnet_name_or_config(&cfg)),
+		PersistentPreRunE: ValidateTestnetCommand(&cfg),
+		RunE:    TestnetCommand(&cfg),
+	}
+	add_testnet_flags(cmd, &cfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
+
+func add_testnet_flags(cmd *cobra.Command, cfg *TestnetConfig) {
+	cmd.Flags().IntVar(&cfg.lifetime, "lifetime", DEFAULT_TESTNET_LIFETIME_MINS, "Keep testnet alive for this duration in mins.")
+	add_match_flags(cmd, &cfg.matchCfg)
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print raw Bazel command to be invoked without execution.")
+	cmd.Flags().StringVarP(&cfg.icVersion, "ic-version", "", "", fmt.Sprintf("Deploy the GuestOS image of this git revision or `%s`, instead of the one built from HEAD.", LATEST_MAINNET_VERSION_ALIAS))
+	cmd.Flags().BoolVarP(&cfg.openDashboard, "open-dashboard", "", false, "Open the Grafana dashboard of the testnet in the browser, once it is up.")
+	cmd.Flags().StringVarP(&cfg.fromConfig, "from-config", "", "", "Create the testnet described by this YAML file (subnets, node counts, features and canisters to install), instead of a testnet target.")
+	cmd.Flags().IntVarP(&cfg.boundaryNodes, "with-boundary-nodes", "", 0, "Add this many boundary nodes with real certificates and DNS records, overrides boundary_nodes of --from-config.")
+	cmd.Flags().Lookup("with-boundary-nodes").NoOptDefVal = "1"
+	cmd.Flags().BoolVarP(&cfg.withNns, "with-nns", "", false, "Install the NNS canisters built from HEAD on the NNS subnet.")
+	cmd.Flags().BoolVarP(&cfg.withIi, "with-ii", "", false, "Install the Internet Identity test canister on the NNS subnet.")
+	cmd.Flags().StringArrayVarP(&cfg.prefund, "prefund", "", []string{}, "Fund the ICP ledger account of a principal, as <principal>=<amount of ICP>, implies --with-nns. Can be repeated.")
+	add_query_flags(cmd, &cfg.queryCfg)
+}
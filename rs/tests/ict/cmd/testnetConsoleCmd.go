@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetConsoleConfig struct {
+	farmCfg FarmConfig
+	noOpen  bool
+}
+
+// Same as the console URL logged by start_vm of the test driver, see rs/tests/src/driver/farm.rs
+func get_farm_console_url(farm_base_url string, group string, vm string) string {
+	return fmt.Sprintf("%s/group/%s/vm/%s/console/", farm_base_url, group, vm)
+}
+
+func TestnetConsoleCommand(cfg *TestnetConsoleConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		vm, err := find_testnet_vm(testnet, args[1])
+		if err != nil {
+			return err
+		}
+		console_url := get_farm_console_url(cfg.farmCfg.get_base_url(), testnet.Group, vm.Name)
+		cmd.Printf("%sSerial console of %s (%s):%s\n", CYAN, vm.Name, vm.Ipv6, NC)
+		cmd.Println(console_url)
+		if cfg.noOpen {
+			return nil
+		}
+		// Farm serves the console as a web terminal, which also works while the replica and SSH of the node are down.
+		if err := open_in_default_app(console_url); err != nil {
+			return fmt.Errorf("\nFailed to open the serial console: %s", err)
+		}
+		return nil
+	}
+}
+
+func NewTestnetConsoleCmd() *cobra.Command {
+	var cfg = TestnetConsoleConfig{}
+	var cmd = &cobra.Command{
+		Use:     "console <group> <node>",
+		Short:   "Open the serial console of a VM of a testnet via Farm, e.g. to debug nodes whose replica or SSH is down",
+		Example: "ict testnet console small--1690000000000 0\nict testnet console small--1690000000000 <node_id> --no-open",
+		Args:    cobra.ExactArgs(2),
+		RunE:    TestnetConsoleCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.noOpen, "no-open", "", false, "Only print the URL of the console, instead of opening it in the default browser.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
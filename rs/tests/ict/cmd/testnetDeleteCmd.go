@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetDeleteConfig struct {
+	farmCfg   FarmConfig
+	allMine   bool
+	assumeYes bool
+}
+
+func ValidateTestnetDeleteCommand(cfg *TestnetDeleteConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.allMine && len(args) > 0 {
+			return fmt.Errorf("either pass the names of the groups to delete or --all-mine, not both.")
+		}
+		if !cfg.allMine && len(args) == 0 {
+			return fmt.Errorf("pass the names of the groups to delete or --all-mine.")
+		}
+		return nil
+	}
+}
+
+func TestnetDeleteCommand(cfg *TestnetDeleteConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		groups := args
+		if cfg.allMine {
+			owned, err := list_user_farm_groups(cfg.farmCfg.get_base_url(), cfg.farmCfg.user)
+			if err != nil {
+				return fmt.Errorf("\nFailed to list the Farm groups of %s: %s", cfg.farmCfg.user, err)
+			}
+			groups = []string{}
+			for _, group := range owned {
+				groups = append(groups, group.Name)
+			}
+			if len(groups) == 0 {
+				cmd.Printf("%sUser %s has no groups running on Farm.%s\n", CYAN, cfg.farmCfg.user, NC)
+				return nil
+			}
+			cmd.Printf("%sThe following %d groups of %s are running on Farm:\n%s%s\n", CYAN, len(groups), cfg.farmCfg.user, strings.Join(groups, "\n"), NC)
+			if !cfg.assumeYes && !ask_confirmation(cmd, "Delete all of them?") {
+				return nil
+			}
+		}
+		failed := []string{}
+		for _, group := range groups {
+			cmd.Printf("%sDeleting Farm group %s ...%s\n", CYAN, group, NC)
+			if err := delete_farm_group(cfg.farmCfg.get_base_url(), group); err != nil {
+				cmd.PrintErrf("%sFailed to delete Farm group %s: %s%s\n", RED, group, err, NC)
+				failed = append(failed, group)
+			} else {
+				update_local_testnet(group, func(testnet *localTestnet) {
+					testnet.Deleted = true
+				})
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("\nFailed to delete %d of %d groups: %s", len(failed), len(groups), strings.Join(failed, ", "))
+		}
+		cmd.Printf("%sDeleted %d groups.%s\n", GREEN, len(groups), NC)
+		return nil
+	}
+}
+
+func NewTestnetDeleteCmd() *cobra.Command {
+	var cfg = TestnetDeleteConfig{}
+	var cmd = &cobra.Command{
+		Use:     "delete [<group>...]",
+		Short:   "Delete testnets running on Farm right away, instead of waiting for their TTL to expire",
+		Example: "ict testnet delete small--1690000000000\nict testnet delete --all-mine",
+		Args:    ValidateTestnetDeleteCommand(&cfg),
+		RunE:    TestnetDeleteCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.allMine, "all-mine", "", false, "Delete all groups of the user running on Farm.")
+	cmd.Flags().BoolVarP(&cfg.assumeYes, "yes", "y", false, "Don't ask for confirmation before deleting all groups of the user.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
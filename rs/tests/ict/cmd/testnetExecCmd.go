@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetExecConfig struct {
+	farmCfg FarmConfig
+	nodes   []string
+}
+
+// Prefixes each line written by a command run on a node, lines of concurrent commands don't interleave.
+type nodePrefixWriter struct {
+	out    io.Writer
+	mu     *sync.Mutex
+	prefix string
+	line   []byte
+	// Only lines matching the filter are written, if set.
+	filter *regexp.Regexp
+}
+
+func (w *nodePrefixWriter) Write(p []byte) (int, error) {
+	w.line = append(w.line, p...)
+	for {
+		idx := bytes.IndexByte(w.line, '\n')
+		if idx < 0 {
+			break
+		}
+		w.write_line(w.line[:idx+1])
+		w.line = w.line[idx+1:]
+	}
+	return len(p), nil
+}
+
+func (w *nodePrefixWriter) write_line(line []byte) {
+	if w.filter != nil && !w.filter.Match(line) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+}
+
+// Writes what is left of the last line, if the output of the command didn't end with a newline.
+func (w *nodePrefixWriter) flush() {
+	if len(w.line) > 0 {
+		w.write_line(append(w.line, '\n'))
+		w.line = nil
+	}
+}
+
+// Selects the given nodes of the testnet, or all of them.
+func find_testnet_vms(testnet *runningTestnet, nodes []string) ([]keptAliveVm, error) {
+	if len(nodes) == 0 {
+		return testnet.Vms, nil
+	}
+	vms := []keptAliveVm{}
+	for _, node := range nodes {
+		vm, err := find_testnet_vm(testnet, node)
+		if err != nil {
+			return nil, err
+		}
+		vms = append(vms, *vm)
+	}
+	return vms, nil
+}
+
+// Runs the command via SSH on all VMs in parallel, prefixing their output with the VM names.
+// Returns the names of the VMs, on which the command failed.
+func run_on_testnet_vms(testnet *runningTestnet, vms []keptAliveVm, remote_command []string, get_prefix func(int, keptAliveVm) string, line_filter *regexp.Regexp) []string {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := make([]string, len(vms))
+	for i, vm := range vms {
+		wg.Add(1)
+		go func(i int, vm keptAliveVm) {
+			defer wg.Done()
+			prefix := get_prefix(i, vm)
+			command := append([]string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=10"}, get_testnet_ssh_options(testnet)...)
+			command = append(command, fmt.Sprintf("%s@%s", TESTNET_SSH_USERNAME, vm.Ipv6))
+			command = append(command, remote_command...)
+			stdout := &nodePrefixWriter{out: os.Stdout, mu: &mu, prefix: prefix, filter: line_filter}
+			stderr := &nodePrefixWriter{out: os.Stderr, mu: &mu, prefix: prefix}
+			ssh := exec.Command("ssh", command...)
+			ssh.Stdout, ssh.Stderr = stdout, stderr
+			err := ssh.Run()
+			stdout.flush()
+			stderr.flush()
+			if err != nil {
+				stderr.filter = nil
+				stderr.write_line([]byte(fmt.Sprintf("%s%s%s\n", RED, err, NC)))
+				failed[i] = vm.Name
+			}
+		}(i, vm)
+	}
+	wg.Wait()
+	return filter(failed, func(name string) bool { return len(name) > 0 })
+}
+
+// VM names are padded to the same width, so that the output of all nodes lines up.
+func get_vm_prefix_width(vms []keptAliveVm) int {
+	width := 0
+	for _, vm := range vms {
+		if len(vm.Name) > width {
+			width = len(vm.Name)
+		}
+	}
+	return width
+}
+
+func ValidateTestnetExecCommand(cfg *TestnetExecConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		positional, remote_command := split_bazel_args(cmd, args)
+		if len(positional) != 1 || len(remote_command) == 0 {
+			return fmt.Errorf("pass the group and the command to run following the --, i.e. ict testnet exec <group> -- <command>.")
+		}
+		return nil
+	}
+}
+
+func TestnetExecCommand(cfg *TestnetExecConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, remote_command := split_bazel_args(cmd, args)
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		vms, err := find_testnet_vms(testnet, cfg.nodes)
+		if err != nil {
+			return err
+		}
+		if _, ok := get_testnet_ssh_key(testnet); !ok {
+			cmd.Printf("%sThe SSH key of the test driver is only known for testnets kept alive by ict on this machine, falling back to your own keys.%s\n", CYAN, NC)
+		}
+		cmd.Printf("%sRunning `%s` on %d nodes of %s%s\n", CYAN, strings.Join(remote_command, " "), len(vms), testnet.Group, NC)
+		width := get_vm_prefix_width(vms)
+		failed := run_on_testnet_vms(testnet, vms, remote_command, func(i int, vm keptAliveVm) string {
+			return fmt.Sprintf("[%-*s] ", width, vm.Name)
+		}, nil)
+		if len(failed) > 0 {
+			return fmt.Errorf("\nCommand failed on %d of %d nodes: %s", len(failed), len(vms), strings.Join(failed, ", "))
+		}
+		return nil
+	}
+}
+
+func NewTestnetExecCmd() *cobra.Command {
+	var cfg = TestnetExecConfig{}
+	var cmd = &cobra.Command{
+		Use:     "exec <group> -- <command>",
+		Short:   "Run a shell command via SSH on all nodes of a testnet running on Farm in parallel",
+		Example: "ict testnet exec small--1690000000000 -- sudo systemctl restart ic-replica\nict testnet exec small--1690000000000 --node 0 --node 1 -- 'journalctl -u ic-replica | grep -i panic'",
+		Args:    ValidateTestnetExecCommand(&cfg),
+		RunE:    TestnetExecCommand(&cfg),
+	}
+	cmd.Flags().StringArrayVarP(&cfg.nodes, "node", "", []string{}, "Run the command only on this node, picked by node id, its prefix or index (repeatable).")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
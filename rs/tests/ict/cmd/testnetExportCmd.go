@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Everything needed to attach to a testnet from another machine, see `ict testnet import`.
+type testnetBundle struct {
+	Group         string         `json:"group"`
+	Target        string         `json:"target,omitempty"`
+	ExpiresAt     time.Time      `json:"expires_at"`
+	Vms           []keptAliveVm  `json:"vms"`
+	BoundaryNodes []boundaryNode `json:"boundary_nodes,omitempty"`
+	PrometheusUrl string         `json:"prometheus_url,omitempty"`
+	GrafanaUrl    string         `json:"grafana_url,omitempty"`
+	Urls          []testnetUrl   `json:"urls"`
+	Ssh           testnetSshHint `json:"ssh"`
+}
+
+type testnetSshHint struct {
+	Username string `json:"username"`
+	// One command per VM, the key of the test driver has to be passed via -i, unless the own key is authorized on the VMs.
+	Commands []string `json:"commands"`
+	// Private key of the test driver, which is authorized on all VMs, only exported via --include-ssh-key.
+	PrivateKey string `json:"private_key,omitempty"`
+}
+
+type TestnetExportConfig struct {
+	farmCfg       FarmConfig
+	includeSshKey bool
+}
+
+func get_testnet_bundle(testnet *runningTestnet, include_ssh_key bool) (*testnetBundle, error) {
+	bundle := testnetBundle{Group: testnet.Group, ExpiresAt: testnet.ExpiresAt, Vms: testnet.Vms, BoundaryNodes: get_boundary_nodes(testnet),
+		PrometheusUrl: testnet.PrometheusUrl, GrafanaUrl: testnet.GrafanaUrl, Urls: get_testnet_urls(testnet), Ssh: testnetSshHint{Username: TESTNET_SSH_USERNAME, Commands: []string{}}}
+	if env, err := find_kept_alive_env(testnet.Group); err == nil {
+		bundle.Target = env.Target
+	}
+	for _, vm := range testnet.Vms {
+		bundle.Ssh.Commands = append(bundle.Ssh.Commands, fmt.Sprintf("ssh %s@%s", TESTNET_SSH_USERNAME, vm.Ipv6))
+	}
+	if include_ssh_key {
+		key, ok := get_testnet_ssh_key(testnet)
+		if !ok {
+			return nil, fmt.Errorf("\nThe SSH key of %s is only known for testnets kept alive by ict on this machine.", testnet.Group)
+		}
+		content, err := os.ReadFile(key)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Ssh.PrivateKey = string(content)
+	}
+	return &bundle, nil
+}
+
+func TestnetExportCommand(cfg *TestnetExportConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		bundle, err := get_testnet_bundle(testnet, cfg.includeSshKey)
+		if err != nil {
+			return err
+		}
+		if len(args) == 1 {
+			return print_json(cmd, bundle)
+		}
+		content, err := json.MarshalIndent(bundle, "", "  ")
+		if err != nil {
+			return err
+		}
+		// The bundle may contain the private key.
+		if err := os.WriteFile(args[1], append(content, '\n'), 0600); err != nil {
+			return fmt.Errorf("\nFailed to write the bundle: %s", err)
+		}
+		cmd.Printf("%sExported %s to %s, attach to it from another machine via:\n$ ict testnet import %s%s\n", GREEN, testnet.Group, args[1], args[1], NC)
+		return nil
+	}
+}
+
+func NewTestnetExportCmd() *cobra.Command {
+	var cfg = TestnetExportConfig{}
+	var cmd = &cobra.Command{
+		Use:     "export <group> [<bundle.json>]",
+		Short:   "Export the topology, URLs and SSH hints of a testnet as JSON, so that others can attach to it via `ict testnet import`",
+		Example: "ict testnet export small--1690000000000 bundle.json\nict testnet export small--1690000000000 bundle.json --include-ssh-key\nict testnet export small--1690000000000 | jq .urls",
+		Args:    cobra.RangeArgs(1, 2),
+		RunE:    TestnetExportCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.includeSshKey, "include-ssh-key", "", false, "Include the private SSH key of the test driver, which is authorized on all VMs of the testnet.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetExtendConfig struct {
+	farmCfg FarmConfig
+	ttl     time.Duration
+}
+
+func ValidateTestnetExtendCommand(cfg *TestnetExtendConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if cfg.ttl < time.Minute {
+			return fmt.Errorf("option --ttl should be at least 1m, e.g. --ttl 4h.")
+		}
+		return nil
+	}
+}
+
+func TestnetExtendCommand(cfg *TestnetExtendConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		group := args[0]
+		if err := set_farm_group_ttl(cfg.farmCfg.get_base_url(), group, cfg.ttl); err != nil {
+			return fmt.Errorf("\nFailed to extend the lifetime of Farm group %s: %s", group, err)
+		}
+		update_local_testnet(group, func(testnet *localTestnet) {
+			testnet.ExpiresAt = time.Now().Add(cfg.ttl)
+		})
+		cmd.Printf("%sFarm group %s now expires at %s (in %s).%s\n", GREEN, group, time.Now().Add(cfg.ttl).Format("2006-01-02 15:04:05"), cfg.ttl, NC)
+		return nil
+	}
+}
+
+func NewTestnetExtendCmd() *cobra.Command {
+	var cfg = TestnetExtendConfig{}
+	var cmd = &cobra.Command{
+		Use:     "extend <group> --ttl <duration>",
+		Short:   "Extend the lifetime of a testnet running on Farm, so that it isn't deleted mid-investigation",
+		Example: "ict testnet extend small--1690000000000 --ttl 4h",
+		Args:    ValidateTestnetExtendCommand(&cfg),
+		RunE:    TestnetExtendCommand(&cfg),
+	}
+	cmd.Flags().DurationVarP(&cfg.ttl, "ttl", "", 0, "Time from now, after which Farm deletes the group, e.g. 90m or 4h.")
+	cmd.MarkFlagRequired("ttl")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
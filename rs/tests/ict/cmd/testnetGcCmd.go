@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetGcConfig struct {
+	farmCfg   FarmConfig
+	assumeYes bool
+	isDryRun  bool
+}
+
+// Returns why a testnet still running on Farm should be deleted, empty if it is still needed.
+func get_obsolete_reason(testnet *localTestnet) string {
+	// Farm groups outlive their intended lifetime, if their TTL was bumped by others, e.g. the test driver.
+	if time.Now().After(testnet.ExpiresAt) {
+		return fmt.Sprintf("its lifetime ended at %s", testnet.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if len(testnet.Branch) > 0 {
+		if _, err := run_git("-C", get_workspace_root(), "rev-parse", "--verify", "--quiet", "refs/heads/"+testnet.Branch); err != nil {
+			return fmt.Sprintf("its branch %s was deleted", testnet.Branch)
+		}
+	}
+	return ""
+}
+
+// Only log dirs of kept alive runs are removed, other test tmpdirs may be shared, e.g. via --test_tmpdir.
+func is_ict_log_dir(log_dir string) bool {
+	ict_dir, err := get_ict_dir()
+	if err != nil || len(log_dir) == 0 {
+		return false
+	}
+	rel, err := filepath.Rel(filepath.Join(ict_dir, "envs"), log_dir)
+	return err == nil && !strings.HasPrefix(rel, "..") && rel != "."
+}
+
+func TestnetGcCommand(cfg *TestnetGcConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		groups, err := list_user_farm_groups(cfg.farmCfg.get_base_url(), cfg.farmCfg.user)
+		if err != nil {
+			return fmt.Errorf("\nFailed to list the Farm groups of %s, which are compared against the local testnets: %s", cfg.farmCfg.user, err)
+		}
+		running := []string{}
+		for _, group := range groups {
+			running = append(running, group.Name)
+		}
+		obsolete, stale := []localTestnet{}, []localTestnet{}
+		for _, testnet := range load_local_testnets() {
+			if !any_equals(running, testnet.Name) {
+				stale = append(stale, testnet)
+			} else if reason := get_obsolete_reason(&testnet); len(reason) > 0 {
+				cmd.Printf("%sFarm group %s is obsolete, as %s.%s\n", CYAN, testnet.Name, reason, NC)
+				obsolete = append(obsolete, testnet)
+			}
+		}
+		for _, testnet := range stale {
+			cmd.Printf("%sFarm group %s is gone, its local metadata is stale.%s\n", CYAN, testnet.Name, NC)
+		}
+		if len(obsolete) == 0 && len(stale) == 0 {
+			cmd.Printf("%sNothing to clean up.%s\n", GREEN, NC)
+			return nil
+		}
+		if cfg.isDryRun {
+			return nil
+		}
+		if !cfg.assumeYes && !ask_confirmation(cmd, fmt.Sprintf("Delete %d Farm groups and prune the metadata of %d testnets?", len(obsolete), len(obsolete)+len(stale))) {
+			return nil
+		}
+		failed := []string{}
+		for _, testnet := range obsolete {
+			cmd.Printf("%sDeleting Farm group %s ...%s\n", CYAN, testnet.Name, NC)
+			if err := delete_farm_group(cfg.farmCfg.get_base_url(), testnet.Name); err != nil {
+				cmd.PrintErrf("%sFailed to delete Farm group %s: %s%s\n", RED, testnet.Name, err, NC)
+				failed = append(failed, testnet.Name)
+				continue
+			}
+			stale = append(stale, testnet)
+		}
+		names := []string{}
+		for _, testnet := range stale {
+			names = append(names, testnet.Name)
+			if is_ict_log_dir(testnet.LogDir) {
+				if err := os.RemoveAll(testnet.LogDir); err != nil {
+					cmd.PrintErrf("%sFailed to remove %s: %s%s\n", RED, testnet.LogDir, err, NC)
+				}
+			}
+		}
+		if err := remove_kept_alive_envs(names); err != nil {
+			return fmt.Errorf("\nFailed to prune the kept alive environments: %s", err)
+		}
+		if err := remove_local_testnets(names); err != nil {
+			return fmt.Errorf("\nFailed to prune the local testnets: %s", err)
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("\nFailed to delete %d of %d groups: %s", len(failed), len(obsolete), strings.Join(failed, ", "))
+		}
+		cmd.Printf("%sDeleted %d Farm groups and pruned the metadata of %d testnets.%s\n", GREEN, len(obsolete), len(names), NC)
+		return nil
+	}
+}
+
+func NewTestnetGcCmd() *cobra.Command {
+	var cfg = TestnetGcConfig{}
+	var cmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Delete obsolete testnets created by ict on this machine and prune the metadata of the ones gone from Farm",
+		Long: `Delete obsolete testnets created by ict on this machine and prune the metadata of the ones gone from Farm.
+
+The testnets recorded in ~/.ict/testnets.json (see ict testnet list --local) are compared against the groups of the user on Farm.
+Groups past their intended lifetime, or whose branch was deleted, are deleted.
+The records, kept alive environments and log dirs of groups gone from Farm are removed.`,
+		Example: "ict testnet gc --dry-run\nict testnet gc --yes",
+		Args:    cobra.ExactArgs(0),
+		RunE:    TestnetGcCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.assumeYes, "yes", "y", false, "Don't ask for confirmation before deleting groups and pruning metadata.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Only print what would be deleted and pruned.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
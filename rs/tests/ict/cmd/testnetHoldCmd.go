@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetHoldConfig struct {
+	farmCfg         FarmConfig
+	interval        time.Duration
+	ttl             time.Duration
+	deleteOnRelease bool
+}
+
+func ValidateTestnetHoldCommand(cfg *TestnetHoldConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if cfg.interval < time.Minute {
+			return fmt.Errorf("option --interval should be at least 1m.")
+		}
+		// Leaves time for a missed heartbeat, e.g. due to a flaky connection.
+		if cfg.ttl < 2*cfg.interval {
+			return fmt.Errorf("option --ttl should be at least twice the --interval.")
+		}
+		return nil
+	}
+}
+
+// Only extends the lifetime of the group, one which already lasts longer, e.g. after `ict testnet extend`, is left as is.
+// Returns when the group expires, as far as known.
+func send_heartbeat(cmd *cobra.Command, cfg *TestnetHoldConfig, group string, expires_at time.Time) time.Time {
+	current, err := get_farm_group(cfg.farmCfg.get_base_url(), group)
+	if err != nil {
+		cmd.PrintErrf("%sFailed to look up Farm group %s, retrying in %s: %s%s\n", RED, group, cfg.interval, err, NC)
+		return expires_at
+	}
+	extended := time.Now().Add(cfg.ttl)
+	if !extended.After(current.ExpiresAt) {
+		cmd.Printf("%s[%s] Farm group %s already expires at %s, later than a heartbeat would extend it to.%s\n", CYAN, time.Now().Format("15:04:05"), group, current.ExpiresAt.Local().Format("15:04:05"), NC)
+		return current.ExpiresAt
+	}
+	if err := set_farm_group_ttl(cfg.farmCfg.get_base_url(), group, cfg.ttl); err != nil {
+		cmd.PrintErrf("%sFailed to extend the lifetime of Farm group %s, retrying in %s: %s%s\n", RED, group, cfg.interval, err, NC)
+		return current.ExpiresAt
+	}
+	update_local_testnet(group, func(testnet *localTestnet) {
+		testnet.ExpiresAt = extended
+	})
+	cmd.Printf("%s[%s] Farm group %s now expires at %s.%s\n", CYAN, time.Now().Format("15:04:05"), group, extended.Format("15:04:05"), NC)
+	return extended
+}
+
+// Holds the group for as long as ict runs, i.e. while the machine is up, a suspended machine stops sending heartbeats.
+func TestnetHoldCommand(cfg *TestnetHoldConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		group := args[0]
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+		defer signal.Stop(signals)
+		cmd.Printf("%sHolding Farm group %s, extending its lifetime to %s every %s, press Ctrl-C to release it.%s\n", GREEN, group, cfg.ttl, cfg.interval, NC)
+		expires_at := send_heartbeat(cmd, cfg, group, time.Time{})
+		ticker := time.NewTicker(cfg.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				expires_at = send_heartbeat(cmd, cfg, group, expires_at)
+			case <-signals:
+				if !cfg.deleteOnRelease {
+					if expires_at.IsZero() {
+						cmd.Printf("\n%sReleased Farm group %s, its lifetime wasn't extended.%s\n", GREEN, group, NC)
+						return nil
+					}
+					cmd.Printf("\n%sReleased Farm group %s, it expires at %s (in %s).%s\n", GREEN, group, expires_at.Local().Format("15:04:05"), time.Until(expires_at).Round(time.Minute), NC)
+					return nil
+				}
+				cmd.Printf("\n%sReleased Farm group %s, deleting it ...%s\n", CYAN, group, NC)
+				if err := delete_farm_group(cfg.farmCfg.get_base_url(), group); err != nil {
+					return fmt.Errorf("\nFailed to delete Farm group %s: %s", group, err)
+				}
+				update_local_testnet(group, func(testnet *localTestnet) {
+					testnet.Deleted = true
+				})
+				cmd.Printf("%sDeleted Farm group %s.%s\n", GREEN, group, NC)
+				return nil
+			}
+		}
+	}
+}
+
+func NewTestnetHoldCmd() *cobra.Command {
+	var cfg = TestnetHoldConfig{}
+	var cmd = &cobra.Command{
+		Use:     "hold <group>",
+		Short:   "Keep extending the lifetime of a testnet running on Farm until Ctrl-C, instead of extending it by hand",
+		Example: "ict testnet hold small--1690000000000\nict testnet hold small--1690000000000 --interval 5m --ttl 20m --delete-on-release",
+		Args:    ValidateTestnetHoldCommand(&cfg),
+		RunE:    TestnetHoldCommand(&cfg),
+	}
+	cmd.Flags().DurationVarP(&cfg.interval, "interval", "", 10*time.Minute, "Time between heartbeats, each of which extends the lifetime of the group.")
+	cmd.Flags().DurationVarP(&cfg.ttl, "ttl", "", 30*time.Minute, "Lifetime of the group from each heartbeat on, i.e. how long it survives once the heartbeats stop. A group, which already lasts longer, is left as is.")
+	cmd.Flags().BoolVarP(&cfg.deleteOnRelease, "delete-on-release", "", false, "Delete the group on Ctrl-C, instead of letting it expire.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
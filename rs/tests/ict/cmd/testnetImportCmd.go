@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func get_imported_setup_dir(group string) (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, "imported", group), nil
+}
+
+// Mirrors the layout of the setup directory of the test driver for the parts contained in the bundle, so that e.g. `ict testnet ssh` finds them.
+func write_imported_setup_dir(setup_dir string, bundle *testnetBundle) error {
+	if len(bundle.Ssh.PrivateKey) > 0 {
+		key := filepath.Join(setup_dir, TESTNET_SSH_PRIV_KEYS_DIR, TESTNET_SSH_USERNAME)
+		if err := os.MkdirAll(filepath.Dir(key), 0700); err != nil {
+			return err
+		}
+		if err := os.WriteFile(key, []byte(bundle.Ssh.PrivateKey), 0600); err != nil {
+			return err
+		}
+	}
+	for _, node := range bundle.BoundaryNodes {
+		dir := filepath.Join(setup_dir, BOUNDARY_NODE_VMS_DIR, node.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		content, err := json.Marshal(node)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "vm.json"), content, 0644); err != nil {
+			return err
+		}
+		if len(node.Domain) > 0 {
+			content, err := json.Marshal(node.Domain)
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, "playnet.json"), content, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestnetImportCommand(cmd *cobra.Command, args []string) error {
+	content, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("\nFailed to read the bundle: %s", err)
+	}
+	bundle := testnetBundle{}
+	if err := json.Unmarshal(content, &bundle); err != nil {
+		return fmt.Errorf("\nFailed to parse the bundle %s: %s", args[0], err)
+	}
+	if len(bundle.Group) == 0 || len(bundle.Vms) == 0 {
+		return fmt.Errorf("\n%s isn't a testnet bundle, export one via `ict testnet export`.", args[0])
+	}
+	if time.Now().After(bundle.ExpiresAt) {
+		return fmt.Errorf("\nFarm group %s has expired at %s.", bundle.Group, bundle.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	setup_dir, err := get_imported_setup_dir(bundle.Group)
+	if err != nil {
+		return err
+	}
+	if err := os.RemoveAll(setup_dir); err != nil {
+		return err
+	}
+	if err := write_imported_setup_dir(setup_dir, &bundle); err != nil {
+		return fmt.Errorf("\nFailed to import %s: %s", bundle.Group, err)
+	}
+	env := keptAliveEnv{Group: bundle.Group, Target: bundle.Target, SetupDir: setup_dir, ExpiresAt: bundle.ExpiresAt, Vms: bundle.Vms,
+		PrometheusUrl: bundle.PrometheusUrl, GrafanaUrl: bundle.GrafanaUrl, Imported: true}
+	if err := record_kept_alive_env(env); err != nil {
+		return fmt.Errorf("\nFailed to import %s: %s", bundle.Group, err)
+	}
+	cmd.Printf("%sImported %s, which expires at %s (in %s).%s\n", GREEN, bundle.Group, bundle.ExpiresAt.Local().Format("2006-01-02 15:04:05"), time.Until(bundle.ExpiresAt).Round(time.Minute), NC)
+	if len(bundle.Ssh.PrivateKey) == 0 {
+		cmd.Printf("%sThe bundle contains no SSH key, so SSH only works if your own key is authorized on the VMs.%s\n", CYAN, NC)
+	}
+	cmd.Printf("%sInspect it via:\n$ ict testnet topology %s\n$ ict testnet urls %s%s\n", GREEN, bundle.Group, bundle.Group, NC)
+	return nil
+}
+
+func NewTestnetImportCmd() *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:     "import <bundle.json>",
+		Short:   "Import a testnet exported via `ict testnet export`, so that the testnet subcommands can attach to it",
+		Example: "ict testnet import bundle.json",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetImportCommand,
+	}
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
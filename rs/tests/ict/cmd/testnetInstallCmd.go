@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// Installs canisters via the test driver, from the setup directory of a testnet, see install_canister.rs in rs/tests/testing_verification/testnets.
+var INSTALL_CANISTER_TARGET = "//rs/tests/testing_verification/testnets:install_canister"
+
+type TestnetInstallConfig struct {
+	farmCfg  FarmConfig
+	wasm     string
+	arg      string
+	subnet   string
+	isDryRun bool
+}
+
+func ValidateTestnetInstallCommand(cfg *TestnetInstallConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		// A missing --wasm is reported as a missing required flag.
+		if _, err := os.Stat(cfg.wasm); len(cfg.wasm) > 0 && err != nil {
+			return fmt.Errorf("option --wasm should be an existing file: %s", err)
+		}
+		return nil
+	}
+}
+
+func TestnetInstallCommand(cfg *TestnetInstallConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		if env, err := find_kept_alive_env(testnet.Group); len(testnet.SetupDir) == 0 || (err == nil && env.Imported) {
+			return fmt.Errorf("\nCanisters can only be installed on testnets kept alive by ict on this machine, as the registry of %s is needed.", testnet.Group)
+		}
+		// The binary runs in its runfiles directory.
+		wasm, err := filepath.Abs(cfg.wasm)
+		if err != nil {
+			return err
+		}
+		command := []string{"bazel", "run", INSTALL_CANISTER_TARGET, "--", "--setup-dir", testnet.SetupDir, "--wasm", wasm, "--subnet", cfg.subnet}
+		if len(cfg.arg) > 0 {
+			command = append(command, "--arg", cfg.arg)
+		}
+		print_bazel_command(cmd, command)
+		if cfg.isDryRun {
+			return nil
+		}
+		return run_bazel_command(command)
+	}
+}
+
+func NewTestnetInstallCmd() *cobra.Command {
+	var cfg = TestnetInstallConfig{}
+	var cmd = &cobra.Command{
+		Use:     "install <group> --wasm <path> [--arg <candid>] [--subnet <index>|<id>]",
+		Short:   "Create a canister on a subnet of a testnet kept alive by ict and install a wasm module, printing the canister id",
+		Example: "ict testnet install small--1690000000000 --wasm counter.wasm\nict testnet install small--1690000000000 --wasm counter.wasm --subnet 1 --arg '(42 : nat)'",
+		Args:    ValidateTestnetInstallCommand(&cfg),
+		RunE:    TestnetInstallCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.wasm, "wasm", "", "", "Path of the .wasm or .wat module to install.")
+	cmd.MarkFlagRequired("wasm")
+	cmd.Flags().StringVarP(&cfg.arg, "arg", "", "", "Init argument of the canister, in candid textual format, e.g. '(42 : nat)'.")
+	cmd.Flags().StringVarP(&cfg.subnet, "subnet", "", "0", "Index or id (prefix) of the subnet to create the canister on, see `ict testnet topology`.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print raw Bazel command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -1,29 +1,118 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
-func TestnetListCommand(cmd *cobra.Command, args []string) error {
-	if targets, err := get_all_testnets(); err == nil {
-		cmd.Printf("%sThe following %d testnets were found:\n%s%s\n", CYAN, len(targets), strings.Join(targets, "\n"), NC)
+type TestnetListConfig struct {
+	queryCfg QueryConfig
+	isJson   bool
+	isLong   bool
+	// List the groups of a user running on Farm instead of the testnet targets.
+	isRunning bool
+	// List the testnets created by ict on this machine, without querying Farm.
+	isLocal bool
+	farmCfg FarmConfig
+}
+
+func TestnetListCommand(cfg *TestnetListConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.isRunning {
+			return print_running_testnets(cmd, cfg)
+		}
+		if cfg.isLocal {
+			return print_local_testnets(cmd, cfg)
+		}
+		if testnets, err := get_all_testnet_targets(&cfg.queryCfg); err == nil {
+			if cfg.isJson {
+				return print_json(cmd, testnets)
+			}
+			if cfg.isLong {
+				return print_targets_table(cmd, testnets)
+			}
+			targets := target_names(testnets)
+			cmd.Printf("%sThe following %d testnets were found:\n%s%s\n", CYAN, len(targets), strings.Join(targets, "\n"), NC)
+			return nil
+		} else {
+			return err
+		}
+	}
+}
+
+func print_running_testnets(cmd *cobra.Command, cfg *TestnetListConfig) error {
+	groups, err := list_user_farm_groups(cfg.farmCfg.get_base_url(), cfg.farmCfg.user)
+	if err != nil {
+		return fmt.Errorf("\nFailed to list the Farm groups of %s: %s", cfg.farmCfg.user, err)
+	}
+	if cfg.isJson {
+		return print_json(cmd, groups)
+	}
+	if len(groups) == 0 {
+		cmd.Printf("%sUser %s has no groups running on Farm.%s\n", CYAN, cfg.farmCfg.user, NC)
+		return nil
+	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTEST\tCREATED\tEXPIRES\tNODES")
+	for _, group := range groups {
+		expires := fmt.Sprintf("%s (in %s)", group.ExpiresAt.Local().Format("2006-01-02 15:04:05"), time.Until(group.ExpiresAt).Round(time.Minute))
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", group.Name, group.Spec.Metadata.TestName, group.CreatedAt.Local().Format("2006-01-02 15:04:05"), expires, len(group.Vms))
+	}
+	return w.Flush()
+}
+
+func get_local_testnet_status(testnet *localTestnet) string {
+	if testnet.Deleted {
+		return "deleted"
+	}
+	if time.Now().After(testnet.ExpiresAt) {
+		return "expired"
+	}
+	return fmt.Sprintf("expires in %s", time.Until(testnet.ExpiresAt).Round(time.Minute))
+}
+
+func print_local_testnets(cmd *cobra.Command, cfg *TestnetListConfig) error {
+	testnets := load_local_testnets()
+	if cfg.isJson {
+		return print_json(cmd, testnets)
+	}
+	if len(testnets) == 0 {
+		cmd.Printf("%sNo testnets were created by ict on this machine.%s\n", CYAN, NC)
 		return nil
-	} else {
-		return err
 	}
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tTARGET\tCREATED\tEXPIRES\tSTATUS\tLOG DIR")
+	for i := range testnets {
+		testnet := &testnets[i]
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", testnet.Name, testnet.Target, testnet.CreatedAt.Local().Format("2006-01-02 15:04:05"), testnet.ExpiresAt.Local().Format("2006-01-02 15:04:05"), get_local_testnet_status(testnet), testnet.LogDir)
+	}
+	return w.Flush()
 }
 
 func NewTestnetListCmd() *cobra.Command {
+	var cfg = TestnetListConfig{}
 	var cmd = &cobra.Command{
 		Use:     "list",
 		Short:   "List all existing IC testnets",
-		Example: "ict testnet list",
+		Example: "ict testnet list\nict testnet list --json\nict testnet list --running\nict testnet list --local",
 		Args:    cobra.ExactArgs(0),
-		RunE:    TestnetListCommand,
+		RunE:    TestnetListCommand(&cfg),
 	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print testnets with their attributes as JSON.")
+	cmd.Flags().BoolVarP(&cfg.isLong, "long", "l", false, "Print size, timeout, flakiness and tags of each target as a table.")
+	cmd.Flags().BoolVarP(&cfg.isRunning, "running", "", false, "List the groups of the user currently running on Farm, instead of the testnet targets.")
+	cmd.Flags().BoolVarP(&cfg.isLocal, "local", "", false, "List the testnets created by ict on this machine, including expired and deleted ones, without querying Farm.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.MarkFlagsMutuallyExclusive("json", "long")
+	cmd.MarkFlagsMutuallyExclusive("running", "long")
+	cmd.MarkFlagsMutuallyExclusive("local", "long")
+	cmd.MarkFlagsMutuallyExclusive("local", "running")
+	add_query_flags(cmd, &cfg.queryCfg)
 	cmd.SetOut(os.Stdout)
 	return cmd
 }
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Colors of the node prefixes, nodes beyond the number of colors reuse them.
+var NODE_COLORS = []string{"\033[0;32m", "\033[0;33m", "\033[0;34m", "\033[0;35m", "\033[0;36m", "\033[0;91m", "\033[0;92m", "\033[0;93m", "\033[0;94m", "\033[0;95m"}
+
+type TestnetLogsConfig struct {
+	farmCfg  FarmConfig
+	nodes    []string
+	units    []string
+	isFollow bool
+	lines    int
+	since    string
+	filter   string
+}
+
+func ValidateTestnetLogsCommand(cfg *TestnetLogsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if _, err := regexp.Compile(cfg.filter); err != nil {
+			return fmt.Errorf("option --filter is not a valid regex: %s", err)
+		}
+		return nil
+	}
+}
+
+// Journald of the nodes is read via journalctl, whose output is merged in the order the lines arrive.
+// Nodes not running the given units, e.g. the Prometheus VM, simply print no lines.
+func get_journalctl_command(cfg *TestnetLogsConfig) []string {
+	command := []string{"sudo", "journalctl", "--no-pager", "--lines", strconv.Itoa(cfg.lines)}
+	for _, unit := range cfg.units {
+		command = append(command, "--unit", unit)
+	}
+	if len(cfg.since) > 0 {
+		command = append(command, "--since", shell_quote([]string{cfg.since}))
+	}
+	if cfg.isFollow {
+		command = append(command, "--follow")
+	}
+	return command
+}
+
+func TestnetLogsCommand(cfg *TestnetLogsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		vms, err := find_testnet_vms(testnet, cfg.nodes)
+		if err != nil {
+			return err
+		}
+		var filter *regexp.Regexp
+		if len(cfg.filter) > 0 {
+			filter = regexp.MustCompile(cfg.filter)
+		}
+		if _, ok := get_testnet_ssh_key(testnet); !ok {
+			cmd.Printf("%sThe SSH key of the test driver is only known for testnets kept alive by ict on this machine, falling back to your own keys.%s\n", CYAN, NC)
+		}
+		command := get_journalctl_command(cfg)
+		cmd.Printf("%sStreaming `%s` from %d nodes of %s, press Ctrl-C to stop.%s\n", CYAN, strings.Join(command, " "), len(vms), testnet.Group, NC)
+		width := get_vm_prefix_width(vms)
+		failed := run_on_testnet_vms(testnet, vms, command, func(i int, vm keptAliveVm) string {
+			return fmt.Sprintf("%s%-*s%s | ", NODE_COLORS[i%len(NODE_COLORS)], width, vm.Name, NC)
+		}, filter)
+		if len(failed) > 0 {
+			return fmt.Errorf("\nFailed to read the logs of %d of %d nodes: %s", len(failed), len(vms), strings.Join(failed, ", "))
+		}
+		return nil
+	}
+}
+
+func NewTestnetLogsCmd() *cobra.Command {
+	var cfg = TestnetLogsConfig{}
+	var cmd = &cobra.Command{
+		Use:     "logs <group>",
+		Short:   "Stream the journald logs of all nodes of a testnet running on Farm, merged and prefixed with the node ids",
+		Example: "ict testnet logs small--1690000000000 --follow\nict testnet logs small--1690000000000 --follow --unit ic-replica --filter 'ERROR|panicked'",
+		Args:    ValidateTestnetLogsCommand(&cfg),
+		RunE:    TestnetLogsCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isFollow, "follow", "f", false, "Keep streaming new log lines, until interrupted.")
+	cmd.Flags().StringVarP(&cfg.filter, "filter", "", "", "Print only lines matching this regex.")
+	cmd.Flags().StringArrayVarP(&cfg.units, "unit", "u", []string{}, "Print only the logs of this systemd unit, e.g. ic-replica (repeatable).")
+	cmd.Flags().IntVarP(&cfg.lines, "lines", "", 100, "Number of most recent lines of each node to start with.")
+	cmd.Flags().StringVarP(&cfg.since, "since", "", "", "Print only lines logged since this time, in any format journalctl accepts, e.g. \"10 min ago\".")
+	cmd.Flags().StringArrayVarP(&cfg.nodes, "node", "", []string{}, "Stream only the logs of this node, picked by node id, its prefix or index (repeatable).")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
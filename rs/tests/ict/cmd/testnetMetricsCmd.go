@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Same as REPLICA_METRICS_PORT of the test driver, see rs/tests/src/driver/prometheus_vm.rs
+var REPLICA_METRICS_PORT = 9090
+
+// Metrics of the replica, which the snapshot consists of, see rs/tests/dashboards for their use in Grafana.
+var FINALIZED_HEIGHT_METRIC = "consensus_batch_height"
+var RESIDENT_MEMORY_METRIC = "process_resident_memory_bytes"
+var CONSUMED_CYCLES_METRIC = "replicated_state_consumed_cycles_since_replica_started"
+
+type TestnetMetricsConfig struct {
+	farmCfg  FarmConfig
+	interval time.Duration
+	isJson   bool
+}
+
+type nodeMetrics struct {
+	Vm     keptAliveVm `json:"vm"`
+	Height uint64      `json:"height"`
+	// Finalized blocks and consumed cycles per second, between the two scrapes.
+	FinalizationRate float64 `json:"finalization_rate"`
+	MemoryBytes      uint64  `json:"memory_bytes"`
+	CyclesBurnRate   float64 `json:"cycles_burn_rate"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// Returns the values of the given unlabelled metrics, in the Prometheus text format.
+func scrape_replica_metrics(ipv6 string, names []string) (map[string]float64, error) {
+	client := http.Client{Timeout: REPLICA_STATUS_TIMEOUT}
+	resp, err := client.Get(fmt.Sprintf("http://[%s]:%d/metrics", ipv6, REPLICA_METRICS_PORT))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scraping metrics failed with %s", resp.Status)
+	}
+	values := map[string]float64{}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || !any_equals(names, fields[0]) {
+			continue
+		}
+		if value, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values, scanner.Err()
+}
+
+func get_node_metrics(vms []keptAliveVm, interval time.Duration) []nodeMetrics {
+	names := []string{FINALIZED_HEIGHT_METRIC, RESIDENT_MEMORY_METRIC, CONSUMED_CYCLES_METRIC}
+	metrics := make([]nodeMetrics, len(vms))
+	var wg sync.WaitGroup
+	for i, vm := range vms {
+		wg.Add(1)
+		go func(i int, vm keptAliveVm) {
+			defer wg.Done()
+			metrics[i] = nodeMetrics{Vm: vm}
+			before, err := scrape_replica_metrics(vm.Ipv6, names)
+			if err != nil {
+				metrics[i].Error = err.Error()
+				return
+			}
+			start := time.Now()
+			time.Sleep(interval)
+			after, err := scrape_replica_metrics(vm.Ipv6, names)
+			if err != nil {
+				metrics[i].Error = err.Error()
+				return
+			}
+			secs := time.Since(start).Seconds()
+			metrics[i].Height = uint64(after[FINALIZED_HEIGHT_METRIC])
+			metrics[i].FinalizationRate = (after[FINALIZED_HEIGHT_METRIC] - before[FINALIZED_HEIGHT_METRIC]) / secs
+			metrics[i].MemoryBytes = uint64(after[RESIDENT_MEMORY_METRIC])
+			metrics[i].CyclesBurnRate = (after[CONSUMED_CYCLES_METRIC] - before[CONSUMED_CYCLES_METRIC]) / secs
+		}(i, vm)
+	}
+	wg.Wait()
+	return metrics
+}
+
+func format_bytes(bytes uint64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value, unit := float64(bytes), 0
+	for value >= 1024 && unit < len(units)-1 {
+		value, unit = value/1024, unit+1
+	}
+	return fmt.Sprintf("%.1f %s", value, units[unit])
+}
+
+// Only nodes assigned to a subnet run a replica, all VMs are scraped if the subnets of the nodes are unknown.
+func get_replica_vms(testnet *runningTestnet) []keptAliveVm {
+	vms := []keptAliveVm{}
+	for _, vm := range testnet.Vms {
+		if len(vm.Subnet) > 0 {
+			vms = append(vms, vm)
+		}
+	}
+	if len(vms) == 0 {
+		return testnet.Vms
+	}
+	return vms
+}
+
+func ValidateTestnetMetricsCommand(cfg *TestnetMetricsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if cfg.interval < time.Second {
+			return fmt.Errorf("option --interval should be at least 1s.")
+		}
+		return nil
+	}
+}
+
+func TestnetMetricsCommand(cfg *TestnetMetricsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		vms := get_replica_vms(testnet)
+		if !cfg.isJson {
+			cmd.Printf("%sScraping the metrics of %d nodes of %s twice, %s apart ...%s\n", CYAN, len(vms), testnet.Group, cfg.interval, NC)
+		}
+		metrics := get_node_metrics(vms, cfg.interval)
+		if cfg.isJson {
+			return print_json(cmd, metrics)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tSUBNET\tHEIGHT\tFINALIZATION RATE\tMEMORY\tCYCLES BURN")
+		for _, m := range metrics {
+			subnet := "-"
+			if len(m.Vm.Subnet) > 0 {
+				subnet = m.Vm.Subnet
+			}
+			if len(m.Error) > 0 {
+				fmt.Fprintf(w, "%s\t%s\tunreachable\t-\t-\t-\n", m.Vm.Name, subnet)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.2f blocks/s\t%s\t%.0f cycles/s\n", m.Vm.Name, subnet, m.Height, m.FinalizationRate, format_bytes(m.MemoryBytes), m.CyclesBurnRate)
+		}
+		return w.Flush()
+	}
+}
+
+func NewTestnetMetricsCmd() *cobra.Command {
+	var cfg = TestnetMetricsConfig{}
+	var cmd = &cobra.Command{
+		Use:     "metrics <group>",
+		Short:   "Print a snapshot of the finalized height, finalization rate, memory and cycles burn of the nodes of a testnet, without Grafana",
+		Example: "ict testnet metrics small--1690000000000\nict testnet metrics small--1690000000000 --interval 30s --json",
+		Args:    ValidateTestnetMetricsCommand(&cfg),
+		RunE:    TestnetMetricsCommand(&cfg),
+	}
+	cmd.Flags().DurationVarP(&cfg.interval, "interval", "", 10*time.Second, "Time between the two scrapes, which the rates are computed from.")
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the metrics of the nodes as JSON.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetReplaceNodeConfig struct {
+	farmCfg     FarmConfig
+	replacement string
+	isDryRun    bool
+}
+
+func get_change_subnet_membership_proposal(nns_url string, subnet string, add []string, remove []string, summary string) []string {
+	proposal := append(get_ic_admin_command(nns_url), "propose-to-change-subnet-membership", "--test-neuron-proposer", "--subnet", subnet)
+	if len(add) > 0 {
+		proposal = append(append(proposal, "--node-ids-add"), add...)
+	}
+	if len(remove) > 0 {
+		proposal = append(append(proposal, "--node-ids-remove"), remove...)
+	}
+	return append(proposal, "--summary", summary)
+}
+
+// Records the new subnet membership locally, so that e.g. `ict testnet topology` and `ict testnet upgrade` see it.
+func record_subnet_membership(testnet *runningTestnet, subnet keptAliveVm, add []string, remove []string) error {
+	return update_kept_alive_env(testnet.Group, func(env *keptAliveEnv) {
+		for i := range env.Vms {
+			vm := &env.Vms[i]
+			if any_equals(add, vm.Name) {
+				vm.Subnet, vm.SubnetType, vm.IsNns, vm.IsUnassigned = subnet.Subnet, subnet.SubnetType, subnet.IsNns, false
+			} else if any_equals(remove, vm.Name) {
+				vm.Subnet, vm.SubnetType, vm.IsNns, vm.IsUnassigned = "", "", false, true
+			}
+		}
+	})
+}
+
+func get_unassigned_nodes(testnet *runningTestnet) []keptAliveVm {
+	nodes := []keptAliveVm{}
+	for _, vm := range testnet.Vms {
+		if vm.IsUnassigned {
+			nodes = append(nodes, vm)
+		}
+	}
+	return nodes
+}
+
+func TestnetReplaceNodeCommand(cfg *TestnetReplaceNodeConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		nns_url, ok := get_nns_url(testnet)
+		if !ok {
+			return fmt.Errorf("\nThe NNS of %s is only known for testnets kept alive by ict on this machine.", testnet.Group)
+		}
+		node, err := find_testnet_vm(testnet, args[1])
+		if err != nil {
+			return err
+		}
+		if len(node.Subnet) == 0 {
+			return fmt.Errorf("\nNode %s isn't assigned to a subnet, see `ict testnet topology %s`.", node.Name, testnet.Group)
+		}
+		var replacement *keptAliveVm
+		if len(cfg.replacement) > 0 {
+			if replacement, err = find_testnet_vm(testnet, cfg.replacement); err != nil {
+				return err
+			}
+			if !replacement.IsUnassigned {
+				return fmt.Errorf("\nNode %s isn't an unassigned node, see `ict testnet topology %s`.", replacement.Name, testnet.Group)
+			}
+		} else if unassigned := get_unassigned_nodes(testnet); len(unassigned) > 0 {
+			replacement = &unassigned[0]
+		} else {
+			return fmt.Errorf("\nFarm group %s has no unassigned nodes to swap in, create the testnet with spare nodes, e.g. `unassigned_nodes: 1` in its --from-config file.", testnet.Group)
+		}
+		add, remove := []string{replacement.Name}, []string{node.Name}
+		proposal := get_change_subnet_membership_proposal(nns_url, node.Subnet, add, remove,
+			fmt.Sprintf("Replace node %s with %s in subnet %s of %s", node.Name, replacement.Name, node.Subnet, testnet.Group))
+		cmd.Printf("%sReplacing node %s with %s in subnet %s via the NNS at %s:%s\n", CYAN, node.Name, replacement.Name, node.Subnet, nns_url, NC)
+		if err := run_ic_admin_proposals(cmd, [][]string{proposal}, cfg.isDryRun); err != nil {
+			return err
+		}
+		if cfg.isDryRun {
+			return nil
+		}
+		if err := record_subnet_membership(testnet, *node, add, remove); err != nil {
+			cmd.PrintErrf("%sFailed to record the new topology: %s%s\n", RED, err, NC)
+		}
+		cmd.Printf("%sNode %s joins the subnet once it has caught up, node %s leaves it and becomes unassigned, watch their progress via:\n$ ict testnet status %s%s\n", GREEN, replacement.Name, node.Name, testnet.Group, NC)
+		return nil
+	}
+}
+
+func NewTestnetReplaceNodeCmd() *cobra.Command {
+	var cfg = TestnetReplaceNodeConfig{}
+	var cmd = &cobra.Command{
+		Use:   "replace-node <group> <node> [--with <node>]",
+		Short: "Replace a node of a subnet with an unassigned node of a testnet kept alive by ict, via an NNS proposal of the test neuron",
+		Long: `Replace a node of a subnet with an unassigned node of a testnet kept alive by ict, via an NNS proposal of the test neuron.
+
+The test driver provisions and registers all nodes of a testnet during its setup, so the replacement is one of its unassigned nodes.
+The replaced node becomes unassigned, so that it can be swapped back in.`,
+		Example: "ict testnet replace-node small--1690000000000 <node_id>\nict testnet replace-node small--1690000000000 <node_id> --with <unassigned_node_id> --dry-run",
+		Args:    cobra.ExactArgs(2),
+		RunE:    TestnetReplaceNodeCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.replacement, "with", "", "", "Name, prefix or index of the unassigned node to swap in, the first unassigned node by default.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ic-admin command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Reservations are empty Farm groups, so that everyone using Farm sees them, their user is the owner and their TTL the end of the reservation.
+var RESERVATION_GROUP_PREFIX = "reserved--"
+var RESERVATION_TEST_NAME = "reservation"
+
+// Farm group names end up in DNS names of the VMs.
+var RESERVATION_NAME_REGEX = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+type TestnetReserveConfig struct {
+	farmCfg   FarmConfig
+	duration  string
+	group     string
+	isRelease bool
+}
+
+type reservation struct {
+	Name      string    `json:"name"`
+	Owner     string    `json:"owner"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// Farm group of the testnet, which the name refers to, if any.
+	Group string `json:"group,omitempty"`
+}
+
+// Same as time.ParseDuration, which additionally accepts days, e.g. 2d or 1d12h.
+func parse_reservation_duration(value string) (time.Duration, error) {
+	days, rest := time.Duration(0), value
+	if idx := strings.Index(rest, "d"); idx >= 0 {
+		n, err := strconv.Atoi(rest[:idx])
+		if err != nil {
+			return 0, fmt.Errorf("`%s` isn't a duration, e.g. 2d or 12h", value)
+		}
+		days, rest = time.Duration(n)*24*time.Hour, rest[idx+1:]
+	}
+	if len(rest) == 0 && len(value) > 0 {
+		return days, nil
+	}
+	// The sign applies to the whole duration, e.g. 1d-2h is rejected.
+	if days != 0 && strings.ContainsAny(rest[:1], "+-") {
+		return 0, fmt.Errorf("`%s` isn't a duration, e.g. 2d or 12h", value)
+	}
+	duration, err := time.ParseDuration(rest)
+	if err != nil {
+		return 0, fmt.Errorf("`%s` isn't a duration, e.g. 2d or 12h", value)
+	}
+	if days < 0 {
+		duration = -duration
+	}
+	return days + duration, nil
+}
+
+func find_reservation(farm_base_url string, name string) (*reservation, error) {
+	groups, err := list_farm_groups(farm_base_url)
+	if err != nil {
+		return nil, fmt.Errorf("\nFailed to look up the reservation of %s on Farm: %s", name, err)
+	}
+	for _, group := range groups {
+		if group.Name == RESERVATION_GROUP_PREFIX+name && time.Now().Before(group.ExpiresAt) {
+			r := reservation{Name: name, Owner: group.Spec.Metadata.User, ExpiresAt: group.ExpiresAt}
+			if group.Spec.Metadata.TestName != RESERVATION_TEST_NAME {
+				r.Group = group.Spec.Metadata.TestName
+			}
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
+func ValidateTestnetReserveCommand(cfg *TestnetReserveConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if !RESERVATION_NAME_REGEX.MatchString(args[0]) {
+			return fmt.Errorf("name `%s` should only consist of letters, digits, '-' and '_'.", args[0])
+		}
+		if !cfg.isRelease && len(cfg.duration) == 0 {
+			return fmt.Errorf("pass the duration of the reservation via --for, e.g. --for 2d.")
+		}
+		return nil
+	}
+}
+
+func release_reservation(cmd *cobra.Command, cfg *TestnetReserveConfig, name string, existing *reservation) error {
+	if existing == nil {
+		return fmt.Errorf("\nTestnet %s isn't reserved.", name)
+	}
+	if existing.Owner != cfg.farmCfg.user {
+		return fmt.Errorf("\nTestnet %s is reserved by %s, only they can release it.", name, existing.Owner)
+	}
+	if err := delete_farm_group(cfg.farmCfg.get_base_url(), RESERVATION_GROUP_PREFIX+name); err != nil {
+		return fmt.Errorf("\nFailed to release the reservation of %s: %s", name, err)
+	}
+	cmd.Printf("%sReleased the reservation of %s.%s\n", GREEN, name, NC)
+	return nil
+}
+
+func TestnetReserveCommand(cfg *TestnetReserveConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		existing, err := find_reservation(cfg.farmCfg.get_base_url(), name)
+		if err != nil {
+			return err
+		}
+		if cfg.isRelease {
+			return release_reservation(cmd, cfg, name, existing)
+		}
+		duration, err := parse_reservation_duration(cfg.duration)
+		if err != nil {
+			return fmt.Errorf("\nOption --for: %s", err)
+		}
+		if duration <= 0 {
+			return fmt.Errorf("\nOption --for should be a positive duration, got `%s`.", cfg.duration)
+		}
+		if existing != nil && existing.Owner != cfg.farmCfg.user {
+			return fmt.Errorf("\nTestnet %s is reserved by %s until %s.", name, existing.Owner, existing.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+		}
+		// The testnet has to live as long as its reservation.
+		if len(cfg.group) > 0 {
+			if existing != nil && len(existing.Group) > 0 && existing.Group != cfg.group {
+				return fmt.Errorf("\nTestnet %s is reserved for Farm group `%s`, release it first via:\n$ ict testnet reserve %s --release", name, existing.Group, name)
+			}
+			if err := set_farm_group_ttl(cfg.farmCfg.get_base_url(), cfg.group, duration); err != nil {
+				return fmt.Errorf("\nFailed to extend the lifetime of Farm group %s: %s", cfg.group, err)
+			}
+		} else if existing != nil && len(existing.Group) > 0 {
+			if err := set_farm_group_ttl(cfg.farmCfg.get_base_url(), existing.Group, duration); err != nil {
+				return fmt.Errorf("\nFailed to extend the lifetime of Farm group %s: %s", existing.Group, err)
+			}
+		}
+		// The group is part of the metadata of the reservation, which Farm only sets on creation, so a reservation without one is recreated.
+		if existing != nil && len(existing.Group) == 0 && len(cfg.group) > 0 {
+			if err := delete_farm_group(cfg.farmCfg.get_base_url(), RESERVATION_GROUP_PREFIX+name); err != nil {
+				return fmt.Errorf("\nFailed to attach Farm group %s to the reservation of %s: %s", cfg.group, name, err)
+			}
+			existing = nil
+		}
+		if existing != nil {
+			err = set_farm_group_ttl(cfg.farmCfg.get_base_url(), RESERVATION_GROUP_PREFIX+name, duration)
+		} else {
+			test_name := RESERVATION_TEST_NAME
+			if len(cfg.group) > 0 {
+				test_name = cfg.group
+			}
+			err = create_farm_group(cfg.farmCfg.get_base_url(), RESERVATION_GROUP_PREFIX+name, duration, cfg.farmCfg.user, test_name)
+		}
+		if err != nil {
+			return fmt.Errorf("\nFailed to reserve %s: %s", name, err)
+		}
+		cmd.Printf("%sReserved %s for %s until %s, others can look it up via:\n$ ict testnet who %s%s\n", GREEN, name, cfg.farmCfg.user, time.Now().Add(duration).Format("2006-01-02 15:04:05"), name, NC)
+		return nil
+	}
+}
+
+func NewTestnetReserveCmd() *cobra.Command {
+	var cfg = TestnetReserveConfig{}
+	var cmd = &cobra.Command{
+		Use:   "reserve <name> --for <duration> [--group <group>]",
+		Short: "Reserve a named, shared testnet on Farm, so that others see who is using it",
+		Long: `Reserve a named, shared testnet on Farm, so that others see who is using it.
+
+Reservations are recorded centrally as empty Farm groups named ` + RESERVATION_GROUP_PREFIX + `<name>, owned by the user and expiring at the end of the reservation.
+Reserving a name again extends the own reservation, names reserved by others are refused.
+With --group, the name refers to the Farm group of a running testnet, whose lifetime is extended along with the reservation.`,
+		Example: "ict testnet reserve perf-env --for 2d --group small--1690000000000\nict testnet reserve perf-env --for 12h\nict testnet reserve perf-env --release",
+		Args:    ValidateTestnetReserveCommand(&cfg),
+		RunE:    TestnetReserveCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.duration, "for", "", "", "Duration of the reservation from now on, e.g. 2d, 1d12h or 90m.")
+	cmd.Flags().StringVarP(&cfg.group, "group", "", "", "Farm group of the testnet the name refers to.")
+	cmd.Flags().BoolVarP(&cfg.isRelease, "release", "", false, "Release the own reservation of the name.")
+	cmd.MarkFlagsMutuallyExclusive("for", "release")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,37 @@
+package cmd_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dfinity/ic/rs/tests/ict/cmd"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseReservationDuration(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"2d", 48 * time.Hour},
+		{"1d12h", 36 * time.Hour},
+		{"0d90m", 90 * time.Minute},
+		{"90m", 90 * time.Minute},
+		{"-1d", -24 * time.Hour},
+		{"-1d12h", -36 * time.Hour},
+	}
+	for _, test := range tests {
+		actual, err := cmd.ParseReservationDuration(test.value)
+
+		assert.Nil(t, err, test.value)
+		assert.Equal(t, test.expected, actual, test.value)
+	}
+}
+
+func Test_ParseReservationDurationErrors(t *testing.T) {
+	for _, value := range []string{"", "2h30d", "d", "1.5d", "1d-2h", "1dfoo", "2 days"} {
+		_, err := cmd.ParseReservationDuration(value)
+
+		assert.EqualError(t, err, "`"+value+"` isn't a duration, e.g. 2d or 12h", value)
+	}
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetScaleConfig struct {
+	farmCfg  FarmConfig
+	subnet   string
+	add      int
+	remove   int
+	isDryRun bool
+}
+
+// Subnets in the order their nodes were created, i.e. the order of the topology, starting with the NNS subnet.
+func find_testnet_subnet(testnet *runningTestnet, subnet string) (keptAliveVm, []keptAliveVm, error) {
+	subnets, nodes := []keptAliveVm{}, map[string][]keptAliveVm{}
+	for _, vm := range testnet.Vms {
+		if len(vm.Subnet) == 0 {
+			continue
+		}
+		if _, ok := nodes[vm.Subnet]; !ok {
+			subnets = append(subnets, vm)
+		}
+		nodes[vm.Subnet] = append(nodes[vm.Subnet], vm)
+	}
+	if len(subnets) == 0 {
+		return keptAliveVm{}, nil, fmt.Errorf("\nNo subnets of %s are known.", testnet.Group)
+	}
+	if idx, err := strconv.Atoi(subnet); err == nil {
+		if idx < 0 || idx >= len(subnets) {
+			return keptAliveVm{}, nil, fmt.Errorf("\nSubnet index %d is out of range, Farm group %s has %d subnets.", idx, testnet.Group, len(subnets))
+		}
+		return subnets[idx], nodes[subnets[idx].Subnet], nil
+	}
+	for _, s := range subnets {
+		if strings.HasPrefix(s.Subnet, subnet) {
+			return s, nodes[s.Subnet], nil
+		}
+	}
+	return keptAliveVm{}, nil, fmt.Errorf("\nSubnet `%s` wasn't found, see `ict testnet topology %s`.", subnet, testnet.Group)
+}
+
+func get_vm_names(vms []keptAliveVm) []string {
+	names := []string{}
+	for _, vm := range vms {
+		names = append(names, vm.Name)
+	}
+	return names
+}
+
+func ValidateTestnetScaleCommand(cfg *TestnetScaleConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if cfg.add < 0 || cfg.remove < 0 {
+			return fmt.Errorf("options --add and --remove should be positive")
+		}
+		if (cfg.add > 0) == (cfg.remove > 0) {
+			return fmt.Errorf("exactly one of the options --add and --remove should be set")
+		}
+		return nil
+	}
+}
+
+func TestnetScaleCommand(cfg *TestnetScaleConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		nns_url, ok := get_nns_url(testnet)
+		if !ok {
+			return fmt.Errorf("\nThe NNS of %s is only known for testnets kept alive by ict on this machine.", testnet.Group)
+		}
+		subnet, nodes, err := find_testnet_subnet(testnet, cfg.subnet)
+		if err != nil {
+			return err
+		}
+		add, remove := []string{}, []string{}
+		if cfg.add > 0 {
+			unassigned := get_unassigned_nodes(testnet)
+			if len(unassigned) < cfg.add {
+				return fmt.Errorf("\nFarm group %s has %d unassigned nodes, create the testnet with more spare nodes, e.g. via `unassigned_nodes` of its --from-config file.", testnet.Group, len(unassigned))
+			}
+			add = get_vm_names(unassigned[:cfg.add])
+		} else {
+			if len(nodes) <= cfg.remove {
+				return fmt.Errorf("\nSubnet %s has %d nodes, at least one of them has to remain.", subnet.Subnet, len(nodes))
+			}
+			remove = get_vm_names(nodes[len(nodes)-cfg.remove:])
+		}
+		size := len(nodes) + len(add) - len(remove)
+		proposal := get_change_subnet_membership_proposal(nns_url, subnet.Subnet, add, remove,
+			fmt.Sprintf("Scale subnet %s of %s from %d to %d nodes", subnet.Subnet, testnet.Group, len(nodes), size))
+		cmd.Printf("%sScaling subnet %s from %d to %d nodes via the NNS at %s:%s\n", CYAN, subnet.Subnet, len(nodes), size, nns_url, NC)
+		if err := run_ic_admin_proposals(cmd, [][]string{proposal}, cfg.isDryRun); err != nil {
+			return err
+		}
+		if cfg.isDryRun {
+			return nil
+		}
+		if err := record_subnet_membership(testnet, subnet, add, remove); err != nil {
+			cmd.PrintErrf("%sFailed to record the new topology: %s%s\n", RED, err, NC)
+		}
+		cmd.Printf("%sAdded nodes join the subnet once they have caught up, removed nodes become unassigned, watch their progress via:\n$ ict testnet status %s%s\n", GREEN, testnet.Group, NC)
+		return nil
+	}
+}
+
+func NewTestnetScaleCmd() *cobra.Command {
+	var cfg = TestnetScaleConfig{}
+	var cmd = &cobra.Command{
+		Use:   "scale <group> --subnet <index>|<id> --add <n>|--remove <n>",
+		Short: "Grow or shrink a subnet of a testnet kept alive by ict, via an NNS proposal of the test neuron",
+		Long: `Grow or shrink a subnet of a testnet kept alive by ict, via an NNS proposal of the test neuron.
+
+Nodes are added from the unassigned nodes of the testnet, as the test driver provisions and registers all of them during its setup.
+Removed nodes become unassigned, so that they can be added again.`,
+		Example: "ict testnet scale small--1690000000000 --subnet 1 --add 1\nict testnet scale small--1690000000000 --subnet <subnet_id> --remove 2 --dry-run",
+		Args:    ValidateTestnetScaleCommand(&cfg),
+		RunE:    TestnetScaleCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.subnet, "subnet", "", "", "Index or id (prefix) of the subnet to scale, see `ict testnet topology`.")
+	cmd.MarkFlagRequired("subnet")
+	cmd.Flags().IntVarP(&cfg.add, "add", "", 0, "Number of unassigned nodes to add to the subnet.")
+	cmd.Flags().IntVarP(&cfg.remove, "remove", "", 0, "Number of nodes to remove from the subnet, the most recently created ones first.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ic-admin command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
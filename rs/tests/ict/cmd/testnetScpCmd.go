@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetScpConfig struct {
+	farmCfg     FarmConfig
+	isRecursive bool
+	isDryRun    bool
+}
+
+// Same as scp, a path is remote if it has a colon before any slash, i.e. <node>:<path>.
+func split_remote_path(path string) (string, string, bool) {
+	node, remote_path, found := strings.Cut(path, ":")
+	if !found || strings.Contains(node, "/") {
+		return "", "", false
+	}
+	return node, remote_path, true
+}
+
+func ValidateTestnetScpCommand(cfg *TestnetScpConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(3)(cmd, args); err != nil {
+			return err
+		}
+		_, _, is_src_remote := split_remote_path(args[1])
+		_, _, is_dst_remote := split_remote_path(args[2])
+		if is_src_remote == is_dst_remote {
+			return fmt.Errorf("exactly one of the source and the destination should be remote, i.e. <node>:<path>.")
+		}
+		return nil
+	}
+}
+
+func TestnetScpCommand(cfg *TestnetScpConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		paths := args[1:]
+		var vm *keptAliveVm
+		direction := "from"
+		for i, path := range paths {
+			if node, remote_path, ok := split_remote_path(path); ok {
+				if vm, err = find_testnet_vm(testnet, node); err != nil {
+					return err
+				}
+				// IPv6 addresses need brackets, so that scp doesn't mistake their colons for the path separator.
+				paths[i] = fmt.Sprintf("%s@[%s]:%s", TESTNET_SSH_USERNAME, vm.Ipv6, remote_path)
+				if i > 0 {
+					direction = "to"
+				}
+			}
+		}
+		if _, ok := get_testnet_ssh_key(testnet); !ok {
+			cmd.Printf("%sThe SSH key of the test driver is only known for testnets kept alive by ict on this machine, falling back to your own keys.%s\n", CYAN, NC)
+		}
+		command := append([]string{"scp"}, get_testnet_ssh_options(testnet)...)
+		if cfg.isRecursive {
+			command = append(command, "-r")
+		}
+		command = append(command, paths...)
+		cmd.Printf("%sCopying %s %s of %s:\n$ %s%s\n", CYAN, direction, vm.Name, testnet.Group, shell_quote(command), NC)
+		if cfg.isDryRun {
+			return nil
+		}
+		scp, err := exec.LookPath("scp")
+		if err != nil {
+			return fmt.Errorf("\nscp wasn't found on PATH.")
+		}
+		trace_command(command)
+		return syscall.Exec(scp, command, os.Environ())
+	}
+}
+
+func NewTestnetScpCmd() *cobra.Command {
+	var cfg = TestnetScpConfig{}
+	var cmd = &cobra.Command{
+		Use:     "scp <group> <source> <destination>",
+		Short:   "Copy files from or to a node of a testnet running on Farm, remote paths are <node_id>|<index>:<path>",
+		Example: "ict testnet scp small--1690000000000 0:/var/lib/ic/data/ic_registry_local_store ./registry -r\nict testnet scp small--1690000000000 ./ic.json5 p6i3o:/tmp/ic.json5",
+		Args:    ValidateTestnetScpCommand(&cfg),
+		RunE:    TestnetScpCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isRecursive, "recursive", "r", false, "Copy directories recursively.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the scp command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Generic testnet, which sets itself up from the config passed in TESTNET_CONFIG_ENV_VAR, see from_config.rs in rs/tests/testing_verification/testnets.
+var FROM_CONFIG_TESTNET_TARGET = "//rs/tests/testing_verification/testnets:from_config"
+var TESTNET_CONFIG_ENV_VAR = "TESTNET_CONFIG"
+
+var SUBNET_TYPES = []string{"system", "application", "verified_application"}
+var SUBNET_FEATURES = []string{"canister_sandboxing", "http_requests"}
+
+// Declarative description of a testnet, read from YAML and passed to the testnet target as JSON.
+type testnetSpec struct {
+	Subnets         []subnetSpec `yaml:"subnets" json:"subnets"`
+	UnassignedNodes int          `yaml:"unassigned_nodes" json:"unassigned_nodes"`
+	BoundaryNodes   int          `yaml:"boundary_nodes" json:"boundary_nodes"`
+	// Install the NNS canisters built from HEAD and the Internet Identity test canister on the root subnet.
+	Nns bool `yaml:"nns" json:"nns"`
+	Ii  bool `yaml:"ii" json:"ii"`
+	// Accounts the ICP ledger is initialized with, they require the NNS.
+	Prefund    []prefundAccount `yaml:"prefund" json:"prefund,omitempty"`
+	Prometheus *bool            `yaml:"prometheus" json:"prometheus,omitempty"`
+	Canisters  []canisterSpec   `yaml:"canisters" json:"canisters,omitempty"`
+}
+
+type subnetSpec struct {
+	Type              string   `yaml:"type" json:"type"`
+	Nodes             int      `yaml:"nodes" json:"nodes"`
+	Features          []string `yaml:"features" json:"features,omitempty"`
+	DkgIntervalLength *uint64  `yaml:"dkg_interval_length" json:"dkg_interval_length,omitempty"`
+}
+
+type canisterSpec struct {
+	Name string `yaml:"name" json:"name"`
+	// Path of the .wasm or .wat module, relative paths are relative to the config file.
+	Wasm   string `yaml:"wasm" json:"wasm"`
+	Subnet int    `yaml:"subnet" json:"subnet"`
+	// Hex-encoded init argument, e.g. the output of `didc encode`.
+	Arg string `yaml:"arg" json:"arg,omitempty"`
+}
+
+type prefundAccount struct {
+	Principal string `yaml:"principal" json:"principal"`
+	// Amount of ICP, with up to 8 decimals, it is passed to the testnet target in e8s.
+	Amount string `yaml:"amount" json:"-"`
+	E8s    uint64 `yaml:"-" json:"e8s"`
+}
+
+// Same shape as the small testnet, except for its boundary node, used when only flags like --with-nns are given.
+func get_default_testnet_spec() *testnetSpec {
+	return &testnetSpec{
+		Subnets:         []subnetSpec{{Type: "system", Nodes: 1}, {Type: "application", Nodes: 1}},
+		UnassignedNodes: 1,
+	}
+}
+
+func load_testnet_spec(path string) (*testnetSpec, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("\nFailed to read testnet config: %s", err)
+	}
+	spec := testnetSpec{}
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("\nFailed to parse testnet config %s: %s", path, err)
+	}
+	// The testnet target runs in a different directory.
+	for i := range spec.Canisters {
+		canister := &spec.Canisters[i]
+		if len(canister.Wasm) > 0 && !filepath.IsAbs(canister.Wasm) {
+			if canister.Wasm, err = filepath.Abs(filepath.Join(filepath.Dir(path), canister.Wasm)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// Validated once the flags are applied, so that the testnet target doesn't fail only after allocating the VMs.
+func validate_testnet_spec(spec *testnetSpec) error {
+	if len(spec.Subnets) == 0 {
+		return fmt.Errorf("at least one subnet is required")
+	}
+	for i, subnet := range spec.Subnets {
+		if !any_equals(SUBNET_TYPES, subnet.Type) {
+			return fmt.Errorf("subnet %d has type `%s`, expected one of %v", i, subnet.Type, SUBNET_TYPES)
+		}
+		if subnet.Nodes < 1 {
+			return fmt.Errorf("subnet %d should have at least one node", i)
+		}
+		for _, feature := range subnet.Features {
+			if !any_equals(SUBNET_FEATURES, feature) {
+				return fmt.Errorf("subnet %d has feature `%s`, expected one of %v", i, feature, SUBNET_FEATURES)
+			}
+		}
+	}
+	if spec.UnassignedNodes < 0 {
+		return fmt.Errorf("unassigned_nodes should be >= 0")
+	}
+	if spec.BoundaryNodes < 0 {
+		return fmt.Errorf("boundary_nodes should be >= 0")
+	}
+	// The first subnet is the root subnet, which the NNS canisters and II are installed on.
+	if (spec.Nns || spec.Ii) && spec.Subnets[0].Type != "system" {
+		return fmt.Errorf("the NNS and II are installed on subnet 0, which should be of type `system`")
+	}
+	for i := range spec.Prefund {
+		account := &spec.Prefund[i]
+		if err := validate_principal(account.Principal); err != nil {
+			return fmt.Errorf("prefunded account %d: %s", i, err)
+		}
+		e8s, err := parse_icp_amount(account.Amount)
+		if err != nil {
+			return fmt.Errorf("prefunded account %s: %s", account.Principal, err)
+		}
+		account.E8s = e8s
+	}
+	if len(spec.Prefund) > 0 && !spec.Nns {
+		return fmt.Errorf("prefunded accounts require the NNS, i.e. nns: true or --with-nns")
+	}
+	for i, canister := range spec.Canisters {
+		if len(canister.Name) == 0 {
+			return fmt.Errorf("canister %d has no name", i)
+		}
+		if canister.Subnet < 0 || canister.Subnet >= len(spec.Subnets) {
+			return fmt.Errorf("canister %s is installed on subnet %d, but there are only %d subnets", canister.Name, canister.Subnet, len(spec.Subnets))
+		}
+		if _, err := os.Stat(canister.Wasm); err != nil {
+			return fmt.Errorf("wasm of canister %s: %s", canister.Name, err)
+		}
+	}
+	return nil
+}
+
+// Textual principals are the base32 encoding of a CRC32 checksum followed by the principal, in groups of 5 characters.
+func validate_principal(text string) error {
+	data, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(strings.ToUpper(strings.ReplaceAll(text, "-", "")))
+	if err != nil || len(data) < 4 {
+		return fmt.Errorf("`%s` isn't a valid principal", text)
+	}
+	if binary.BigEndian.Uint32(data[:4]) != crc32.ChecksumIEEE(data[4:]) {
+		return fmt.Errorf("`%s` isn't a valid principal, its checksum doesn't match", text)
+	}
+	return nil
+}
+
+func parse_icp_amount(amount string) (uint64, error) {
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > 8 {
+		return 0, fmt.Errorf("amount `%s` has more than 8 decimals", amount)
+	}
+	e8s, err := strconv.ParseUint(whole+fraction+strings.Repeat("0", 8-len(fraction)), 10, 64)
+	if err != nil || len(whole) == 0 {
+		return 0, fmt.Errorf("amount `%s` isn't a number of ICP", amount)
+	}
+	return e8s, nil
+}
+
+// Parses a --prefund value, i.e. <principal>=<amount>.
+func parse_prefund_flag(value string) (prefundAccount, error) {
+	principal, amount, ok := strings.Cut(value, "=")
+	if !ok {
+		return prefundAccount{}, fmt.Errorf("option --prefund should be <principal>=<amount>, got `%s`.", value)
+	}
+	return prefundAccount{Principal: principal, Amount: amount}, nil
+}
+
+func get_testnet_spec_flag(spec *testnetSpec) (string, error) {
+	content, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("--test_env=%s=%s", TESTNET_CONFIG_ENV_VAR, content), nil
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetSshConfig struct {
+	farmCfg  FarmConfig
+	isDryRun bool
+}
+
+func TestnetSshCommand(cfg *TestnetSshConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, remote_command := split_bazel_args(cmd, args)
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		node := ""
+		if len(args) > 1 {
+			node = args[1]
+		}
+		vm, err := find_testnet_vm(testnet, node)
+		if err != nil {
+			return err
+		}
+		if _, ok := get_testnet_ssh_key(testnet); !ok {
+			cmd.Printf("%sThe SSH key of the test driver is only known for testnets kept alive by ict on this machine, falling back to your own keys.%s\n", CYAN, NC)
+		}
+		command := append([]string{"ssh"}, get_testnet_ssh_options(testnet)...)
+		command = append(command, fmt.Sprintf("%s@%s", TESTNET_SSH_USERNAME, vm.Ipv6))
+		command = append(command, remote_command...)
+		cmd.Printf("%sConnecting to %s of %s:\n$ %s%s\n", CYAN, vm.Name, testnet.Group, shell_quote(command), NC)
+		if cfg.isDryRun {
+			return nil
+		}
+		ssh, err := exec.LookPath("ssh")
+		if err != nil {
+			return fmt.Errorf("\nssh wasn't found on PATH.")
+		}
+		trace_command(command)
+		return syscall.Exec(ssh, command, os.Environ())
+	}
+}
+
+func NewTestnetSshCmd() *cobra.Command {
+	var cfg = TestnetSshConfig{}
+	var cmd = &cobra.Command{
+		Use:     "ssh <group> [<node_id>|<index>] [-- <command>]",
+		Short:   "SSH into a node of a testnet running on Farm, with the admin key of the test driver",
+		Example: "ict testnet ssh small--1690000000000\nict testnet ssh small--1690000000000 1\nict testnet ssh small--1690000000000 p6i3o -- journalctl -u ic-replica -f",
+		Args:    positional_args(cobra.MinimumNArgs(1), cobra.MaximumNArgs(2)),
+		RunE:    TestnetSshCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ssh command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetStatusConfig struct {
+	farmCfg FarmConfig
+	isJson  bool
+}
+
+type nodeStatus struct {
+	Vm     keptAliveVm    `json:"vm"`
+	Status *replicaStatus `json:"status,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+func get_node_statuses(vms []keptAliveVm) []nodeStatus {
+	statuses := make([]nodeStatus, len(vms))
+	var wg sync.WaitGroup
+	for i, vm := range vms {
+		wg.Add(1)
+		go func(i int, vm keptAliveVm) {
+			defer wg.Done()
+			statuses[i] = nodeStatus{Vm: vm}
+			if status, err := get_replica_status(vm.Ipv6); err == nil {
+				statuses[i].Status = status
+			} else {
+				statuses[i].Error = err.Error()
+			}
+		}(i, vm)
+	}
+	wg.Wait()
+	// Nodes of the same subnet are listed together, unassigned nodes and other VMs come last.
+	sort.SliceStable(statuses, func(i, j int) bool {
+		if len(statuses[i].Vm.Subnet) == 0 || len(statuses[j].Vm.Subnet) == 0 {
+			return len(statuses[i].Vm.Subnet) > 0
+		}
+		return statuses[i].Vm.Subnet < statuses[j].Vm.Subnet
+	})
+	return statuses
+}
+
+func TestnetStatusCommand(cfg *TestnetStatusConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		statuses := get_node_statuses(testnet.Vms)
+		if cfg.isJson {
+			return print_json(cmd, struct {
+				Testnet *runningTestnet `json:"testnet"`
+				Nodes   []nodeStatus    `json:"nodes"`
+			}{testnet, statuses})
+		}
+		cmd.Printf("%sFarm group: %s\nTest: %s\nExpires at: %s (in %s)%s\n", CYAN, testnet.Group, testnet.Test, testnet.ExpiresAt.Local().Format(time.RFC1123), time.Until(testnet.ExpiresAt).Round(time.Minute), NC)
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NODE\tSUBNET\tIPV6\tHEALTH\tREPLICA VERSION")
+		unhealthy := 0
+		for _, s := range statuses {
+			subnet := "-"
+			if len(s.Vm.Subnet) > 0 {
+				subnet = fmt.Sprintf("%s (%s)", s.Vm.Subnet, s.Vm.SubnetType)
+			}
+			health, version := "unreachable", "-"
+			if s.Status != nil {
+				health, version = s.Status.ReplicaHealthStatus, s.Status.ImplVersion
+			}
+			if health != "healthy" {
+				unhealthy++
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Vm.Name, subnet, s.Vm.Ipv6, health, version)
+		}
+		w.Flush()
+		if len(testnet.SetupDir) == 0 && len(statuses) > 0 {
+			cmd.Printf("%sSubnets are only known for testnets kept alive by ict on this machine.%s\n", CYAN, NC)
+		}
+		if unhealthy > 0 {
+			cmd.Printf("%s%d of %d nodes aren't healthy, VMs not running a replica are always unreachable.%s\n", RED, unhealthy, len(statuses), NC)
+		} else {
+			cmd.Printf("%sAll %d nodes are healthy.%s\n", GREEN, len(statuses), NC)
+		}
+		return nil
+	}
+}
+
+func NewTestnetStatusCmd() *cobra.Command {
+	var cfg = TestnetStatusConfig{}
+	var cmd = &cobra.Command{
+		Use:     "status <group>",
+		Short:   "Print subnets, health and replica version of the nodes of a testnet running on Farm, and its time to expiry",
+		Example: "ict testnet status small--1690000000000\nict testnet status small--1690000000000 --json",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetStatusCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the testnet and the status of its nodes as JSON.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetTopologyConfig struct {
+	farmCfg FarmConfig
+	isJson  bool
+}
+
+type topologyNode struct {
+	Id   string `json:"id"`
+	Ipv6 string `json:"ipv6"`
+	Url  string `json:"url"`
+}
+
+type topologySubnet struct {
+	Id    string         `json:"id"`
+	Type  string         `json:"type"`
+	IsNns bool           `json:"nns"`
+	Nodes []topologyNode `json:"nodes"`
+}
+
+type testnetTopology struct {
+	Group           string           `json:"group"`
+	NnsUrl          string           `json:"nns_url,omitempty"`
+	Subnets         []topologySubnet `json:"subnets"`
+	UnassignedNodes []topologyNode   `json:"unassigned_nodes"`
+	BoundaryNodes   []boundaryNode   `json:"boundary_nodes"`
+	// VMs not running a replica, e.g. the Prometheus VM, or all VMs if the topology isn't known.
+	OtherVms []keptAliveVm `json:"other_vms"`
+}
+
+func get_testnet_topology(testnet *runningTestnet) testnetTopology {
+	topology := testnetTopology{Group: testnet.Group, Subnets: []topologySubnet{}, UnassignedNodes: []topologyNode{}, BoundaryNodes: get_boundary_nodes(testnet), OtherVms: []keptAliveVm{}}
+	topology.NnsUrl, _ = get_nns_url(testnet)
+	boundary_nodes := map[string]bool{}
+	for _, bn := range topology.BoundaryNodes {
+		boundary_nodes[bn.Name] = true
+	}
+	subnets := map[string]int{}
+	for _, vm := range testnet.Vms {
+		node := topologyNode{Id: vm.Name, Ipv6: vm.Ipv6, Url: get_replica_url(vm)}
+		if len(vm.Subnet) > 0 {
+			idx, ok := subnets[vm.Subnet]
+			if !ok {
+				idx = len(topology.Subnets)
+				subnets[vm.Subnet] = idx
+				topology.Subnets = append(topology.Subnets, topologySubnet{Id: vm.Subnet, Type: vm.SubnetType, IsNns: vm.IsNns, Nodes: []topologyNode{}})
+			}
+			topology.Subnets[idx].Nodes = append(topology.Subnets[idx].Nodes, node)
+		} else if vm.IsUnassigned {
+			topology.UnassignedNodes = append(topology.UnassignedNodes, node)
+		} else if !boundary_nodes[vm.Name] {
+			topology.OtherVms = append(topology.OtherVms, vm)
+		}
+	}
+	return topology
+}
+
+func print_testnet_topology(cmd *cobra.Command, topology testnetTopology) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Farm group: %s\n", topology.Group)
+	if len(topology.NnsUrl) > 0 {
+		fmt.Fprintf(&sb, "NNS URL: %s\n", topology.NnsUrl)
+	}
+	for _, subnet := range topology.Subnets {
+		nns := ""
+		if subnet.IsNns {
+			nns = ", NNS"
+		}
+		fmt.Fprintf(&sb, "Subnet %s (%s%s):\n", subnet.Id, subnet.Type, nns)
+		for _, node := range subnet.Nodes {
+			fmt.Fprintf(&sb, "  %s\t%s\n", node.Id, node.Ipv6)
+		}
+	}
+	if len(topology.UnassignedNodes) > 0 {
+		fmt.Fprintf(&sb, "Unassigned nodes:\n")
+		for _, node := range topology.UnassignedNodes {
+			fmt.Fprintf(&sb, "  %s\t%s\n", node.Id, node.Ipv6)
+		}
+	}
+	if len(topology.BoundaryNodes) > 0 {
+		fmt.Fprintf(&sb, "Boundary nodes:\n")
+		for _, bn := range topology.BoundaryNodes {
+			fmt.Fprintf(&sb, "  %s\t%s\t%s\n", bn.Name, bn.Ipv6, bn.Url)
+		}
+	}
+	if len(topology.OtherVms) > 0 {
+		fmt.Fprintf(&sb, "Other VMs:\n")
+		for _, vm := range topology.OtherVms {
+			fmt.Fprintf(&sb, "  %s\t%s\n", vm.Name, vm.Ipv6)
+		}
+	}
+	cmd.Print(sb.String())
+}
+
+func TestnetTopologyCommand(cfg *TestnetTopologyConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		topology := get_testnet_topology(testnet)
+		if cfg.isJson {
+			return print_json(cmd, topology)
+		}
+		print_testnet_topology(cmd, topology)
+		if len(topology.Subnets) == 0 {
+			cmd.Printf("%sSubnets and boundary nodes are only known for testnets kept alive by ict on this machine.%s\n", CYAN, NC)
+		}
+		return nil
+	}
+}
+
+func NewTestnetTopologyCmd() *cobra.Command {
+	var cfg = TestnetTopologyConfig{}
+	var cmd = &cobra.Command{
+		Use:     "topology <group>",
+		Short:   "Print subnets, nodes, boundary nodes and the NNS URL of a testnet running on Farm",
+		Example: "ict testnet topology small--1690000000000\nict testnet topology small--1690000000000 --json | jq -r .nns_url",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetTopologyCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the topology as JSON, e.g. for scripts or to point dfx at the NNS URL.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetTunnelConfig struct {
+	farmCfg   FarmConfig
+	via       string
+	socksPort int
+	port      int
+	isDryRun  bool
+}
+
+type tunnelForward struct {
+	Name      string
+	LocalPort int
+	Vm        keptAliveVm
+}
+
+// Prometheus and Grafana share the IPv6 of the Prometheus VM and are told apart by their DNS names, so they are
+// only reachable via the SOCKS proxy, while the public API of the replicas is forwarded to consecutive local ports.
+func get_tunnel_forwards(testnet *runningTestnet, first_port int) []tunnelForward {
+	forwards := []tunnelForward{}
+	for _, vm := range get_replica_vms(testnet) {
+		if vm.Name == "prometheus" {
+			continue
+		}
+		name := vm.Name
+		if vm.IsNns {
+			name += " (NNS)"
+		}
+		forwards = append(forwards, tunnelForward{Name: name, LocalPort: first_port + len(forwards), Vm: vm})
+	}
+	return forwards
+}
+
+// Without --via, ssh connects to a VM of the testnet itself, which requires IPv6 on this machine, but not in the browser.
+func get_tunnel_command(testnet *runningTestnet, cfg *TestnetTunnelConfig, forwards []tunnelForward) ([]string, error) {
+	command := []string{"ssh", "-N", "-o", "ExitOnForwardFailure=yes", "-D", fmt.Sprintf("localhost:%d", cfg.socksPort)}
+	for _, f := range forwards {
+		command = append(command, "-L", fmt.Sprintf("localhost:%d:[%s]:%d", f.LocalPort, f.Vm.Ipv6, REPLICA_PUBLIC_API_PORT))
+	}
+	if len(cfg.via) > 0 {
+		return append(command, cfg.via), nil
+	}
+	vm, err := find_testnet_vm(testnet, "")
+	if err != nil {
+		return nil, err
+	}
+	command = append(command, get_testnet_ssh_options(testnet)...)
+	return append(command, fmt.Sprintf("%s@%s", TESTNET_SSH_USERNAME, vm.Ipv6)), nil
+}
+
+func ValidateTestnetTunnelCommand(cfg *TestnetTunnelConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := cobra.ExactArgs(1)(cmd, args); err != nil {
+			return err
+		}
+		if cfg.socksPort <= 0 || cfg.socksPort > 65535 || cfg.port <= 0 || cfg.port > 65535 {
+			return fmt.Errorf("options --socks and --port should be valid TCP ports.")
+		}
+		return nil
+	}
+}
+
+func TestnetTunnelCommand(cfg *TestnetTunnelConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		forwards := get_tunnel_forwards(testnet, cfg.port)
+		command, err := get_tunnel_command(testnet, cfg, forwards)
+		if err != nil {
+			return err
+		}
+		cmd.Printf("%s$ %s%s\n", CYAN, shell_quote(command), NC)
+		cmd.Printf("%sSOCKS proxy at localhost:%d, resolving DNS names remotely, e.g.:\n$ chromium --proxy-server=socks5://localhost:%d%s\n", GREEN, cfg.socksPort, cfg.socksPort, NC)
+		if _, grafana_url, ok := get_prometheus_urls(testnet); ok {
+			cmd.Printf("Grafana via the proxy: %s\n", get_grafana_dashboard_url(grafana_url, REPLICA_DASHBOARDS[0]))
+		}
+		for _, f := range forwards {
+			cmd.Printf("%s: http://localhost:%d\n", f.Name, f.LocalPort)
+		}
+		if cfg.isDryRun {
+			return nil
+		}
+		cmd.Printf("%sPress Ctrl-C to close the tunnel.%s\n", CYAN, NC)
+		ssh, err := exec.LookPath("ssh")
+		if err != nil {
+			return fmt.Errorf("\nssh wasn't found on PATH.")
+		}
+		trace_command(command)
+		return syscall.Exec(ssh, command, os.Environ())
+	}
+}
+
+func NewTestnetTunnelCmd() *cobra.Command {
+	var cfg = TestnetTunnelConfig{}
+	var cmd = &cobra.Command{
+		Use:   "tunnel <group> [--via <[user@]host>]",
+		Short: "Open a SOCKS proxy and local port-forwards to the IPv6-only services of a testnet running on Farm, for browsers without IPv6",
+		Long: `Open a SOCKS proxy and local port-forwards to the IPv6-only services of a testnet running on Farm, for browsers without IPv6.
+
+The public API of each replica is forwarded to consecutive local ports from --port on.
+Grafana, Prometheus and the boundary nodes are reached by their DNS names via the SOCKS proxy, with DNS resolved on the remote end.
+Without --via, ssh connects to the first VM of the testnet, so this machine needs IPv6, otherwise pass a host with IPv6 via --via, e.g. a devenv.`,
+		Example: "ict testnet tunnel small--1690000000000\nict testnet tunnel small--1690000000000 --via devenv --socks 1081\nict testnet tunnel small--1690000000000 --dry-run",
+		Args:    ValidateTestnetTunnelCommand(&cfg),
+		RunE:    TestnetTunnelCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.via, "via", "", "", "SSH into this host with IPv6 instead of a VM of the testnet, e.g. user@devenv.")
+	cmd.Flags().IntVarP(&cfg.socksPort, "socks", "", 1080, "Local port of the SOCKS proxy.")
+	cmd.Flags().IntVarP(&cfg.port, "port", "", 18080, "First local port the public API of the replicas is forwarded to.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ssh command to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
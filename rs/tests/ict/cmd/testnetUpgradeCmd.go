@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var IC_ADMIN_TARGET = "//rs/registry/admin:ic-admin"
+
+type TestnetUpgradeConfig struct {
+	farmCfg   FarmConfig
+	version   string
+	subnets   []string
+	skipBless bool
+	isDryRun  bool
+}
+
+// ic-admin from PATH is preferred, otherwise it is built from HEAD.
+func get_ic_admin_command(nns_url string) []string {
+	command := []string{"bazel", "run", IC_ADMIN_TARGET, "--"}
+	if ic_admin, err := exec.LookPath("ic-admin"); err == nil {
+		command = []string{ic_admin}
+	}
+	return append(command, "--nns-url", nns_url)
+}
+
+// Proposals are submitted by the test neuron, which has the majority of the voting power, so they are adopted right away.
+func run_ic_admin_proposals(cmd *cobra.Command, proposals [][]string, is_dry_run bool) error {
+	for _, proposal := range proposals {
+		cmd.Printf("%s$ %s%s\n", CYAN, shell_quote(proposal), NC)
+		if is_dry_run {
+			continue
+		}
+		proposalCmd := exec.Command(proposal[0], proposal[1:]...)
+		proposalCmd.Stdout = os.Stdout
+		proposalCmd.Stderr = os.Stderr
+		traced := trace_command(proposal)
+		err := proposalCmd.Run()
+		traced(err)
+		if err != nil {
+			return fmt.Errorf("\nFailed to submit proposal: %s", err)
+		}
+	}
+	return nil
+}
+
+func get_update_img_url(version string) string {
+	return fmt.Sprintf("%s/%s/guest-os/update-img/update-img.tar.zst", IC_DOWNLOAD_BASE_URL, version)
+}
+
+// Same as fetch_update_file_sha256 of rs/tests/src/orchestrator/utils/upgrade.rs
+func get_update_img_sha256(version string) (string, error) {
+	url := fmt.Sprintf("%s/%s/guest-os/update-img/SHA256SUMS", IC_DOWNLOAD_BASE_URL, version)
+	resp, err := http_get(url)
+	if err != nil {
+		return "", fmt.Errorf("\nFailed to fetch GuestOS update image checksums of version `%s`: %s", version, err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == "update-img.tar.zst" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("\nNo checksum of update-img.tar.zst was found in %s", url)
+}
+
+// Subnets of the testnet, with the NNS subnet last, so that proposals for the others can still be submitted while it upgrades.
+func get_testnet_subnets(testnet *runningTestnet) []string {
+	subnets, nns_subnet := []string{}, ""
+	for _, vm := range testnet.Vms {
+		if len(vm.Subnet) == 0 || vm.Subnet == nns_subnet || any_equals(subnets, vm.Subnet) {
+			continue
+		}
+		if vm.IsNns {
+			nns_subnet = vm.Subnet
+		} else {
+			subnets = append(subnets, vm.Subnet)
+		}
+	}
+	if len(nns_subnet) > 0 {
+		subnets = append(subnets, nns_subnet)
+	}
+	return subnets
+}
+
+func TestnetUpgradeCommand(cfg *TestnetUpgradeConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		nns_url, ok := get_nns_url(testnet)
+		if !ok {
+			return fmt.Errorf("\nThe NNS of %s is only known for testnets kept alive by ict on this machine.", testnet.Group)
+		}
+		version, err := resolve_ic_version(cfg.version)
+		if err != nil {
+			return err
+		}
+		subnets := cfg.subnets
+		if len(subnets) == 0 {
+			subnets = get_testnet_subnets(testnet)
+		}
+		if len(subnets) == 0 {
+			return fmt.Errorf("\nNo subnets of %s are known, pass them via --subnet.", testnet.Group)
+		}
+		proposals := [][]string{}
+		if !cfg.skipBless {
+			sha256, err := get_update_img_sha256(version)
+			if err != nil {
+				return err
+			}
+			proposals = append(proposals, append(get_ic_admin_command(nns_url), "propose-to-update-elected-replica-versions", "--test-neuron-proposer",
+				"--replica-version-to-elect", version, "--release-package-sha256-hex", sha256, "--release-package-urls", get_update_img_url(version),
+				"--summary", fmt.Sprintf("Elect replica version %s on %s", version, testnet.Group)))
+		}
+		for _, subnet := range subnets {
+			proposals = append(proposals, append(get_ic_admin_command(nns_url), "propose-to-update-subnet-replica-version", "--test-neuron-proposer", subnet, version,
+				"--summary", fmt.Sprintf("Upgrade subnet %s of %s to replica version %s", subnet, testnet.Group, version)))
+		}
+		cmd.Printf("%sUpgrading %d subnets of %s to %s via the NNS at %s:%s\n", CYAN, len(subnets), testnet.Group, version, nns_url, NC)
+		if err := run_ic_admin_proposals(cmd, proposals, cfg.isDryRun); err != nil {
+			return err
+		}
+		if !cfg.isDryRun {
+			cmd.Printf("%sThe nodes download the new version and restart, watch their progress via:\n$ ict testnet status %s%s\n", GREEN, testnet.Group, NC)
+		}
+		return nil
+	}
+}
+
+func NewTestnetUpgradeCmd() *cobra.Command {
+	var cfg = TestnetUpgradeConfig{}
+	var cmd = &cobra.Command{
+		Use:     "upgrade <group> --version <git_revision> [--subnet <id>...]",
+		Short:   "Upgrade the replica version of the subnets of a testnet kept alive by ict, via NNS proposals of the test neuron",
+		Example: "ict testnet upgrade small--1690000000000 --version latest-mainnet\nict testnet upgrade small--1690000000000 --version <git_revision> --subnet <subnet_id> --dry-run",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetUpgradeCommand(&cfg),
+	}
+	cmd.Flags().StringVarP(&cfg.version, "version", "", "", fmt.Sprintf("Git revision or `%s` to upgrade to, its GuestOS update image must have been built by CI.", LATEST_MAINNET_VERSION_ALIAS))
+	cmd.MarkFlagRequired("version")
+	cmd.Flags().StringArrayVarP(&cfg.subnets, "subnet", "", []string{}, "Id or index of the subnet to upgrade (repeatable), all subnets of the testnet by default.")
+	cmd.Flags().BoolVarP(&cfg.skipBless, "skip-bless", "", false, "Don't propose to elect the version, e.g. because a previous upgrade already did.")
+	cmd.Flags().BoolVarP(&cfg.isDryRun, "dry-run", "n", false, "Print the ic-admin commands to be invoked without execution.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
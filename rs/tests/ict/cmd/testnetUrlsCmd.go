@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// Same as kibana_link of the test driver, see rs/tests/src/driver/constants.rs
+var KIBANA_BASE_URL = "https://kibana.testnet.dfinity.systems"
+
+// DNS names of Prometheus VMs are <name>.<group>.<suffix>, see small.rs in rs/tests/testing_verification/testnets.
+var FARM_DNS_SUFFIX = "testnet.farm.dfinity.systems"
+
+// The NNS dapp is installed with this canister id, it is served by the boundary nodes.
+var NNS_DAPP_CANISTER_ID = "qoctq-giaaa-aaaaa-aaaea-cai"
+
+// Grafana dashboards of rs/tests/dashboards, which are the most useful to watch the replicas.
+var REPLICA_DASHBOARDS = []string{"ic-progress-clock", "ic-health", "replica-details", "http-replica"}
+
+type testnetUrl struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type TestnetUrlsConfig struct {
+	farmCfg FarmConfig
+	isJson  bool
+}
+
+func get_kibana_link(group string) string {
+	return fmt.Sprintf("%s/app/kibana#/discover?_g=(time:(from:now-1y,to:now))&_a=(columns:!(_source),index:c8cf8e20-593f-11ec-9f11-0fb8445c6897,interval:auto,query:(language:kuery,query:'tags:%%22%s%%22'),sort:!(!('@timestamp',desc)))", KIBANA_BASE_URL, group)
+}
+
+func get_grafana_dashboard_url(grafana_url string, dashboard string) string {
+	return fmt.Sprintf("%s/d/%s/%s?refresh=10s&from=now-5m&to=now", grafana_url, dashboard, dashboard)
+}
+
+// Prometheus and Grafana URLs logged by the test driver are preferred, otherwise they are derived from the group name.
+func get_prometheus_urls(testnet *runningTestnet) (string, string, bool) {
+	if len(testnet.GrafanaUrl) > 0 {
+		return testnet.PrometheusUrl, testnet.GrafanaUrl, true
+	}
+	for _, vm := range testnet.Vms {
+		if vm.Name == "prometheus" {
+			return fmt.Sprintf("http://prometheus.%s.%s", testnet.Group, FARM_DNS_SUFFIX), fmt.Sprintf("http://grafana.%s.%s", testnet.Group, FARM_DNS_SUFFIX), true
+		}
+	}
+	return "", "", false
+}
+
+func get_testnet_urls(testnet *runningTestnet) []testnetUrl {
+	urls := []testnetUrl{}
+	if nns_url, ok := get_nns_url(testnet); ok {
+		urls = append(urls, testnetUrl{"NNS", nns_url})
+	}
+	for _, bn := range get_boundary_nodes(testnet) {
+		urls = append(urls, testnetUrl{fmt.Sprintf("Boundary node API (%s)", bn.Name), bn.Url + "/api/v2"})
+		// Canisters are only reachable via their subdomain with real certificates and DNS records.
+		if len(bn.Domain) > 0 {
+			urls = append(urls, testnetUrl{"NNS dapp (if installed)", fmt.Sprintf("https://%s.%s", NNS_DAPP_CANISTER_ID, bn.Domain)})
+		}
+	}
+	if prometheus_url, grafana_url, ok := get_prometheus_urls(testnet); ok {
+		urls = append(urls, testnetUrl{"Grafana", grafana_url})
+		for _, dashboard := range REPLICA_DASHBOARDS {
+			urls = append(urls, testnetUrl{"Grafana " + dashboard, get_grafana_dashboard_url(grafana_url, dashboard)})
+		}
+		urls = append(urls, testnetUrl{"Prometheus", prometheus_url})
+	}
+	urls = append(urls, testnetUrl{"Kibana replica logs", get_kibana_link(testnet.Group)})
+	return urls
+}
+
+func TestnetUrlsCommand(cfg *TestnetUrlsConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		testnet, err := find_testnet(&cfg.farmCfg, args[0])
+		if err != nil {
+			return err
+		}
+		urls := get_testnet_urls(testnet)
+		if cfg.isJson {
+			return print_json(cmd, urls)
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		for _, u := range urls {
+			fmt.Fprintf(w, "%s\t%s\n", u.Name, u.Url)
+		}
+		w.Flush()
+		if len(testnet.SetupDir) == 0 {
+			cmd.Printf("%sNNS and boundary node URLs are only known for testnets kept alive by ict on this machine.%s\n", CYAN, NC)
+		}
+		return nil
+	}
+}
+
+func NewTestnetUrlsCmd() *cobra.Command {
+	var cfg = TestnetUrlsConfig{}
+	var cmd = &cobra.Command{
+		Use:     "urls <group>",
+		Short:   "Print the NNS, boundary node, Grafana, Prometheus and Kibana URLs of a testnet running on Farm",
+		Example: "ict testnet urls small--1690000000000\nict testnet urls small--1690000000000 --json",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetUrlsCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the URLs as JSON.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+type TestnetWhoConfig struct {
+	farmCfg FarmConfig
+	isJson  bool
+}
+
+func TestnetWhoCommand(cfg *TestnetWhoConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		r, err := find_reservation(cfg.farmCfg.get_base_url(), name)
+		if err != nil {
+			return err
+		}
+		if cfg.isJson {
+			return print_json(cmd, r)
+		}
+		if r == nil {
+			cmd.Printf("%sTestnet %s isn't reserved, reserve it via:\n$ ict testnet reserve %s --for 2d%s\n", CYAN, name, name, NC)
+			return nil
+		}
+		cmd.Printf("%sTestnet %s is reserved by %s until %s (in %s).%s\n", GREEN, name, r.Owner, r.ExpiresAt.Local().Format("2006-01-02 15:04:05"), time.Until(r.ExpiresAt).Round(time.Minute), NC)
+		if len(r.Group) > 0 {
+			cmd.Printf("Farm group: %s\n", r.Group)
+		}
+		return nil
+	}
+}
+
+func NewTestnetWhoCmd() *cobra.Command {
+	var cfg = TestnetWhoConfig{}
+	var cmd = &cobra.Command{
+		Use:     "who <name>",
+		Short:   "Show who reserved a named testnet via `ict testnet reserve`, and until when",
+		Example: "ict testnet who perf-env\nict testnet who perf-env --json",
+		Args:    cobra.ExactArgs(1),
+		RunE:    TestnetWhoCommand(&cfg),
+	}
+	cmd.Flags().BoolVarP(&cfg.isJson, "json", "", false, "Print the reservation as JSON, null if the name isn't reserved.")
+	add_farm_flags(cmd, &cfg.farmCfg)
+	cmd.SetOut(os.Stdout)
+	return cmd
+}
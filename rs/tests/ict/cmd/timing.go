@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Bazel writes the Build Event Protocol (BEP) stream of the invocation as newline-delimited JSON into this file in ~/.ict.
+var BUILD_EVENTS_FILE = "build_events.json"
+
+// The test driver logs a summary of its tasks and their runtimes, the setup task provisions the VMs of the farm group.
+var DRIVER_JSON_REPORT_REGEX = regexp.MustCompile(`JSON Report:\s*(\{.*\})`)
+var DRIVER_SETUP_TASK_NAME = "setup"
+
+// Subset of the build events needed for the timing, int64 fields are encoded as strings in JSON.
+type buildEvent struct {
+	Started *struct {
+		StartTimeMillis int64 `json:"startTimeMillis,string"`
+	} `json:"started"`
+	Finished *struct {
+		FinishTimeMillis int64 `json:"finishTimeMillis,string"`
+	} `json:"finished"`
+	TestResult *struct {
+		TestAttemptStartMillisEpoch int64 `json:"testAttemptStartMillisEpoch,string"`
+		TestAttemptDurationMillis   int64 `json:"testAttemptDurationMillis,string"`
+	} `json:"testResult"`
+	Id struct {
+		TestResult *struct {
+			Label string `json:"label"`
+		} `json:"testResult"`
+	} `json:"id"`
+}
+
+type driverTaskReport struct {
+	Name    string  `json:"name"`
+	Runtime float64 `json:"runtime"`
+}
+
+type driverReport struct {
+	Success []driverTaskReport `json:"success"`
+	Failure []driverTaskReport `json:"failure"`
+	Skipped []driverTaskReport `json:"skipped"`
+}
+
+type targetTiming struct {
+	Target string
+	// Time from the start of the first to the end of the last attempt of the target.
+	Total time.Duration
+	// Zero, if the driver's report couldn't be found in the test log.
+	Setup time.Duration
+}
+
+type runTiming struct {
+	Wall time.Duration
+	// Time until the first test attempt started, i.e. analysis and building of the targets and the GuestOS images.
+	Build   time.Duration
+	Targets []targetTiming
+}
+
+func get_build_events_file() (string, error) {
+	ict_dir, err := get_ict_dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(ict_dir, BUILD_EVENTS_FILE), nil
+}
+
+func read_run_timing(path string, since time.Time) (*runTiming, error) {
+	info, err := os.Stat(path)
+	if err != nil || info.ModTime().Before(since) {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var started, finished, first_attempt int64
+	attempts := map[string][2]int64{}
+	labels := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var event buildEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if event.Started != nil {
+			started = event.Started.StartTimeMillis
+		}
+		if event.Finished != nil {
+			finished = event.Finished.FinishTimeMillis
+		}
+		if event.TestResult != nil && event.Id.TestResult != nil {
+			label := event.Id.TestResult.Label
+			start := event.TestResult.TestAttemptStartMillisEpoch
+			end := start + event.TestResult.TestAttemptDurationMillis
+			if first_attempt == 0 || start < first_attempt {
+				first_attempt = start
+			}
+			span, ok := attempts[label]
+			if !ok {
+				labels = append(labels, label)
+				span = [2]int64{start, end}
+			}
+			if start < span[0] {
+				span[0] = start
+			}
+			if end > span[1] {
+				span[1] = end
+			}
+			attempts[label] = span
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if started == 0 || finished == 0 {
+		return nil, fmt.Errorf("build events in %s are incomplete", path)
+	}
+	timing := runTiming{Wall: time.Duration(finished-started) * time.Millisecond, Targets: []targetTiming{}}
+	timing.Build = timing.Wall
+	if first_attempt > 0 {
+		timing.Build = time.Duration(first_attempt-started) * time.Millisecond
+	}
+	for _, label := range labels {
+		span := attempts[label]
+		target := targetTiming{Target: label, Total: time.Duration(span[1]-span[0]) * time.Millisecond}
+		target.Setup = get_setup_duration(get_test_logs_dir(label))
+		timing.Targets = append(timing.Targets, target)
+	}
+	return &timing, nil
+}
+
+// Returns the runtime of the setup task from the last report of the driver in the test log, zero if there is none.
+func get_setup_duration(logs_dir string) time.Duration {
+	content, err := os.ReadFile(filepath.Join(logs_dir, "test.log"))
+	if err != nil {
+		return 0
+	}
+	matches := DRIVER_JSON_REPORT_REGEX.FindAllSubmatch(content, -1)
+	if len(matches) == 0 {
+		return 0
+	}
+	var report driverReport
+	if err := json.Unmarshal(matches[len(matches)-1][1], &report); err != nil {
+		return 0
+	}
+	for _, tasks := range [][]driverTaskReport{report.Success, report.Failure, report.Skipped} {
+		for _, task := range tasks {
+			if task.Name == DRIVER_SETUP_TASK_NAME {
+				return time.Duration(task.Runtime * float64(time.Second))
+			}
+		}
+	}
+	return 0
+}
+
+func format_phase_duration(d time.Duration, total time.Duration) string {
+	if total <= 0 {
+		return d.Round(time.Second).String()
+	}
+	return fmt.Sprintf("%s (%.0f%%)", d.Round(time.Second), 100*float64(d)/float64(total))
+}
+
+// Without the driver's report, provisioning can't be told apart from the execution of the tests.
+func get_phase_durations(target targetTiming, wall time.Duration) (string, string) {
+	if target.Setup == 0 || target.Setup > target.Total {
+		return "unknown", format_phase_duration(target.Total, wall) + " incl. VM provisioning"
+	}
+	return format_phase_duration(target.Setup, wall), format_phase_duration(target.Total-target.Setup, wall)
+}
+
+// Prints how much of the run was spent building, provisioning VMs on Farm and executing tests.
+func print_run_timing(cmd *cobra.Command, path string, since time.Time) {
+	timing, err := read_run_timing(path, since)
+	if err != nil || timing == nil {
+		return
+	}
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Timing of the run (%s in total):\n", timing.Wall.Round(time.Second))
+	fmt.Fprintf(w, "Build\t%s\n", format_phase_duration(timing.Build, timing.Wall))
+	if len(timing.Targets) == 1 {
+		setup, execution := get_phase_durations(timing.Targets[0], timing.Wall)
+		fmt.Fprintf(w, "VM provisioning\t%s\n", setup)
+		fmt.Fprintf(w, "Test execution\t%s\n", execution)
+	} else if len(timing.Targets) > 1 {
+		fmt.Fprintln(w, "TARGET\tVM PROVISIONING\tTEST EXECUTION")
+		for _, target := range timing.Targets {
+			setup, execution := get_phase_durations(target, timing.Wall)
+			fmt.Fprintf(w, "%s\t%s\t%s\n", target.Target, setup, execution)
+		}
+	}
+	w.Flush()
+	cmd.Printf("%s%s%s", CYAN, sb.String(), NC)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Keys of the config files, by section, e.g. "" for the top-level keys and "profile.debug" for [profile.debug].
+// Values are kept as strings, arrays having an element each, as they end up as flag values anyway.
+type tomlDocument map[string]map[string][]string
+
+var TOML_SECTION_REGEX = regexp.MustCompile(`^\[\s*([A-Za-z0-9_.-]+)\s*\]$`)
+
+// Values of the keys are strings, numbers, booleans or arrays of them, nested tables end up in sections of their own.
+func parse_toml(content string) (tomlDocument, error) {
+	table := map[string]interface{}{}
+	if err := toml.Unmarshal([]byte(content), &table); err != nil {
+		var decode_err *toml.DecodeError
+		if errors.As(err, &decode_err) {
+			row, _ := decode_err.Position()
+			return nil, fmt.Errorf("line %d: %s", row, strings.TrimPrefix(decode_err.Error(), "toml: "))
+		}
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "toml: "))
+	}
+	doc := tomlDocument{"": {}}
+	if err := add_toml_table(doc, "", table); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Tables, which only consist of other tables, e.g. the one of [profile.debug] named profile, don't get a section.
+func add_toml_table(doc tomlDocument, section string, table map[string]interface{}) error {
+	keys := map[string][]string{}
+	for key, value := range table {
+		if sub_table, ok := value.(map[string]interface{}); ok {
+			name := key
+			if len(section) > 0 {
+				name = section + "." + key
+			}
+			if err := add_toml_table(doc, name, sub_table); err != nil {
+				return err
+			}
+			continue
+		}
+		values, err := get_toml_values(value)
+		if err != nil {
+			return fmt.Errorf("key `%s`: %s", key, err)
+		}
+		keys[key] = values
+	}
+	if len(keys) > 0 || len(table) == 0 || len(section) == 0 {
+		doc[section] = keys
+	}
+	return nil
+}
+
+func get_toml_values(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		scalar, err := get_toml_scalar(value)
+		return []string{scalar}, err
+	}
+	values := []string{}
+	for _, item := range items {
+		scalar, err := get_toml_scalar(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, scalar)
+	}
+	return values, nil
+}
+
+func get_toml_scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		return "", fmt.Errorf("nested arrays aren't supported")
+	case map[string]interface{}:
+		return "", fmt.Errorf("inline tables aren't supported in arrays")
+	}
+	return "", fmt.Errorf("unsupported value `%v`, values should be strings, numbers, booleans or arrays of them", value)
+}
+
+// Strips a trailing # comment, which isn't part of a string.
+func strip_toml_comment(line string) string {
+	quote, escaped := rune(0), false
+	for i, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && c == '\\':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '#':
+			return line[:i]
+		case c == '"' || c == '\'':
+			quote = c
+		}
+	}
+	return line
+}
+
+// Sets a top-level string key of the content of a config file, keeping all other lines including comments.
+// An empty value removes the key.
+func set_toml_key(content string, key string, value string) string {
+	lines := strings.Split(strings.TrimRight(content, "\n"), "\n")
+	if len(content) == 0 {
+		lines = []string{}
+	}
+	line := fmt.Sprintf("%s = %s", key, strconv.Quote(value))
+	updated := []string{}
+	is_set := len(value) == 0
+	for i, l := range lines {
+		if TOML_SECTION_REGEX.MatchString(strings.TrimSpace(strip_toml_comment(l))) {
+			// Top-level keys have to precede all sections, separated from them by a blank line.
+			if !is_set && len(updated) > 0 && len(strings.TrimSpace(updated[len(updated)-1])) == 0 {
+				updated = append(updated[:len(updated)-1], line, "")
+			} else if !is_set {
+				updated = append(updated, line, "")
+			}
+			updated = append(updated, lines[i:]...)
+			return strings.Join(updated, "\n") + "\n"
+		}
+		if existing, _, ok := strings.Cut(strip_toml_comment(l), "="); ok && strings.ReplaceAll(strings.TrimSpace(existing), "_", "-") == key {
+			if !is_set {
+				updated = append(updated, line)
+				is_set = true
+			}
+			continue
+		}
+		updated = append(updated, l)
+	}
+	if !is_set {
+		updated = append(updated, line)
+	}
+	return strings.Join(updated, "\n") + "\n"
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var LATEST_MAINNET_VERSION_ALIAS = "latest-mainnet"
+var IC_API_BASE_URL = "https://ic-api.internetcomputer.org/api/v3"
+var NNS_SUBNET_ID = "tdb26-jop6k-aogll-7ltgs-eruif-6kk7m-qpktf-gdiqx-mxtrf-vb5e6-eqe"
+var IC_DOWNLOAD_BASE_URL = "https://download.dfinity.systems/ic"
+var HTTP_REQUEST_TIMEOUT = 30 * time.Second
+
+var GIT_REVISION_REGEX = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+func http_get(url string) (*http.Response, error) {
+	client := http.Client{Timeout: HTTP_REQUEST_TIMEOUT}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+// Replica version of the NNS subnet, as reported by the public dashboard API.
+func get_latest_mainnet_nns_version() (string, error) {
+	resp, err := http_get(fmt.Sprintf("%s/subnets/%s", IC_API_BASE_URL, NNS_SUBNET_ID))
+	if err != nil {
+		return "", fmt.Errorf("\nFailed to resolve `%s`: %s", LATEST_MAINNET_VERSION_ALIAS, err)
+	}
+	defer resp.Body.Close()
+	var subnet struct {
+		ReplicaVersionId string `json:"replica_version_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&subnet); err != nil || !GIT_REVISION_REGEX.MatchString(subnet.ReplicaVersionId) {
+		return "", fmt.Errorf("\nFailed to resolve `%s`: unexpected response of the dashboard API", LATEST_MAINNET_VERSION_ALIAS)
+	}
+	return subnet.ReplicaVersionId, nil
+}
+
+// Resolves aliases and validates that the version is a full git revision.
+func resolve_ic_version(version string) (string, error) {
+	if version == LATEST_MAINNET_VERSION_ALIAS {
+		return get_latest_mainnet_nns_version()
+	}
+	if !GIT_REVISION_REGEX.MatchString(version) {
+		return "", fmt.Errorf("\nIC version `%s` should be either a full git revision or `%s`.", version, LATEST_MAINNET_VERSION_ALIAS)
+	}
+	return version, nil
+}
+
+func get_guestos_img_url(version string) string {
+	return fmt.Sprintf("%s/%s/guest-os/disk-img-dev/disk-img.tar.zst", IC_DOWNLOAD_BASE_URL, version)
+}
+
+func get_guestos_img_sha256(version string) (string, error) {
+	url := fmt.Sprintf("%s/%s/guest-os/disk-img-dev/SHA256SUMS", IC_DOWNLOAD_BASE_URL, version)
+	resp, err := http_get(url)
+	if err != nil {
+		return "", fmt.Errorf("\nFailed to fetch GuestOS image checksums of version `%s`: %s", version, err)
+	}
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == "disk-img.tar.zst" {
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("\nNo checksum of disk-img.tar.zst was found in %s", url)
+}
+
+// Returns Bazel flags, which make the test driver use the GuestOS image of the given version instead of the one built from HEAD.
+func get_ic_version_flags(version string) ([]string, error) {
+	sha256, err := get_guestos_img_sha256(version)
+	if err != nil {
+		return []string{}, err
+	}
+	return []string{
+		"--test_env=IC_VERSION=" + version,
+		"--test_env=IC_OS_IMG_URL=" + get_guestos_img_url(version),
+		"--test_env=IC_OS_IMG_SHA256=" + sha256,
+	}, nil
+}
+
+func resolve_ic_version_flags(cmd *cobra.Command, version string) ([]string, error) {
+	if len(version) == 0 {
+		return []string{}, nil
+	}
+	resolved, err := resolve_ic_version(version)
+	if err != nil {
+		return []string{}, err
+	}
+	if resolved != version {
+		cmd.Printf("%sIC version `%s` was resolved to %s%s\n", CYAN, version, resolved, NC)
+	}
+	return get_ic_version_flags(resolved)
+}
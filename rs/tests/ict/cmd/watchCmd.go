@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var DEFAULT_WATCH_DEBOUNCE = 2 * time.Second
+var WATCH_POLL_INTERVAL = 500 * time.Millisecond
+
+// Only changes of these source files of the target trigger a new run.
+var WATCHED_SOURCE_SUFFIXES = []string{".rs"}
+
+type WatchConfig struct {
+	testCfg  Config
+	debounce time.Duration
+}
+
+func ValidateWatchCommand(cfg *WatchConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cfg.debounce < 0 {
+			return fmt.Errorf("option --debounce should be >= 0.")
+		}
+		return positional_args(cobra.ExactArgs(1))(cmd, args)
+	}
+}
+
+func WatchCommandWithConfig(cfg *WatchConfig) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		args, bazel_args := split_bazel_args(cmd, args)
+		all_targets, err := get_all_system_test_targets(&cfg.testCfg.queryCfg)
+		if err != nil {
+			return err
+		}
+		target, err := resolve_target(cmd, all_targets, args[0], &cfg.testCfg.matchCfg)
+		if err != nil {
+			return err
+		}
+		command := get_bazel_test_command(&cfg.testCfg, []string{target}, bazel_args)
+		print_bazel_command(cmd, command)
+		return watch_and_run(cmd, cfg, target, command)
+	}
+}
+
+// Source files in the workspace, on which the target transitively depends, as paths relative to the workspace root.
+func get_source_files(target string) ([]string, error) {
+	workspace, err := get_ic_workspace_root()
+	if err != nil {
+		return []string{}, err
+	}
+	command := []string{"bazel", "query", fmt.Sprintf(`kind("source file", deps(%s))`, target), "--output=label"}
+	spinner := start_spinner("querying the sources of " + target)
+	defer spinner.stop()
+	queryCmd := exec.Command(command[0], command[1:]...)
+	queryCmd.Dir = workspace
+	outputBuffer := &bytes.Buffer{}
+	stdErrBuffer := &bytes.Buffer{}
+	queryCmd.Stdout = outputBuffer
+	queryCmd.Stderr = stdErrBuffer
+	traced := trace_command(command)
+	err = queryCmd.Run()
+	traced(err)
+	if err != nil {
+		return []string{}, fmt.Errorf("Bazel command: [%s] failed: %s", strings.Join(command, " "), stdErrBuffer.String())
+	}
+	files := []string{}
+	for _, label := range strings.Split(outputBuffer.String(), "\n") {
+		// Sources of external repositories, i.e. @crate_index//..., can't change locally.
+		if !strings.HasPrefix(label, "//") || !any_has_suffix(label, WATCHED_SOURCE_SUFFIXES) {
+			continue
+		}
+		pkg, name, _ := strings.Cut(strings.TrimPrefix(label, "//"), ":")
+		files = append(files, filepath.Join(pkg, name))
+	}
+	return files, nil
+}
+
+func any_has_suffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Computes a hash over paths, sizes and modification times of the files, deleted files are hashed as such.
+func get_files_fingerprint(workspace string, files []string) string {
+	hash := sha256.New()
+	for _, file := range files {
+		if info, err := os.Stat(filepath.Join(workspace, file)); err == nil {
+			fmt.Fprintf(hash, "%s:%d:%d\n", file, info.Size(), info.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(hash, "%s:deleted\n", file)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// Waits until the files haven't changed for the debounce period, e.g. while an editor or git checkout modifies several of them.
+func wait_for_quiet_files(workspace string, files []string, fingerprint string, debounce time.Duration) string {
+	for {
+		time.Sleep(debounce)
+		next := get_files_fingerprint(workspace, files)
+		if next == fingerprint {
+			return fingerprint
+		}
+		fingerprint = next
+	}
+}
+
+// Interrupts the run like Ctrl-C does, forwarding further signals until Bazel exits, and offers to delete its farm groups.
+func cancel_watched_run(cmd *cobra.Command, cfg *Config, running *interruptibleBazelRun, sig os.Signal, signals chan os.Signal) error {
+	running.interrupt(sig)
+	for {
+		select {
+		case sig := <-signals:
+			running.interrupt(sig)
+		case err := <-running.done:
+			return cleanup_interrupted_run(cmd, cfg, running.get_result(err))
+		}
+	}
+}
+
+// Runs the target and re-runs it on every change of its sources, a run in progress is cancelled by the change.
+// Ctrl-C stops the watcher, after cancelling the run in progress.
+func watch_and_run(cmd *cobra.Command, cfg *WatchConfig, target string, command []string) error {
+	workspace := get_workspace_root()
+	sources, err := get_source_files(target)
+	if err != nil {
+		return err
+	}
+	cmd.Printf("%sWatching %d source files of %s, press Ctrl-C to stop.%s\n", CYAN, len(sources), target, NC)
+	fingerprint := get_files_fingerprint(workspace, sources)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	running, err := start_interruptible_bazel_command(command, get_bazel_stdout())
+	if err != nil {
+		return err
+	}
+	done := running.done
+	ticker := time.NewTicker(WATCH_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case sig := <-signals:
+			if running == nil {
+				return nil
+			}
+			return cancel_watched_run(cmd, &cfg.testCfg, running, sig, signals)
+		case err := <-done:
+			running, done = nil, nil
+			if err != nil {
+				cmd.PrintErrf("%sRun of %s failed: %s, waiting for changes ...%s\n", RED, target, err, NC)
+			} else {
+				cmd.Printf("%sRun of %s passed, waiting for changes ...%s\n", GREEN, target, NC)
+			}
+		case <-ticker.C:
+			next := get_files_fingerprint(workspace, sources)
+			if next == fingerprint {
+				continue
+			}
+			fingerprint = wait_for_quiet_files(workspace, sources, next, cfg.debounce)
+			if running != nil {
+				cmd.Printf("%sSources have changed, cancelling the run in progress ...%s\n", CYAN, NC)
+				cancel_watched_run(cmd, &cfg.testCfg, running, syscall.SIGINT, signals)
+			} else {
+				cmd.Printf("%sSources have changed, re-running %s ...%s\n", CYAN, target, NC)
+			}
+			// Changed sources might have added or removed dependencies.
+			if updated, err := get_source_files(target); err == nil {
+				sources = updated
+				fingerprint = get_files_fingerprint(workspace, sources)
+			}
+			if running, err = start_interruptible_bazel_command(command, get_bazel_stdout()); err != nil {
+				return err
+			}
+			done = running.done
+		}
+	}
+}
+
+func NewWatchCmd() *cobra.Command {
+	var cfg = WatchConfig{}
+	var watchCmd = &cobra.Command{
+		Use:     "watch <system_test_target> [flags] [-- <bazel_args>]",
+		Short:   "Run system_test target with Bazel and re-run it whenever its Rust sources change",
+		Example: "  ict watch basic_health_test\n  ict watch basic_health_test --debounce=5s -- --test_output=errors",
+		Args:    ValidateWatchCommand(&cfg),
+		RunE:    WatchCommandWithConfig(&cfg),
+	}
+	add_match_flags(watchCmd, &cfg.testCfg.matchCfg)
+	watchCmd.Flags().DurationVarP(&cfg.debounce, "debounce", "", DEFAULT_WATCH_DEBOUNCE, "Wait until sources haven't changed for this long, before a new run is started.")
+	watchCmd.Flags().Uint64VarP(&cfg.testCfg.seed, "seed", "", DEFAULT_RNG_SEED, "Seed of the random number generator of the test driver, which is passed to the tests as RNG_SEED.")
+	watchCmd.Flags().StringArrayVarP(&cfg.testCfg.testEnv, "env", "e", []string{}, "Set an environment variable of the tests, e.g. --env FEATURE_FLAG=1 (can be repeated).")
+	watchCmd.Flags().StringVarP(&cfg.testCfg.filterTests, "include-tests", "i", "", "Execute only those test functions which contain a substring.")
+	watchCmd.Flags().StringVarP(&cfg.testCfg.farmBaseUrl, "farm-url", "", "", "Use a custom url for the Farm webservice.")
+	add_query_flags(watchCmd, &cfg.testCfg.queryCfg)
+	watchCmd.SetOut(os.Stdout)
+	return watchCmd
+}
@@ -14,14 +14,52 @@ func AssembleAllCmds() *cobra.Command {
 	testCmd.AddCommand(cmd.NewTestListCmd()) // command + subcommand
 	var testnetCmd = cmd.NewTestnetCmd()
 	testnetCmd.AddCommand(cmd.NewTestnetListCmd()) // command + subcommand
+	testnetCmd.AddCommand(cmd.NewTestnetCreateCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetDeleteCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetExtendCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetStatusCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetSshCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetScpCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetExecCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetLogsCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetTopologyCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetUrlsCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetInstallCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetUpgradeCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetReplaceNodeCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetScaleCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetConsoleCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetGcCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetExportCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetImportCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetHoldCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetReserveCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetWhoCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetAdminCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetMetricsCmd())
+	testnetCmd.AddCommand(cmd.NewTestnetTunnelCmd())
+	var artifactsCmd = cmd.NewArtifactsCmd()
+	artifactsCmd.AddCommand(cmd.NewArtifactsListCmd()) // command + subcommand
+	artifactsCmd.AddCommand(cmd.NewArtifactsOpenCmd())
 	var rootCmd = cmd.NewRootCmd()
 	rootCmd.AddCommand(testCmd)
+	rootCmd.AddCommand(cmd.NewTestAllCmd())
+	rootCmd.AddCommand(cmd.NewWatchCmd())
+	rootCmd.AddCommand(cmd.NewBisectCmd())
+	rootCmd.AddCommand(artifactsCmd)
 	rootCmd.AddCommand(testnetCmd)
+	rootCmd.AddCommand(cmd.NewQueryCmd())
+	rootCmd.AddCommand(cmd.NewRdepsCmd())
+	rootCmd.AddCommand(cmd.NewCheckConnectivityCmd())
+	rootCmd.AddCommand(cmd.NewDoctorCmd())
+	rootCmd.AddCommand(cmd.NewRecentCmd())
+	rootCmd.AddCommand(cmd.NewRerunCmd())
+	rootCmd.AddCommand(cmd.NewInitCmd())
 	return rootCmd
 }
 
 func main() {
-	if err := AssembleAllCmds().Execute(); err != nil {
+	if err := cmd.Execute(AssembleAllCmds(), os.Args[1:]); err != nil {
 		color.New(color.FgRed, color.Bold).Fprintf(os.Stderr, "There was an error while executing CLI: ")
 		fmt.Fprintf(os.Stderr, "%s\n", err)
 		os.Exit(1)
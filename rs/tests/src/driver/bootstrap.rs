@@ -9,6 +9,7 @@ use crate::driver::ic::{InternetComputer, Node};
 use crate::driver::test_env::{HasIcPrepDir, TestEnv};
 use crate::driver::test_env_api::{
     HasDependencies, HasIcDependencies, HasTopologySnapshot, IcNodeContainer, NodesInfo,
+    REPLICA_LOG_LEVEL_ENV_VAR,
 };
 use ic_base_types::NodeId;
 use ic_prep_lib::{
@@ -335,6 +336,9 @@ pub fn create_config_disk_image(
         cmd.arg("--replica_log_debug_overrides")
             .arg(replica_log_debug_overrides_val);
     }
+    if let Ok(replica_log_level) = std::env::var(REPLICA_LOG_LEVEL_ENV_VAR) {
+        cmd.arg("--replica_log_level").arg(replica_log_level);
+    }
     // --bitcoind_addr indicates the local bitcoin node that the bitcoin adapter should be connected to in the system test environment.
     if let Ok(arg) = test_env.read_json_object::<String, _>(BITCOIND_ADDR_PATH) {
         cmd.arg("--bitcoind_addr").arg(arg);
@@ -18,6 +18,7 @@ pub struct GroupContext {
     pub exec_path: PathBuf,
     pub group_dir: PathBuf,
     pub filter_tests: Option<String>,
+    pub exact_test: Option<String>,
     logger: Logger,
     pub sock_id: u64,
     pub debug_keepalive: bool,
@@ -33,6 +34,7 @@ impl GroupContext {
         group_dir: PathBuf,
         subproc_info: Option<(TaskId, u64)>,
         filter_tests: Option<String>,
+        exact_test: Option<String>,
         debug_keepalive: bool,
     ) -> Result<Self> {
         let task_id = subproc_info.as_ref().map(|t| t.0.clone());
@@ -52,6 +54,7 @@ impl GroupContext {
             exec_path,
             group_dir,
             filter_tests,
+            exact_test,
             logger,
             sock_id,
             debug_keepalive,
@@ -0,0 +1,77 @@
+use crate::driver::test_env::{HasIcPrepDir, TestEnv};
+use crate::driver::test_env_api::{
+    HasTopologySnapshot, IcNodeContainer, IcNodeSnapshot, SshSession,
+};
+use anyhow::Result;
+use slog::{info, warn};
+use std::fs;
+use std::io::Read;
+use std::path::Path;
+
+/// Directory in the undeclared outputs of the test, into which artifacts of the nodes are collected if tests fail.
+pub const CRASH_ARTIFACTS_DIR: &str = "crash_artifacts";
+const CORE_DUMPS_DIR: &str = "/var/lib/systemd/coredump";
+
+/// Collects core dumps, panics and the journald logs of the last `log_minutes` of all nodes of the IC into
+/// `dir`, one subdirectory per node. Nodes, which can't be reached anymore, are skipped.
+pub fn collect_crash_artifacts(env: &TestEnv, dir: &Path, log_minutes: u64) {
+    // Groups without an IC, e.g. with universal VMs only, have no nodes to collect from.
+    if env.prep_dir("").is_none() {
+        return;
+    }
+    let topology = env.topology_snapshot();
+    let nodes: Vec<IcNodeSnapshot> = topology
+        .subnets()
+        .flat_map(|subnet| subnet.nodes())
+        .chain(topology.unassigned_nodes())
+        .collect();
+    for node in nodes {
+        let node_dir = dir.join(node.node_id.to_string());
+        if let Err(e) = collect_node_artifacts(&node, &node_dir, log_minutes) {
+            warn!(
+                env.logger(),
+                "Failed to collect crash artifacts of node {}: {:?}", node.node_id, e
+            );
+        }
+    }
+    info!(
+        env.logger(),
+        "Crash artifacts of the nodes were collected in {:?}", dir
+    );
+}
+
+fn collect_node_artifacts(node: &IcNodeSnapshot, node_dir: &Path, log_minutes: u64) -> Result<()> {
+    let session = node.get_ssh_session()?;
+    fs::create_dir_all(node_dir)?;
+    let journal = node.block_on_bash_script_from_session(
+        &session,
+        &format!("sudo journalctl --no-pager --since '-{log_minutes}min'"),
+    )?;
+    let panics: Vec<&str> = journal
+        .lines()
+        .filter(|line| line.contains("panicked at"))
+        .collect();
+    fs::write(node_dir.join("journal.log"), &journal)?;
+    fs::write(node_dir.join("panics.log"), panics.join("\n"))?;
+    // Core dumps are readable by root only, hence they are copied to a world-readable location first.
+    let core_dumps = node.block_on_bash_script_from_session(
+        &session,
+        &format!(
+            r#"set -e
+            mkdir -p /tmp/{CRASH_ARTIFACTS_DIR}
+            for f in $(sudo find {CORE_DUMPS_DIR} -type f 2>/dev/null); do
+                sudo install -m 0644 "$f" /tmp/{CRASH_ARTIFACTS_DIR}/
+                echo "/tmp/{CRASH_ARTIFACTS_DIR}/$(basename "$f")"
+            done
+            "#
+        ),
+    )?;
+    for core_dump in core_dumps.lines().filter(|line| !line.is_empty()) {
+        let (mut remote_file, _) = session.scp_recv(Path::new(core_dump))?;
+        let mut content = Vec::new();
+        remote_file.read_to_end(&mut content)?;
+        let file_name = Path::new(core_dump).file_name().unwrap();
+        fs::write(node_dir.join(file_name), content)?;
+    }
+    Ok(())
+}
@@ -20,7 +20,7 @@ use crate::driver::{
 };
 use crate::driver::{
     pot_dsl::{PotSetupFn, SysTestFn},
-    test_env::{TestEnv, TestEnvAttribute},
+    test_env::{get_rng_seed, TestEnv, TestEnvAttribute},
     test_env_api::HasIcDependencies,
     test_setup::GroupSetup,
 };
@@ -31,7 +31,8 @@ use clap::Parser;
 use tokio::runtime::{Builder, Handle, Runtime};
 
 use crate::driver::{
-    constants::{kibana_link, GROUP_TTL, KEEPALIVE_INTERVAL},
+    constants::{kibana_link, GROUP_SETUP_DIR, GROUP_TTL, KEEPALIVE_INTERVAL},
+    crash_artifacts::{collect_crash_artifacts, CRASH_ARTIFACTS_DIR},
     subprocess_task::SubprocessTask,
     task::{SkipTestTask, Task},
     timeout::TimeoutTask,
@@ -73,11 +74,35 @@ pub struct CliArgs {
     )]
     pub filter_tests: Option<String>,
 
+    #[clap(
+        long = "exact-test",
+        help = r#"Execute only the test function with exactly this name and skip all the others, e.g. to rerun a single step against a reused setup."#
+    )]
+    pub exact_test: Option<String>,
+
+    #[clap(
+        long = "list-tests",
+        help = r#"Print the names of the setup and test functions of the group and exit, without setting up anything."#
+    )]
+    pub list_tests: bool,
+
     #[clap(
         long = "farm-base-url",
         help = r#"Use a custom url for the Farm webservice."#
     )]
     pub farm_base_url: Option<url::Url>,
+
+    #[clap(
+        long = "reuse-setup-dir",
+        help = r#"Reuse the setup directory of an already running environment, e.g. one kept alive via --debug-keepalive. The setup function is skipped and the farm group is neither created nor deleted."#
+    )]
+    pub reuse_setup_dir: Option<PathBuf>,
+
+    #[clap(
+        long = "crash-artifacts-log-minutes",
+        help = r#"If set and tests fail, collect core dumps, panics and the journald logs of the given last minutes of all nodes into the undeclared outputs of the test."#
+    )]
+    pub crash_artifacts_log_minutes: Option<u64>,
 }
 
 impl CliArgs {
@@ -257,6 +282,16 @@ impl Default for SystemTestSubGroup {
 }
 
 impl SystemTestSubGroup {
+    /// Names of the test functions of this sub group and all of its nested ones.
+    pub fn task_names(&self) -> Vec<String> {
+        match self {
+            SystemTestSubGroup::Multiple { tasks, .. } => {
+                tasks.iter().flat_map(|task| task.task_names()).collect()
+            }
+            SystemTestSubGroup::Singleton { task_id, .. } => vec![task_id.to_string()],
+        }
+    }
+
     pub fn new() -> Self {
         Self::Multiple {
             tasks: vec![],
@@ -299,18 +334,24 @@ impl SystemTestSubGroup {
                     .collect(),
                 ctx,
             ),
-            // If filtering flag `--include-tests` is set, then for all
+            // If filtering flag `--include-tests` or `--exact-test` is set, then for all
             // skipped test function we execute a SkipTestTask
             SystemTestSubGroup::Singleton { task_fn, task_id } => {
                 let logger = ctx.logger.clone();
                 let group_ctx = ctx.group_ctx.clone();
-                if let Some(ref filter) = group_ctx.filter_tests {
-                    if let TaskId::Test(ref name) = task_id {
-                        if !name.contains(filter) {
-                            return Plan::Leaf {
-                                task: Box::from(SkipTestTask::new(task_id.clone())),
-                            };
-                        }
+                if let TaskId::Test(ref name) = task_id {
+                    let is_included = group_ctx
+                        .filter_tests
+                        .as_ref()
+                        .map_or(true, |filter| name.contains(filter));
+                    let is_exact = group_ctx
+                        .exact_test
+                        .as_ref()
+                        .map_or(true, |exact| name == exact);
+                    if !is_included || !is_exact {
+                        return Plan::Leaf {
+                            task: Box::from(SkipTestTask::new(task_id.clone())),
+                        };
                     }
                 }
                 let closure = {
@@ -560,6 +601,11 @@ impl SystemTestGroup {
                 move || {
                     debug!(logger, ">>> setup_fn");
                     let env = get_setup_env(group_ctx);
+                    // A reused setup directory already contains the result of a successful setup.
+                    if SetupResult::try_read_attribute(&env).is_ok() {
+                        info!(logger, "Reusing existing setup, skipping setup function.");
+                        return;
+                    }
                     setup_fn(env.clone());
                     SetupResult {}.write_attribute(&env);
                 },
@@ -648,6 +694,18 @@ impl SystemTestGroup {
         ))
     }
 
+    /// Prints one line per function, e.g. `Test function: test`, in the order of the group.
+    fn print_test_names(&self) {
+        if self.setup.is_some() {
+            println!("Setup function: {}", SETUP_TASK_NAME);
+        }
+        for test in self.tests.iter() {
+            for name in test.task_names() {
+                println!("Test function: {}", name);
+            }
+        }
+    }
+
     pub fn execute(self) -> Result<Outcome> {
         // TODO: check preconditions:
         // 0. None of the test functions (modulo the setup function) have the literal name "setup"
@@ -659,18 +717,32 @@ impl SystemTestGroup {
         // 1. CLI arguments are sane
         // 2. Test / setup functions are not specified more than once in the group
         let args = CliArgs::parse().validate()?;
+        if args.list_tests {
+            self.print_test_names();
+            return Ok(Outcome::FromParentProcess(SystemGroupSummary::default()));
+        }
         let is_parent_process = matches!(args.action, SystemTestsSubcommand::Run);
 
         let group_ctx = GroupContext::new(
             args.group_dir.path.clone(),
             args.subproc_id(),
             args.filter_tests,
+            args.exact_test,
             args.debug_keepalive,
         )?;
+        let is_setup_reused = args.reuse_setup_dir.is_some();
+        let crash_artifacts_log_minutes = args.crash_artifacts_log_minutes;
         if is_parent_process {
             let root_env = group_ctx.get_root_env().unwrap();
             FarmBaseUrl::new_or_default(args.farm_base_url).write_attribute(&root_env);
-            if self.with_farm {
+            info!(group_ctx.log(), "RNG seed: {}", get_rng_seed());
+            if let Some(reuse_setup_dir) = &args.reuse_setup_dir {
+                info!(
+                    group_ctx.log(),
+                    "Reusing setup directory {:?}", reuse_setup_dir
+                );
+                TestEnv::shell_copy(reuse_setup_dir, args.group_dir.path.join(GROUP_SETUP_DIR))?;
+            } else if self.with_farm {
                 root_env.create_group_setup();
             }
             debug!(group_ctx.log(), "Created group context: {:?}", group_ctx);
@@ -767,7 +839,14 @@ impl SystemTestGroup {
                 info!(group_ctx.log(), "JSON Report:\n{}", report);
                 info!(group_ctx.log(), "Report:\n{}", report.pretty_print());
 
-                if with_farm {
+                // Nodes have to be reached before the farm group is deleted.
+                if let Some(log_minutes) = crash_artifacts_log_minutes {
+                    if with_farm && !report.failure.is_empty() {
+                        Self::collect_crash_artifacts(group_ctx.clone(), log_minutes);
+                    }
+                }
+                // A reused farm group is owned by the environment, which created it.
+                if with_farm && !is_setup_reused {
                     Self::delete_farm_group(group_ctx.clone());
                 }
                 if report.failure.is_empty() {
@@ -803,6 +882,15 @@ impl SystemTestGroup {
         }
     }
 
+    fn collect_crash_artifacts(ctx: GroupContext, log_minutes: u64) {
+        // Bazel keeps the undeclared outputs of the test, i.e. bazel-testlogs/<target>/test.outputs
+        if let Ok(outputs_dir) = std::env::var("TEST_UNDECLARED_OUTPUTS_DIR") {
+            let env = get_setup_env(ctx);
+            let dir = PathBuf::from(outputs_dir).join(CRASH_ARTIFACTS_DIR);
+            collect_crash_artifacts(&env, &dir, log_minutes);
+        }
+    }
+
     fn delete_farm_group(ctx: GroupContext) {
         info!(ctx.log(), "Deleting farm group.");
         let env = get_setup_env(ctx);
@@ -4,6 +4,7 @@ pub mod boundary_node;
 pub mod config;
 pub mod constants;
 pub mod context;
+pub mod crash_artifacts;
 pub mod driver_setup;
 pub mod dsl;
 pub mod event;
@@ -212,12 +212,24 @@ pub trait HasDefaultRng {
     fn default_rng(&self) -> Box<dyn RngCore>;
 }
 
+/// Seed of the default random number generator, which can be set in the
+/// environment of the test, e.g. via `ict test --seed`.
+pub const RNG_SEED_ENV_VAR: &str = "RNG_SEED";
+const DEFAULT_RNG_SEED: u64 = 42;
+
+/// Returns the seed set via `RNG_SEED`, or the constant default one.
+pub fn get_rng_seed() -> u64 {
+    std::env::var(RNG_SEED_ENV_VAR)
+        .ok()
+        .and_then(|seed| seed.parse().ok())
+        .unwrap_or(DEFAULT_RNG_SEED)
+}
+
 impl HasDefaultRng for TestEnv {
-    /// Returns a random number generator based on a constant seed. At a later
-    /// point, the seed will be configured through the underlying test
-    /// environment.
+    /// Returns a random number generator based on the seed of the test,
+    /// see `get_rng_seed`.
     fn default_rng(&self) -> Box<dyn RngCore> {
-        Box::new(ChaCha8Rng::seed_from_u64(42))
+        Box::new(ChaCha8Rng::seed_from_u64(get_rng_seed()))
     }
 }
 
@@ -136,7 +136,7 @@ use super::driver_setup::SSH_AUTHORIZED_PRIV_KEYS_DIR;
 use super::farm::{DnsRecord, PlaynetCertificate};
 use super::test_setup::GroupSetup;
 use crate::driver::constants::{self, kibana_link, SSH_USERNAME};
-use crate::driver::farm::{Farm, GroupSpec};
+use crate::driver::farm::{Farm, GroupSpec, HostFeature};
 use crate::driver::test_env::{HasIcPrepDir, SshKeyGen, TestEnv, TestEnvAttribute};
 use crate::util::{create_agent, delay};
 use anyhow::{anyhow, bail, Result};
@@ -187,6 +187,14 @@ use url::Url;
 pub const READY_WAIT_TIMEOUT: Duration = Duration::from_secs(500);
 pub const SSH_RETRY_TIMEOUT: Duration = Duration::from_secs(500);
 pub const RETRY_BACKOFF: Duration = Duration::from_secs(5);
+/// Environment variables, which override the GuestOS version built from HEAD, e.g. `bazel test --test_env=IC_VERSION=<hash>`.
+pub const IC_VERSION_OVERRIDE_ENV_VAR: &str = "IC_VERSION";
+pub const IC_OS_IMG_URL_OVERRIDE_ENV_VAR: &str = "IC_OS_IMG_URL";
+pub const IC_OS_IMG_SHA256_OVERRIDE_ENV_VAR: &str = "IC_OS_IMG_SHA256";
+/// Environment variable, which pins all VMs of the Farm group to the given datacenter, e.g. `bazel test --test_env=FARM_DC=<dc>`.
+pub const FARM_DC_ENV_VAR: &str = "FARM_DC";
+/// Environment variable, which sets the log level of the node software, e.g. `bazel test --test_env=REPLICA_LOG_LEVEL=debug`.
+pub const REPLICA_LOG_LEVEL_ENV_VAR: &str = "REPLICA_LOG_LEVEL";
 const REGISTRY_QUERY_TIMEOUT: Duration = Duration::from_secs(5);
 const READY_RESPONSE_TIMEOUT: Duration = Duration::from_secs(6);
 
@@ -821,6 +829,9 @@ impl<T: HasDependencies + HasTestEnv> HasIcDependencies for T {
     }
 
     fn get_initial_replica_version(&self) -> Result<ReplicaVersion> {
+        if let Ok(replica_ver) = std::env::var(IC_VERSION_OVERRIDE_ENV_VAR) {
+            return Ok(ReplicaVersion::try_from(replica_ver)?);
+        }
         let dep_rel_path = std::env::var("IC_VERSION_FILE")?;
         let replica_ver = self.read_dependency_to_string(dep_rel_path)?;
         Ok(ReplicaVersion::try_from(replica_ver)?)
@@ -833,6 +844,9 @@ impl<T: HasDependencies + HasTestEnv> HasIcDependencies for T {
     }
 
     fn get_ic_os_img_url(&self) -> Result<Url> {
+        if let Ok(url) = std::env::var(IC_OS_IMG_URL_OVERRIDE_ENV_VAR) {
+            return Ok(Url::parse(&url)?);
+        }
         let dep_rel_path =
             "ic-os/guestos/envs/dev/upload_disk-img_disk-img.tar.zst.proxy-cache-url";
         let url = self.read_dependency_to_string(dep_rel_path)?;
@@ -840,6 +854,10 @@ impl<T: HasDependencies + HasTestEnv> HasIcDependencies for T {
     }
 
     fn get_ic_os_img_sha256(&self) -> Result<String> {
+        if let Ok(sha256) = std::env::var(IC_OS_IMG_SHA256_OVERRIDE_ENV_VAR) {
+            bail_if_sha256_invalid(&sha256, "ic_os_img_sha256")?;
+            return Ok(sha256);
+        }
         let dep_rel_path = "ic-os/guestos/envs/dev/disk-img.tar.zst.sha256";
         let sha256 = self.read_dependency_to_string(dep_rel_path)?;
         bail_if_sha256_invalid(&sha256, "ic_os_img_sha256")?;
@@ -956,9 +974,12 @@ impl HasGroupSetup for TestEnv {
         let group_setup = GroupSetup::from_bazel_env();
         let farm_base_url = FarmBaseUrl::read_attribute(self);
         let farm = Farm::new(farm_base_url.into(), self.logger());
+        let required_host_features = std::env::var(FARM_DC_ENV_VAR)
+            .map(|dc| vec![HostFeature::DC(dc)])
+            .unwrap_or_default();
         let group_spec = GroupSpec {
             vm_allocation: None,
-            required_host_features: vec![],
+            required_host_features,
             preferred_network: None,
             metadata: None,
         };
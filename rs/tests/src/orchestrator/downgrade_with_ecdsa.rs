@@ -59,8 +59,11 @@ fn downgrade_test(env: TestEnv, subnet_type: SubnetType) {
     // TODO: abandon the TARGET_VERSION approach once run-system-tests.py is deprecated [VER-1818]
     let is_bazel = env::var("TARGET_VERSION").is_err();
 
+    // UPGRADE_TARGET_VERSION replaces the mainnet version also under Bazel, e.g. set via `ict test --to-version`.
+    // It has to be blessed from the public download URL, i.e. built by CI.
     // TODO: [VER-1818]
-    let mainnet_version = env::var("TARGET_VERSION")
+    let mainnet_version = env::var("UPGRADE_TARGET_VERSION")
+        .or_else(|_| env::var("TARGET_VERSION"))
         .or_else(|_| env.read_dependency_to_string("testnet/mainnet_nns_revision.txt"))
         .unwrap();
 
@@ -0,0 +1,267 @@
+// Set up a testnet from a declarative config, instead of requiring a dedicated target per shape.
+//
+// The config is passed as JSON in the TESTNET_CONFIG environment variable, e.g.:
+//
+//   {
+//     "subnets": [
+//       {"type": "system", "nodes": 1},
+//       {"type": "application", "nodes": 4, "features": ["http_requests"]}
+//     ],
+//     "unassigned_nodes": 1,
+//     "boundary_nodes": 1,
+//     "nns": true,
+//     "ii": true,
+//     "prefund": [{"principal": "<principal>", "e8s": 100000000}],
+//     "prometheus": true,
+//     "canisters": [
+//       {"name": "counter", "wasm": "/abs/path/to/counter.wasm", "subnet": 1}
+//     ]
+//   }
+//
+// ict translates a YAML file with the same fields into this variable:
+//
+//   $ ict testnet create --from-config topology.yaml
+//
+// Boundary nodes are named boundary-node-<n> and use real certificates and DNS records, all of them
+// share the same domain.
+//
+// The NNS canisters built from the tip of the branch are installed on the first (System) subnet, the
+// Internet Identity test canister too. The ICP ledger is initialized with the prefunded accounts of
+// the given principals, on top of the ones the test neurons need.
+//
+// Canisters are installed via the provisional API on the first node of the subnet with the given
+// index, the optional "arg" field holds the hex-encoded init argument.
+
+use anyhow::{anyhow, bail, Context, Result};
+use serde::Deserialize;
+use slog::info;
+use std::collections::HashMap;
+use std::str::FromStr;
+
+use ic_base_types::PrincipalId;
+use ic_registry_subnet_features::SubnetFeatures;
+use ic_registry_subnet_type::SubnetType;
+use ic_tests::driver::{
+    boundary_node::BoundaryNode,
+    group::SystemTestGroup,
+    ic::{InternetComputer, Subnet},
+    prometheus_vm::{HasPrometheus, PrometheusVm},
+    test_env::TestEnv,
+    test_env_api::{
+        HasPublicApiUrl, HasTopologySnapshot, IcNodeContainer, NnsCanisterWasmStrategy,
+        NnsCustomizations, NnsInstallationExt,
+    },
+};
+use ic_tests::util::delegations::INTERNET_IDENTITY_WASM;
+use icp_ledger::{AccountIdentifier, Tokens};
+
+const TESTNET_CONFIG_ENV_VAR: &str = "TESTNET_CONFIG";
+
+#[derive(Deserialize)]
+#[serde(deny_unknown_fields)]
+struct TestnetConfig {
+    subnets: Vec<SubnetConfig>,
+    #[serde(default)]
+    unassigned_nodes: usize,
+    #[serde(default)]
+    boundary_nodes: usize,
+    #[serde(default)]
+    nns: bool,
+    #[serde(default)]
+    ii: bool,
+    #[serde(default)]
+    prefund: Vec<PrefundConfig>,
+    #[serde(default = "default_prometheus")]
+    prometheus: bool,
+    #[serde(default)]
+    canisters: Vec<CanisterConfig>,
+}
+
+#[derive(Deserialize)]
+#[serde(deny_unknown_fields)]
+struct SubnetConfig {
+    #[serde(rename = "type")]
+    subnet_type: SubnetType,
+    nodes: usize,
+    #[serde(default)]
+    features: Vec<String>,
+    dkg_interval_length: Option<u64>,
+}
+
+#[derive(Deserialize)]
+#[serde(deny_unknown_fields)]
+struct CanisterConfig {
+    name: String,
+    wasm: String,
+    #[serde(default)]
+    subnet: usize,
+    arg: Option<String>,
+}
+
+#[derive(Deserialize)]
+#[serde(deny_unknown_fields)]
+struct PrefundConfig {
+    principal: String,
+    e8s: u64,
+}
+
+fn default_prometheus() -> bool {
+    true
+}
+
+fn main() -> Result<()> {
+    SystemTestGroup::new()
+        .with_setup(setup)
+        .execute_from_args()?;
+    Ok(())
+}
+
+fn read_config() -> Result<TestnetConfig> {
+    let json = std::env::var(TESTNET_CONFIG_ENV_VAR)
+        .with_context(|| format!("{TESTNET_CONFIG_ENV_VAR} is not set"))?;
+    let config: TestnetConfig = serde_json::from_str(&json)
+        .with_context(|| format!("{TESTNET_CONFIG_ENV_VAR} is not a valid testnet config"))?;
+    if config.subnets.is_empty() {
+        bail!("at least one subnet is required");
+    }
+    if !config.prefund.is_empty() && !config.nns {
+        bail!("prefunded accounts require the NNS");
+    }
+    for canister in config.canisters.iter() {
+        if canister.subnet >= config.subnets.len() {
+            bail!(
+                "canister {} is installed on subnet {}, but there are only {} subnets",
+                canister.name,
+                canister.subnet,
+                config.subnets.len()
+            );
+        }
+    }
+    Ok(config)
+}
+
+fn get_ledger_balances(prefund: &[PrefundConfig]) -> Result<HashMap<AccountIdentifier, Tokens>> {
+    let mut ledger_balances = HashMap::new();
+    for account in prefund {
+        let principal = PrincipalId::from_str(&account.principal)
+            .map_err(|e| anyhow!("invalid principal {}: {e}", account.principal))?;
+        ledger_balances.insert(principal.into(), Tokens::from_e8s(account.e8s));
+    }
+    Ok(ledger_balances)
+}
+
+fn get_subnet_features(features: &[String]) -> Result<SubnetFeatures> {
+    let mut subnet_features = SubnetFeatures::default();
+    for feature in features {
+        match feature.as_str() {
+            "canister_sandboxing" => subnet_features.canister_sandboxing = true,
+            "http_requests" => subnet_features.http_requests = true,
+            _ => bail!("unknown subnet feature {feature}"),
+        }
+    }
+    Ok(subnet_features)
+}
+
+pub fn setup(env: TestEnv) {
+    let config = read_config().expect("Failed to read testnet config");
+    if config.prometheus {
+        PrometheusVm::default()
+            .start(&env)
+            .expect("Failed to start prometheus VM");
+    }
+    let mut ic = InternetComputer::new();
+    for subnet_config in config.subnets.iter() {
+        let mut subnet = Subnet::new(subnet_config.subnet_type)
+            .add_nodes(subnet_config.nodes)
+            .with_features(
+                get_subnet_features(&subnet_config.features).expect("Invalid subnet features"),
+            );
+        if let Some(dkg_interval_length) = subnet_config.dkg_interval_length {
+            subnet = subnet.with_dkg_interval_length(ic_types::Height::from(dkg_interval_length));
+        }
+        ic = ic.add_subnet(subnet);
+    }
+    if config.unassigned_nodes > 0 {
+        ic = ic.with_unassigned_nodes(config.unassigned_nodes as i32);
+    }
+    ic.setup_and_start(&env)
+        .expect("Failed to setup IC under test");
+    if config.nns {
+        env.topology_snapshot()
+            .root_subnet()
+            .nodes()
+            .next()
+            .unwrap()
+            .install_nns_canisters_with_customizations(
+                NnsCanisterWasmStrategy::TakeBuiltFromSources,
+                NnsCustomizations {
+                    ledger_balances: Some(
+                        get_ledger_balances(&config.prefund).expect("Invalid prefunded accounts"),
+                    ),
+                },
+            )
+            .expect("Could not install NNS canisters.");
+    }
+    if config.ii {
+        install_ii_canister(&env).expect("Failed to install the Internet Identity canister");
+    }
+    for i in 1..=config.boundary_nodes {
+        BoundaryNode::new(format!("boundary-node-{i}"))
+            .allocate_vm(&env)
+            .expect("Allocation of BoundaryNode failed.")
+            .for_ic(&env, "")
+            .use_real_certs_and_dns()
+            .start(&env)
+            .expect("failed to setup BoundaryNode VM");
+    }
+    if config.prometheus {
+        env.sync_prometheus_config_with_topology();
+    }
+    install_canisters(&env, &config.canisters).expect("Failed to install canisters");
+}
+
+fn install_ii_canister(env: &TestEnv) -> Result<()> {
+    let node = env
+        .topology_snapshot()
+        .root_subnet()
+        .nodes()
+        .next()
+        .unwrap();
+    node.await_status_is_healthy()?;
+    let ii_canister_id = node.create_and_install_canister_with_arg(INTERNET_IDENTITY_WASM, None);
+    info!(
+        env.logger(),
+        "Installed the Internet Identity canister with id {ii_canister_id} on the NNS subnet"
+    );
+    Ok(())
+}
+
+fn install_canisters(env: &TestEnv, canisters: &[CanisterConfig]) -> Result<()> {
+    let log = env.logger();
+    for canister in canisters {
+        let node = env
+            .topology_snapshot()
+            .subnets()
+            .nth(canister.subnet)
+            .and_then(|subnet| subnet.nodes().next())
+            .ok_or_else(|| anyhow!("subnet {} has no nodes", canister.subnet))?;
+        node.await_status_is_healthy()?;
+        let arg = match &canister.arg {
+            Some(arg) => Some(
+                hex::decode(arg)
+                    .with_context(|| format!("arg of canister {} isn't hex", canister.name))?,
+            ),
+            None => None,
+        };
+        let canister_id = node.create_and_install_canister_with_arg(&canister.wasm, arg);
+        info!(
+            log,
+            "Installed canister {} from {} with id {} on subnet {}",
+            canister.name,
+            canister.wasm,
+            canister_id,
+            canister.subnet
+        );
+    }
+    Ok(())
+}
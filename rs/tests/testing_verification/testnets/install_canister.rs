@@ -0,0 +1,76 @@
+// Install a canister on a running testnet, reusing the setup directory of the test driver which
+// set it up. This binary is invoked by ict, which knows the setup directories of the testnets it
+// keeps alive:
+//
+//   $ ict testnet install small--1690000000000 --wasm counter.wasm --subnet 1 --arg '(42 : nat)'
+//
+// The setup directory is copied first, as the test driver keeping the testnet alive holds a lock
+// on it. The canister is created via the provisional API on the first node of the subnet with the
+// given index or id (prefix), and the wasm is installed with the candid-encoded init argument.
+
+use anyhow::{anyhow, Result};
+use candid::IDLArgs;
+use clap::Parser;
+use std::path::PathBuf;
+use std::str::FromStr;
+
+use ic_tests::driver::{
+    logger::new_stdout_logger,
+    test_env::TestEnv,
+    test_env_api::{HasPublicApiUrl, HasTopologySnapshot, IcNodeContainer},
+};
+
+#[derive(Parser, Debug)]
+struct Args {
+    #[clap(
+        long,
+        help = "Setup directory of the test driver that set up the testnet."
+    )]
+    setup_dir: PathBuf,
+
+    #[clap(long, help = "Path of the .wasm or .wat module to install.")]
+    wasm: PathBuf,
+
+    #[clap(
+        long,
+        default_value = "0",
+        help = "Index or id (prefix) of the subnet to create the canister on."
+    )]
+    subnet: String,
+
+    #[clap(
+        long,
+        help = "Init argument of the canister, in candid textual format."
+    )]
+    arg: Option<String>,
+}
+
+fn main() -> Result<()> {
+    let args = Args::parse();
+    let logger = new_stdout_logger();
+    let work_dir = tempfile::tempdir()?;
+    let env = TestEnv::fork_from(args.setup_dir.as_path(), work_dir.path(), logger)?;
+    let arg = match &args.arg {
+        Some(arg) => Some(IDLArgs::from_str(arg)?.to_bytes()?),
+        None => None,
+    };
+    let mut subnets = env.topology_snapshot().subnets();
+    let subnet = match args.subnet.parse::<usize>() {
+        Ok(index) => subnets.nth(index),
+        Err(_) => subnets.find(|s| s.subnet_id.to_string().starts_with(&args.subnet)),
+    }
+    .ok_or_else(|| anyhow!("subnet {} wasn't found", args.subnet))?;
+    let node = subnet
+        .nodes()
+        .next()
+        .ok_or_else(|| anyhow!("subnet {} has no nodes", subnet.subnet_id))?;
+    node.await_status_is_healthy()?;
+    let canister_id = node
+        .create_and_install_canister_with_arg(args.wasm.to_str().expect("Invalid wasm path"), arg);
+    println!(
+        "Installed {:?} on subnet {} with canister id:",
+        args.wasm, subnet.subnet_id
+    );
+    println!("{canister_id}");
+    Ok(())
+}